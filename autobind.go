@@ -3,6 +3,7 @@ package torrent
 import (
 	"errors"
 
+	"github.com/james-lawrence/torrent/bep0014"
 	"github.com/james-lawrence/torrent/internal/errorsx"
 	"github.com/james-lawrence/torrent/internal/langx"
 	"github.com/james-lawrence/torrent/sockets"
@@ -22,6 +23,11 @@ func BinderOptionDHT(a *binder) {
 	a.EnableDHT = true
 }
 
+// BinderOptionLSD enables local service discovery (BEP 14) on the IPv4 multicast group.
+func BinderOptionLSD(a *binder) {
+	a.EnableLSD = true
+}
+
 // NewSocketsBind binds a set of sockets to the client.
 // it bypasses any disable checks (tcp,udp, ip4/6) from the configuration.
 func NewSocketsBind(s ...sockets.Socket) binder {
@@ -30,6 +36,7 @@ func NewSocketsBind(s ...sockets.Socket) binder {
 
 type binder struct {
 	EnableDHT bool
+	EnableLSD bool
 	sockets   []sockets.Socket
 }
 
@@ -62,6 +69,20 @@ func (t binder) Bind(cl *Client, err error) (*Client, error) {
 		}
 	}
 
+	if t.EnableLSD {
+		conn, err := bep0014.Listen(bep0014.IPv4)
+		if err != nil {
+			cl.Close()
+			return nil, err
+		}
+
+		if err = cl.BindLSD(conn, bep0014.IPv4); err != nil {
+			conn.Close()
+			cl.Close()
+			return nil, err
+		}
+	}
+
 	return cl, nil
 }
 
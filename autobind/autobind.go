@@ -6,12 +6,14 @@ package autobind
 import (
 	"fmt"
 	"net"
+	"net/netip"
 	"os"
 	"strconv"
 	"strings"
 	"sync/atomic"
 
 	"github.com/james-lawrence/torrent"
+	"github.com/james-lawrence/torrent/bep0014"
 	"github.com/james-lawrence/torrent/internal/errorsx"
 	"github.com/james-lawrence/torrent/internal/langx"
 	"github.com/james-lawrence/torrent/internal/netx"
@@ -57,6 +59,11 @@ func EnableDHT(a *Autobind) {
 	a.EnableDHT = true
 }
 
+// EnableLSD enables local service discovery (BEP 14) on the enabled ip networks.
+func EnableLSD(a *Autobind) {
+	a.EnableLSD = true
+}
+
 // Autobind manages automatically binding a client to available networks.
 type Autobind struct {
 	// The address to listen for new uTP and TCP bittorrent protocol
@@ -69,6 +76,7 @@ type Autobind struct {
 	DisableTCP  bool
 	DisableUTP  bool
 	EnableDHT   bool
+	EnableLSD   bool
 }
 
 // New used to automatically listen to available networks
@@ -154,9 +162,35 @@ func (t Autobind) Bind(cl *torrent.Client, err error) (*torrent.Client, error) {
 		}
 	}
 
+	if t.EnableLSD {
+		if err = t.bindLSD(cl); err != nil {
+			return nil, err
+		}
+	}
+
 	return cl, nil
 }
 
+func (t Autobind) bindLSD(cl *torrent.Client) error {
+	for _, group := range []netip.AddrPort{bep0014.IPv4, bep0014.IPv6} {
+		if group.Addr().Is4() && t.DisableIPv4 || group.Addr().Is6() && t.DisableIPv6 {
+			continue
+		}
+
+		conn, err := bep0014.Listen(group)
+		if err != nil {
+			return err
+		}
+
+		if err = cl.BindLSD(conn, group); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (t Autobind) Close() error {
 	return nil
 }
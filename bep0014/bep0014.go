@@ -0,0 +1,145 @@
+// Package bep0014 implements local service discovery, peers multicast the infohashes
+// of their torrents to the local network allowing peers on the same network to find
+// each other without a tracker or the DHT.
+// This is described in BitTorrent Enhancement Proposal 14 (BEP 14): Local Service Discovery.
+package bep0014
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+)
+
+// Port the multicast groups are addressed on.
+const Port = 6771
+
+// MaximumMessageSize of an announcement, keeps announcements within a single
+// unfragmented datagram.
+const MaximumMessageSize = 1400
+
+const requestLine = "BT-SEARCH * HTTP/1.1"
+
+var (
+	IPv4 = netip.AddrPortFrom(netip.MustParseAddr("239.192.152.143"), Port)
+	IPv6 = netip.AddrPortFrom(netip.MustParseAddr("ff15::efc0:988f"), Port)
+)
+
+// Announce advertises the infohashes a peer is participating in.
+type Announce struct {
+	Host       netip.AddrPort // multicast group the announcement is sent to.
+	Port       uint16         // port the peer accepts connections on.
+	Infohashes []int160.T
+	Cookie     string // allows peers to ignore their own announcements.
+}
+
+func (t Announce) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s\r\n", requestLine)
+	fmt.Fprintf(&buf, "Host: %s\r\n", t.Host)
+	fmt.Fprintf(&buf, "Port: %d\r\n", t.Port)
+	for _, id := range t.Infohashes {
+		fmt.Fprintf(&buf, "Infohash: %s\r\n", hex.EncodeToString(id.Bytes()))
+	}
+
+	if t.Cookie != "" {
+		fmt.Fprintf(&buf, "cookie: %s\r\n", t.Cookie)
+	}
+
+	buf.WriteString("\r\n\r\n")
+
+	return buf.Bytes(), nil
+}
+
+func (t *Announce) UnmarshalBinary(b []byte) error {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(b)))
+
+	line, err := r.ReadLine()
+	if err != nil {
+		return errorsx.Wrap(err, "unable to read request line")
+	}
+
+	if line != requestLine {
+		return errorsx.Errorf("unexpected request line: %q", line)
+	}
+
+	headers, err := r.ReadMIMEHeader()
+	if err != nil && len(headers) == 0 {
+		return errorsx.Wrap(err, "unable to read headers")
+	}
+
+	port, err := strconv.ParseUint(headers.Get("Port"), 10, 16)
+	if err != nil || port == 0 {
+		return errorsx.Errorf("invalid port: %q", headers.Get("Port"))
+	}
+
+	ids := make([]int160.T, 0, len(headers.Values("Infohash")))
+	for _, v := range headers.Values("Infohash") {
+		decoded, err := hex.DecodeString(strings.TrimSpace(v))
+		if err != nil || len(decoded) != 20 {
+			return errorsx.Errorf("invalid infohash: %q", v)
+		}
+
+		ids = append(ids, int160.FromBytes(decoded))
+	}
+
+	if len(ids) == 0 {
+		return errorsx.New("announcement is missing infohashes")
+	}
+
+	host, _ := netip.ParseAddrPort(headers.Get("Host"))
+
+	*t = Announce{
+		Host:       host,
+		Port:       uint16(port),
+		Infohashes: ids,
+		Cookie:     headers.Get("Cookie"),
+	}
+
+	return nil
+}
+
+// Announcements splits the infohashes into announcements that fit within the
+// MaximumMessageSize.
+func Announcements(host netip.AddrPort, port uint16, cookie string, ids ...int160.T) (results []Announce) {
+	const (
+		overhead = 128 // request line, host, port, cookie and terminators.
+		perhash  = len("Infohash: \r\n") + 40
+	)
+
+	n := max(1, (MaximumMessageSize-overhead-len(cookie))/perhash)
+	for offset := 0; offset < len(ids); offset += n {
+		results = append(results, Announce{
+			Host:       host,
+			Port:       port,
+			Infohashes: ids[offset:min(offset+n, len(ids))],
+			Cookie:     cookie,
+		})
+	}
+
+	return results
+}
+
+// Listen joins the multicast group on the default interface.
+func Listen(group netip.AddrPort) (*net.UDPConn, error) {
+	network := "udp4"
+	if group.Addr().Is6() {
+		network = "udp6"
+	}
+
+	conn, err := net.ListenMulticastUDP(network, nil, net.UDPAddrFromAddrPort(group))
+	if err != nil {
+		return nil, errorsx.Wrapf(err, "unable to join multicast group %s", group)
+	}
+
+	return conn, nil
+}
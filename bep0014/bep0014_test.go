@@ -0,0 +1,71 @@
+package bep0014_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/bep0014"
+	"github.com/james-lawrence/torrent/dht/int160"
+)
+
+func TestAnnounce(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		expected := bep0014.Announce{
+			Host:       bep0014.IPv4,
+			Port:       6881,
+			Infohashes: []int160.T{int160.Random(), int160.Random()},
+			Cookie:     "abc123",
+		}
+
+		encoded, err := expected.MarshalBinary()
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(encoded), bep0014.MaximumMessageSize)
+
+		var decoded bep0014.Announce
+		require.NoError(t, decoded.UnmarshalBinary(encoded))
+		require.Equal(t, expected, decoded)
+	})
+
+	t.Run("decode specification example", func(t *testing.T) {
+		var decoded bep0014.Announce
+		require.NoError(t, decoded.UnmarshalBinary([]byte("BT-SEARCH * HTTP/1.1\r\nHost: [ff15::efc0:988f]:6771\r\nPort: 1234\r\nInfohash: 0123456789abcdef0123456789ABCDEF01234567\r\n\r\n\r\n")))
+		require.Equal(t, bep0014.IPv6, decoded.Host)
+		require.Equal(t, uint16(1234), decoded.Port)
+		require.Len(t, decoded.Infohashes, 1)
+		require.Equal(t, "0123456789abcdef0123456789abcdef01234567", decoded.Infohashes[0].String())
+		require.Empty(t, decoded.Cookie)
+	})
+
+	t.Run("reject invalid announcements", func(t *testing.T) {
+		for _, encoded := range []string{
+			"GET / HTTP/1.1\r\nPort: 1234\r\nInfohash: 0123456789abcdef0123456789abcdef01234567\r\n\r\n",
+			"BT-SEARCH * HTTP/1.1\r\nInfohash: 0123456789abcdef0123456789abcdef01234567\r\n\r\n",
+			"BT-SEARCH * HTTP/1.1\r\nPort: 1234\r\n\r\n",
+			"BT-SEARCH * HTTP/1.1\r\nPort: 1234\r\nInfohash: 0123\r\n\r\n",
+		} {
+			var decoded bep0014.Announce
+			require.Error(t, decoded.UnmarshalBinary([]byte(encoded)), encoded)
+		}
+	})
+
+	t.Run("split large announcements", func(t *testing.T) {
+		ids := make([]int160.T, 100)
+		for i := range ids {
+			ids[i] = int160.Random()
+		}
+
+		announcements := bep0014.Announcements(netip.MustParseAddrPort("239.192.152.143:6771"), 6881, "cookie", ids...)
+		require.Greater(t, len(announcements), 1)
+
+		var combined []int160.T
+		for _, a := range announcements {
+			encoded, err := a.MarshalBinary()
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(encoded), bep0014.MaximumMessageSize)
+			combined = append(combined, a.Infohashes...)
+		}
+		require.Equal(t, ids, combined)
+	})
+}
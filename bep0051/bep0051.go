@@ -96,7 +96,7 @@ func (t Endpoint) Handle(ctx context.Context, source dht.Addr, s *dht.Server, ra
 			Available: total,
 			Sample:    sampled,
 			Nodes:     s.MakeReturnNodes(int160.FromByteArray(m.A.Target), func(na krpc.NodeAddr) bool { return na.Addr().Is4() }),
-			Nodes6:    s.MakeReturnNodes(int160.FromByteArray(m.A.Target), func(krpc.NodeAddr) bool { return true }),
+			Nodes6:    s.MakeReturnNodes(int160.FromByteArray(m.A.Target), func(na krpc.NodeAddr) bool { return na.Addr().Is6() }),
 		},
 	}
 
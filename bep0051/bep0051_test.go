@@ -0,0 +1,134 @@
+package bep0051_test
+
+import (
+	"iter"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/bep0051"
+	"github.com/james-lawrence/torrent/dht"
+	"github.com/james-lawrence/torrent/dht/dhttest"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/dht/krpc"
+	peer_store "github.com/james-lawrence/torrent/dht/peer-store"
+)
+
+func source(ids ...int160.T) bep0051.Source {
+	return func() iter.Seq[int160.T] {
+		return slices.Values(ids)
+	}
+}
+
+func random(n int) (ids []int160.T) {
+	for range n {
+		ids = append(ids, int160.Random())
+	}
+	return ids
+}
+
+func decode(sample []byte) (ids []int160.T) {
+	for ih := range slices.Chunk(sample, 20) {
+		ids = append(ids, int160.FromBytes(ih))
+	}
+	return ids
+}
+
+func TestSampler(t *testing.T) {
+	t.Run("unique infohashes from every source are sampled", func(t *testing.T) {
+		ids := random(3)
+		ps := &peer_store.InMemory{}
+		ps.AddPeer(ids[2].AsByteArray(), krpc.NewNodeAddrFromIPPort(net.IPv4(127, 0, 0, 1), 7000))
+
+		s := bep0051.NewSampler(time.Hour, source(ids[0], ids[1]), source(ids[1]), bep0051.SourcePeerStore(ps))
+		ttl, total, sample := s.Snapshot(20)
+		require.EqualValues(t, 3, total)
+		require.InDelta(t, 3600, ttl, 1)
+		require.ElementsMatch(t, ids, decode(sample))
+
+		// complete samples reveal new infohashes immediately.
+		added := int160.Random()
+		ps.AddPeer(added.AsByteArray(), krpc.NewNodeAddrFromIPPort(net.IPv4(127, 0, 0, 1), 7000))
+		_, total, sample = s.Snapshot(20)
+		require.EqualValues(t, 4, total)
+		require.ElementsMatch(t, append(ids, added), decode(sample))
+	})
+
+	t.Run("sample is limited and rotated", func(t *testing.T) {
+		ids := random(100)
+		s := bep0051.NewSampler(time.Hour, source(ids...))
+
+		_, total, sample := s.Snapshot(10)
+		require.EqualValues(t, 100, total)
+		require.Len(t, decode(sample), 10)
+		require.Subset(t, ids, decode(sample))
+
+		// the sample is stable until the ttl elapses.
+		_, _, again := s.Snapshot(10)
+		require.Equal(t, sample, again)
+
+		s = bep0051.NewSampler(0, source(ids...))
+		rotated := make(map[int160.T]struct{})
+		for range 20 {
+			_, _, sample = s.Snapshot(10)
+			for _, id := range decode(sample) {
+				rotated[id] = struct{}{}
+			}
+		}
+		require.Greater(t, len(rotated), 10)
+	})
+
+	t.Run("ttl is capped", func(t *testing.T) {
+		ttl, total, sample := bep0051.NewSampler(24 * time.Hour).Snapshot(20)
+		require.EqualValues(t, bep0051.TTLMax, ttl)
+		require.Zero(t, total)
+		require.Empty(t, sample)
+	})
+}
+
+func newServer(t *testing.T, ids ...int160.T) *dht.Server {
+	return dhttest.NewServer(t, dht.DefaultMuxer().Method(bep0051.Query, bep0051.NewEndpoint(bep0051.NewSampler(time.Hour, source(ids...)))))
+}
+
+// ping the node so it is returned to queries.
+func introduce(t *testing.T, from, to *dht.Server) {
+	require.NoError(t, from.Ping(to.Addr()).ToError())
+	require.Eventually(t, func() bool {
+		return slices.ContainsFunc(from.MakeReturnNodes(int160.FromByteArray(to.ID()), func(krpc.NodeAddr) bool { return true }), func(n krpc.NodeInfo) bool {
+			return n.ID == to.ID()
+		})
+	}, time.Second, time.Millisecond)
+}
+
+func TestCrawl(t *testing.T) {
+	ids := random(6)
+	crawler := newServer(t)
+	s1 := newServer(t, ids[:3]...)
+	s2 := newServer(t, ids[2:5]...)
+	s3 := newServer(t, ids[5])
+
+	// the crawler only knows of the first node, the remaining nodes are discovered.
+	// nodes are only returned to queries once they have responded.
+	require.NoError(t, crawler.AddNode(dhttest.NodeInfo(s1)))
+	introduce(t, s1, s2)
+	introduce(t, s2, s3)
+
+	crawled, err := bep0051.Crawl(t.Context(), crawler, bep0051.CrawlOptionTimeout(time.Second))
+	require.NoError(t, err)
+	require.ElementsMatch(t, ids, slices.Collect(crawled))
+
+	t.Run("stops when the consumer does", func(t *testing.T) {
+		crawled, err := bep0051.Crawl(t.Context(), crawler, bep0051.CrawlOptionTimeout(time.Second))
+		require.NoError(t, err)
+
+		var found []int160.T
+		for id := range crawled {
+			found = append(found, id)
+			break
+		}
+		require.Len(t, found, 1)
+	})
+}
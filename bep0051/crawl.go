@@ -0,0 +1,203 @@
+package bep0051
+
+import (
+	"context"
+	"encoding/binary"
+	"iter"
+	"net/netip"
+	"time"
+
+	"github.com/james-lawrence/torrent/dht"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/dht/krpc"
+	"github.com/james-lawrence/torrent/dht/types"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/langx"
+)
+
+// CrawlOption configures a crawl, see Crawl.
+type CrawlOption func(*crawler)
+
+// CrawlOptionConcurrency sets the number of nodes queried in parallel, defaults to 16.
+func CrawlOptionConcurrency(n int) CrawlOption {
+	return func(c *crawler) {
+		c.concurrency = max(n, 1)
+	}
+}
+
+// CrawlOptionTimeout sets the maximum time to wait for a node to respond, defaults to 10 seconds.
+func CrawlOptionTimeout(d time.Duration) CrawlOption {
+	return func(c *crawler) {
+		c.timeout = d
+	}
+}
+
+// CrawlOptionRevisit queries nodes holding more infohashes than they sampled again once
+// their interval elapses, as long as the interval does not exceed the maximum. the crawl
+// then continues until the context is done. disabled by default.
+func CrawlOptionRevisit(maximum time.Duration) CrawlOption {
+	return func(c *crawler) {
+		c.revisit = maximum
+	}
+}
+
+type crawler struct {
+	s           *dht.Server
+	concurrency int
+	timeout     time.Duration
+	revisit     time.Duration
+}
+
+// the outcome of a sample_infohashes query.
+type crawled struct {
+	addr     krpc.NodeAddr
+	samples  krpc.CompactInfohashes
+	nodes    []krpc.NodeInfo
+	interval time.Duration
+	// the node holds infohashes that were not sampled.
+	partial bool
+}
+
+// Crawl walks the keyspace issuing sample_infohashes queries to every node it discovers,
+// starting from the routing table of the server, and yields each unique infohash sampled.
+// the crawl completes once every node has been queried or the context is done.
+func Crawl(ctx context.Context, s *dht.Server, options ...CrawlOption) (iter.Seq[int160.T], error) {
+	c := langx.Clone(crawler{
+		s:           s,
+		concurrency: 16,
+		timeout:     10 * time.Second,
+	}, options...)
+
+	nodes, err := s.TraversalStartingNodes()
+	if err != nil {
+		return nil, errorsx.Wrap(err, "unable to determine starting nodes")
+	}
+
+	return func(yield func(int160.T) bool) {
+		c.crawl(ctx, nodes, yield)
+	}, nil
+}
+
+func (t crawler) crawl(ctx context.Context, starting []types.AddrMaybeId, yield func(int160.T) bool) {
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+
+	var (
+		queue       []krpc.NodeAddr
+		queried     = make(map[netip.AddrPort]struct{})
+		found       = make(map[int160.T]struct{})
+		results     = make(chan crawled)
+		revisits    = make(chan krpc.NodeAddr)
+		outstanding int
+		pending     int // revisits waiting for their interval to elapse.
+		regions     uint16
+	)
+
+	enqueue := func(n types.AddrMaybeId) {
+		// nodes6 may contain ipv4 mapped addresses of nodes already seen.
+		key := netip.AddrPortFrom(n.Addr.Addr().Unmap(), n.Addr.Port())
+		if _, ok := queried[key]; ok || !t.s.TraversalNodeFilter(n) {
+			return
+		}
+
+		queried[key] = struct{}{}
+		queue = append(queue, n.Addr)
+	}
+
+	for _, n := range starting {
+		enqueue(n)
+	}
+
+	for {
+		for ; outstanding < t.concurrency && len(queue) > 0; outstanding++ {
+			addr := queue[0]
+			queue = queue[1:]
+
+			// spread the targets across the keyspace, the nodes returned alongside
+			// the samples are the closest to the target.
+			target := int160.Random().AsByteArray()
+			binary.BigEndian.PutUint16(target[:], regions)
+			regions += 0x9e37
+
+			go func() {
+				r := t.query(ctx, addr, target)
+				select {
+				case results <- r:
+				case <-ctx.Done():
+				}
+			}()
+		}
+
+		if outstanding == 0 && pending == 0 {
+			return
+		}
+
+		select {
+		case r := <-results:
+			outstanding--
+
+			for _, ih := range r.samples {
+				id := int160.FromByteArray(ih)
+				if _, ok := found[id]; ok {
+					continue
+				}
+
+				found[id] = struct{}{}
+				if !yield(id) {
+					return
+				}
+			}
+
+			for _, n := range types.AddrMaybeIdSliceFromNodeInfoSlice(r.nodes) {
+				enqueue(n)
+			}
+
+			if r.partial && t.revisit > 0 && r.interval <= t.revisit {
+				pending++
+				time.AfterFunc(max(r.interval, time.Second), func() {
+					select {
+					case revisits <- r.addr:
+					case <-ctx.Done():
+					}
+				})
+			}
+		case addr := <-revisits:
+			pending--
+			queue = append(queue, addr)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t crawler) query(ctx context.Context, addr krpc.NodeAddr, target krpc.ID) (r crawled) {
+	r.addr = addr
+
+	ctx, done := context.WithTimeout(ctx, t.timeout)
+	defer done()
+
+	qi, err := NewRequest(t.s.ID(), target)
+	if err != nil {
+		return r
+	}
+
+	res := t.s.Query(ctx, dht.NewAddr(addr.UDP()), qi)
+	if res.Err != nil || res.Reply.R == nil {
+		return r
+	}
+
+	reply := res.Reply.R
+	r.nodes = append(reply.Nodes, reply.Nodes6...)
+
+	if reply.Samples != nil {
+		r.samples = *reply.Samples
+	}
+
+	if reply.Interval != nil {
+		r.interval = time.Duration(*reply.Interval) * time.Second
+	}
+
+	r.partial = reply.Num != nil && *reply.Num > int64(len(r.samples))
+
+	return r
+}
@@ -0,0 +1,98 @@
+package bep0051
+
+import (
+	"iter"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/james-lawrence/torrent/dht/int160"
+	peer_store "github.com/james-lawrence/torrent/dht/peer-store"
+)
+
+// Source of the infohashes a node is able to sample.
+type Source func() iter.Seq[int160.T]
+
+// SourcePeerStore samples the infohashes announced to the node, peer stores that
+// are unable to enumerate their infohashes provide none.
+func SourcePeerStore(ps peer_store.Interface) Source {
+	return func() iter.Seq[int160.T] {
+		return func(yield func(int160.T) bool) {
+			enumerable, ok := ps.(peer_store.Enumerable)
+			if !ok {
+				return
+			}
+
+			for ih := range enumerable.InfoHashes() {
+				if !yield(int160.FromByteArray(ih)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// NewSampler samples the unique infohashes provided by the sources, the sample is
+// drawn at random and rotated once the ttl elapses.
+func NewSampler(ttl time.Duration, sources ...Source) *RandomSampler {
+	return &RandomSampler{
+		ttl:     min(ttl, TTLMax*time.Second),
+		sources: sources,
+	}
+}
+
+// RandomSampler is a Sampler drawing from a set of sources, see NewSampler.
+type RandomSampler struct {
+	ttl     time.Duration
+	sources []Source
+
+	mu      sync.Mutex
+	expires time.Time
+	total   uint
+	sample  [][20]byte
+}
+
+func (t *RandomSampler) Snapshot(n int) (ttl uint, total uint, sample []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	// resample early when a larger sample is requested than was drawn, or when the
+	// previous sample held every infohash so newly added infohashes are revealed.
+	if !now.Before(t.expires) || len(t.sample) < n || uint(len(t.sample)) >= t.total {
+		t.rotate(now, n)
+	}
+
+	sample = make([]byte, 0, min(n, len(t.sample))*20)
+	for _, ih := range t.sample[:min(n, len(t.sample))] {
+		sample = append(sample, ih[:]...)
+	}
+
+	return uint(max(t.expires.Sub(now).Round(time.Second)/time.Second, TTLMin)), t.total, sample
+}
+
+// draw a new sample of up to n infohashes using reservoir sampling.
+func (t *RandomSampler) rotate(now time.Time, n int) {
+	seen := make(map[int160.T]struct{})
+	t.sample = make([][20]byte, 0, n)
+
+	for _, src := range t.sources {
+		for id := range src() {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+
+			if len(t.sample) < n {
+				t.sample = append(t.sample, id.AsByteArray())
+			} else if i := rand.IntN(len(seen)); i < n {
+				t.sample[i] = id.AsByteArray()
+			}
+		}
+	}
+
+	rand.Shuffle(len(t.sample), func(i, j int) { t.sample[i], t.sample[j] = t.sample[j], t.sample[i] })
+
+	t.total = uint(len(seen))
+	t.expires = now.Add(t.ttl)
+}
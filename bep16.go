@@ -0,0 +1,120 @@
+package torrent
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/james-lawrence/torrent/internal/errorsx"
+
+	pp "github.com/james-lawrence/torrent/btprotocol"
+)
+
+// superseeder implements BEP 16, instead of advertising every piece an initial
+// seed reveals a single piece at a time to each peer, revealing another only once
+// the previous piece has spread to other peers. this minimizes the number of
+// times a piece is uploaded by the seed.
+type superseeder struct {
+	enabled  atomic.Bool
+	mu       sync.Mutex
+	revealed map[uint64]int // number of peers each piece is outstanding with.
+}
+
+// select the piece to reveal to the peer, preferring pieces revealed to the
+// fewest peers followed by the rarest pieces.
+func (t *superseeder) next(cn *connection) (pid uint64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.revealed == nil {
+		t.revealed = make(map[uint64]int)
+	}
+
+	var best uint64
+	chunks := cn.t.chunks
+	for it := chunks.CompletedBitmap().Iterator(); it.HasNext(); {
+		candidate := uint64(it.Next())
+		if cn.PeerHasPiece(candidate) {
+			continue
+		}
+
+		rank := uint64(t.revealed[candidate])<<48 | uint64(chunks.Availability(candidate))<<32 | chunks.tiebreak(candidate)
+		if ok && rank >= best {
+			continue
+		}
+
+		pid, best, ok = candidate, rank, true
+	}
+
+	if ok {
+		t.revealed[pid]++
+	}
+
+	return pid, ok
+}
+
+// the piece is no longer outstanding with a peer.
+func (t *superseeder) release(pid uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.revealed[pid] <= 1 {
+		delete(t.revealed, pid)
+		return
+	}
+
+	t.revealed[pid]--
+}
+
+// returns true if the torrent is super seeding, only applies once every piece is available.
+func (t *torrent) superseeding() bool {
+	return t.superseed.enabled.Load() && t.chunks.Cardinality(t.chunks.completed) == int(t.chunks.pieces)
+}
+
+// a revealed piece has spread once another peer announced it, seeds have every
+// piece so they are ignored. if the peer is the only one we're connected to
+// there is no one else to spread it to.
+func (cn *connection) superseeded(pid uint64) bool {
+	others := cn.t.chunks.rarity(pid)
+	if cn.advertising(pid) {
+		others--
+	}
+
+	return others > 0 || (cn.PeerHasPiece(pid) && cn.t.conns.length() <= 1)
+}
+
+// reveal the next piece to the peer once the previously revealed piece has spread.
+func (cn *connection) superseed(msg func(pp.Message) error) error {
+	if prev := cn.revealing.Load(); prev > 0 {
+		if !cn.superseeded(prev - 1) {
+			return nil
+		}
+
+		cn.t.superseed.release(prev - 1)
+		cn.revealing.Store(0)
+	}
+
+	pid, ok := cn.t.superseed.next(cn)
+	if !ok {
+		return nil
+	}
+
+	cn.revealing.Store(pid + 1)
+	cn.sentHaves.Add(uint32(pid))
+	cn.cfg.debug().Printf("c(%p) seed(%t) super seeding revealed piece %d\n", cn, cn.t.seeding(), pid)
+
+	return errorsx.Wrapf(msg(pp.NewHavePiece(pid)), "unable to reveal piece %d", pid)
+}
+
+// release the piece outstanding with the peer.
+func (cn *connection) superseedRelease() {
+	if prev := cn.revealing.Swap(0); prev > 0 {
+		cn.t.superseed.release(prev - 1)
+	}
+}
+
+// wake the writers of the torrent's connections, a piece may have spread.
+func (t *torrent) superseedSpread() {
+	for _, c := range t.conns.list() {
+		c.request.Broadcast()
+	}
+}
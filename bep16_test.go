@@ -0,0 +1,82 @@
+package torrent
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	pp "github.com/james-lawrence/torrent/btprotocol"
+	"github.com/james-lawrence/torrent/internal/bitmapx"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+func TestSuperSeeding(t *testing.T) {
+	cl := &Client{
+		config: TestingConfig(t, t.TempDir()),
+	}
+	ts, err := New(metainfo.Hash{})
+	require.NoError(t, err)
+	tt := newTorrent(cl, ts)
+	require.NoError(t, tt.setInfo(&metainfo.Info{
+		Pieces:      make([]byte, metainfo.HashSize*4),
+		Length:      32 * (1 << 10),
+		PieceLength: 8 * (1 << 10),
+	}))
+
+	require.NoError(t, tt.Tune(TuneSuperSeeding(true)))
+	require.False(t, tt.superseeding(), "super seeding only applies once the data is available")
+	tt.chunks.MergeInto(tt.chunks.completed, bitmapx.Fill(tt.chunks.pieces))
+	require.True(t, tt.superseeding())
+
+	connect := func(port uint16) (*connection, *[]pp.Message) {
+		var msgs []pp.Message
+		c := cl.newConnection(nil, false, netip.AddrPortFrom(netip.IPv4Unspecified(), port))
+		c.setTorrent(tt)
+		tt.conns.insert(c)
+		return c, &msgs
+	}
+	reveal := func(c *connection, msgs *[]pp.Message) {
+		require.NoError(t, c.superseed(func(m pp.Message) error {
+			*msgs = append(*msgs, m)
+			return nil
+		}))
+	}
+
+	c1, m1 := connect(1)
+	c2, m2 := connect(2)
+
+	reveal(c1, m1)
+	reveal(c2, m2)
+	require.Len(t, *m1, 1)
+	require.Len(t, *m2, 1)
+	require.Equal(t, pp.Have, (*m1)[0].Type)
+	a, b := uint64((*m1)[0].Index), uint64((*m2)[0].Index)
+	require.NotEqual(t, a, b, "peers should be revealed different pieces")
+
+	// nothing new is revealed until the piece spreads to another peer.
+	require.NoError(t, c1.peerSentHave(a))
+	reveal(c1, m1)
+	require.Len(t, *m1, 1)
+
+	require.NoError(t, c2.peerSentHave(a))
+	reveal(c1, m1)
+	require.Len(t, *m1, 2)
+	require.NotContains(t, []uint64{a, b}, uint64((*m1)[1].Index))
+	require.Equal(t, uint64(2), c1.sentHaves.GetCardinality())
+
+	// releasing a connection makes its piece available to others.
+	tt.deleteConnection(c2)
+	require.NotContains(t, tt.superseed.revealed, b)
+
+	// seeds have every piece, they don't indicate the piece spread.
+	c3, _ := connect(3)
+	c3.onPeerSentHaveAll()
+	c4, m4 := connect(4)
+	reveal(c4, m4)
+	require.Len(t, *m4, 1)
+	d := uint64((*m4)[0].Index)
+	require.NoError(t, c4.peerSentHave(d))
+	reveal(c4, m4)
+	require.Len(t, *m4, 1)
+}
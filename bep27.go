@@ -0,0 +1,38 @@
+package torrent
+
+import (
+	"maps"
+
+	"github.com/james-lawrence/torrent/internal/langx"
+
+	pp "github.com/james-lawrence/torrent/btprotocol"
+)
+
+// returns true if the info marks the torrent as private (BEP 27).
+func (t *torrent) private() bool {
+	info := t.info
+	return info != nil && langx.Autoderef(info.Private)
+}
+
+// private torrents only use peers from their trackers, peers discovered via
+// the DHT, PEX and local service discovery are ignored (BEP 27).
+func (t *torrent) peerAllowed(p Peer) bool {
+	switch p.Source {
+	case peerSourceDhtGetPeers, peerSourceDhtAnnouncePeer, peerSourcePex, peerSourceLSD:
+		return !t.private()
+	default:
+		return true
+	}
+}
+
+// the extensions advertised to the peer, peer exchange is disabled
+// for private torrents (BEP 27).
+func (cn *connection) localExtensions() map[pp.ExtensionName]pp.ExtensionNumber {
+	if cn.t == nil || !cn.t.private() {
+		return cn.cfg.extensions
+	}
+
+	extensions := maps.Clone(cn.cfg.extensions)
+	delete(extensions, pp.ExtensionNamePex)
+	return extensions
+}
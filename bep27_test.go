@@ -0,0 +1,71 @@
+package torrent
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	pp "github.com/james-lawrence/torrent/btprotocol"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/metainfo"
+	"github.com/james-lawrence/torrent/torrenttest"
+)
+
+func TestPrivateTorrents(t *testing.T) {
+	cl, err := NewClient(TestingConfig(t, t.TempDir()))
+	require.NoError(t, err)
+	defer cl.Close()
+
+	start := func(t *testing.T, options ...metainfo.Option) *torrent {
+		info, _, err := torrenttest.Random(t.TempDir(), 32, options...)
+		require.NoError(t, err)
+		md, err := NewFromInfo(info)
+		require.NoError(t, err)
+		tt, _, err := cl.start(md, TuneMaxConnections(0))
+		require.NoError(t, err)
+		return tt
+	}
+
+	private := func(i *metainfo.Info) { i.Private = langx.Autoptr(true) }
+
+	t.Run("only tracker peers are used", func(t *testing.T) {
+		added := func(tt *torrent, source peerSource) bool {
+			tt.addPeers(NewPeer(int160.Random(), netip.MustParseAddrPort("10.1.2.3:6881"), PeerOptionSource(source)))
+			_, ok := tt.peers.PopMax()
+			return ok
+		}
+
+		tt := start(t, private)
+		public := start(t)
+		for _, source := range []peerSource{peerSourceTracker, peerSourceIncoming} {
+			require.True(t, added(tt, source), source)
+			require.True(t, added(public, source), source)
+		}
+
+		for _, source := range []peerSource{peerSourceDhtGetPeers, peerSourceDhtAnnouncePeer, peerSourcePex, peerSourceLSD} {
+			require.False(t, added(tt, source), source)
+			require.True(t, added(public, source), source)
+		}
+	})
+
+	t.Run("peer exchange is not advertised", func(t *testing.T) {
+		connect := func(tt *torrent) *connection {
+			c := cl.newConnection(nil, false, netip.AddrPortFrom(netip.IPv4Unspecified(), 1))
+			c.setTorrent(tt)
+			c.PeerExtensionIDs = map[pp.ExtensionName]pp.ExtensionNumber{pp.ExtensionNamePex: 1}
+			return c
+		}
+
+		c := connect(start(t, private))
+		require.NotContains(t, c.localExtensions(), pp.ExtensionNamePex)
+		require.Contains(t, c.localExtensions(), pp.ExtensionName(pp.ExtensionNameMetadata))
+		require.False(t, c.extensionEnabled(pp.ExtensionNamePex))
+
+		c = connect(start(t))
+		require.Contains(t, c.localExtensions(), pp.ExtensionNamePex)
+		require.True(t, c.extensionEnabled(pp.ExtensionNamePex))
+		require.Contains(t, cl.config.extensions, pp.ExtensionNamePex, "client configuration must be unaffected")
+	})
+}
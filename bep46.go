@@ -0,0 +1,323 @@
+package torrent
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"io"
+	"iter"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/dht"
+	"github.com/james-lawrence/torrent/dht/bep44"
+	"github.com/james-lawrence/torrent/dht/exts/getput"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/dht/krpc"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+// prefix of the xs parameter of a mutable torrent magnet link.
+const btpkPrefix = "urn:btpk:"
+
+// Mutable identifies a BEP 46 mutable torrent, the infohash of the current version
+// is published as a BEP 44 mutable item signed by the public key. the salt allows
+// a single key to publish multiple torrents.
+type Mutable struct {
+	PublicKey [32]byte
+	Salt      []byte
+}
+
+// ParseMutable parses a mutable torrent magnet link, magnet:?xs=urn:btpk:<public key>&s=<salt>
+// with the public key and salt hex encoded.
+func ParseMutable(uri string) (m Mutable, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return m, errorsx.Wrap(err, "invalid mutable torrent uri")
+	}
+
+	if u.Scheme != "magnet" {
+		return m, errorsx.Errorf("invalid mutable torrent uri, unexpected scheme %q", u.Scheme)
+	}
+
+	q := u.Query()
+	xs := q.Get("xs")
+	if !strings.HasPrefix(xs, btpkPrefix) {
+		return m, errorsx.Errorf("invalid mutable torrent uri, missing %s public key", btpkPrefix)
+	}
+
+	pk, err := hex.DecodeString(strings.TrimPrefix(xs, btpkPrefix))
+	if err != nil || len(pk) != len(m.PublicKey) {
+		return m, errorsx.Errorf("invalid mutable torrent uri, public key must be %d hex encoded bytes", len(m.PublicKey))
+	}
+	copy(m.PublicKey[:], pk)
+
+	if m.Salt, err = hex.DecodeString(q.Get("s")); err != nil {
+		return m, errorsx.Wrap(err, "invalid mutable torrent uri, salt must be hex encoded")
+	}
+
+	return m, nil
+}
+
+// Target of the BEP 44 item holding the current version of the torrent.
+func (t Mutable) Target() bep44.Target {
+	return bep44.MakeMutableTarget(t.PublicKey, t.Salt)
+}
+
+// String returns the magnet link of the mutable torrent.
+func (t Mutable) String() string {
+	uri := "magnet:?xs=" + btpkPrefix + hex.EncodeToString(t.PublicKey[:])
+	if len(t.Salt) > 0 {
+		uri += "&s=" + hex.EncodeToString(t.Salt)
+	}
+
+	return uri
+}
+
+// the value of the BEP 44 item of a mutable torrent.
+type mutableValue struct {
+	InfoHash []byte `bencode:"ih"`
+}
+
+// ResolveMutable retrieves the infohash of the current version of the mutable torrent
+// along with its sequence number, which increases with every version published.
+func ResolveMutable(ctx context.Context, s *dht.Server, m Mutable) (ih metainfo.Hash, seq int64, err error) {
+	var v mutableValue
+
+	res, _, err := getput.Get(ctx, m.Target(), s, nil, m.Salt)
+	if err != nil {
+		return ih, seq, errorsx.Wrapf(err, "unable to resolve %s", m)
+	}
+
+	if !res.Mutable {
+		return ih, seq, errorsx.Errorf("unable to resolve %s, the item is immutable", m)
+	}
+
+	if err = bencode.Unmarshal(res.V, &v); err != nil {
+		return ih, seq, errorsx.Wrapf(err, "unable to resolve %s, invalid value", m)
+	}
+
+	if len(v.InfoHash) != len(ih) {
+		return ih, seq, errorsx.Errorf("unable to resolve %s, invalid infohash %x", m, v.InfoHash)
+	}
+
+	return metainfo.Hash(v.InfoHash), res.Seq, nil
+}
+
+// NewFromMutable creates a torrent from the current version of the mutable torrent, see
+// Client.FollowMutable to track later versions.
+func NewFromMutable(ctx context.Context, s *dht.Server, m Mutable, options ...Option) (t Metadata, err error) {
+	ih, _, err := ResolveMutable(ctx, s, m)
+	if err != nil {
+		return t, err
+	}
+
+	return New(ih, options...)
+}
+
+// PublishMutable signs and stores the infohash as the current version of the mutable
+// torrent identified by the key and salt, superseding previously published versions.
+// returns the sequence number of the published version.
+func PublishMutable(ctx context.Context, s *dht.Server, key ed25519.PrivateKey, salt []byte, ih metainfo.Hash) (seq int64, err error) {
+	put := bep44.Put{
+		V:    mutableValue{InfoHash: ih.Bytes()},
+		K:    (*[32]byte)(key.Public().(ed25519.PublicKey)),
+		Salt: salt,
+	}
+
+	sign := func(current int64) bep44.Put {
+		signed := put
+		signed.Seq = current + 1
+		signed.Sign(key)
+		seq = signed.Seq
+		return signed
+	}
+
+	if _, err = getput.Put(ctx, krpc.ID(put.Target()), s, salt, sign); err != nil {
+		return seq, errorsx.Wrapf(err, "unable to publish %x", ih)
+	}
+
+	return seq, nil
+}
+
+// MutableOption configures how a mutable torrent is followed, see Client.FollowMutable.
+type MutableOption func(*mutableFollower)
+
+// MutableOptionInterval sets how often the DHT is polled for a new version, defaults to 15 minutes.
+func MutableOptionInterval(d time.Duration) MutableOption {
+	return func(t *mutableFollower) {
+		t.interval = d
+	}
+}
+
+// MutableOptionMetadata sets the options used to create the metadata of every version,
+// e.g. the storage or trackers.
+func MutableOptionMetadata(options ...Option) MutableOption {
+	return func(t *mutableFollower) {
+		t.options = options
+	}
+}
+
+// MutableOptionTuners sets the tuners applied to every version once it has been started.
+func MutableOptionTuners(options ...Tuner) MutableOption {
+	return func(t *mutableFollower) {
+		t.tuners = options
+	}
+}
+
+type mutableFollower struct {
+	interval time.Duration
+	options  []Option
+	tuners   []Tuner
+}
+
+// FollowMutable starts the current version of the mutable torrent and polls the DHT for
+// newer versions. a newer version is started, seeded with the completed files it shares
+// with the previous version, and then replaces the previous version which is stopped.
+// yields each version once it is running, along with any failures resolving or starting
+// a version. the latest version remains running once the iteration stops.
+func (cl *Client) FollowMutable(ctx context.Context, m Mutable, options ...MutableOption) iter.Seq2[Torrent, error] {
+	f := langx.Clone(mutableFollower{
+		interval: 15 * time.Minute,
+	}, options...)
+
+	return func(yield func(Torrent, error) bool) {
+		var (
+			current *torrent
+			seq     int64
+		)
+
+		for {
+			ih, latest, err := cl.resolveMutable(ctx, m)
+			switch {
+			case err != nil:
+				if !yield(nil, err) {
+					return
+				}
+			case current != nil && latest <= seq:
+			case current != nil && current.md.ID == int160.FromByteArray(ih):
+				// the version was republished.
+				seq = latest
+			default:
+				next, err := cl.migrateMutable(ctx, current, ih, f)
+				if err != nil {
+					if !yield(nil, errorsx.Wrapf(err, "unable to start version %d of %s", latest, m)) {
+						return
+					}
+					break
+				}
+
+				current, seq = next, latest
+				if !yield(next, nil) {
+					return
+				}
+			}
+
+			select {
+			case <-time.After(f.interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// resolve the mutable torrent using every DHT server of the client, returning the latest version found.
+func (cl *Client) resolveMutable(ctx context.Context, m Mutable) (ih metainfo.Hash, seq int64, err error) {
+	found := false
+	for _, s := range cl.DhtServers() {
+		_ih, _seq, _err := ResolveMutable(ctx, s, m)
+		if _err != nil {
+			err = errorsx.Compact(err, _err)
+			continue
+		}
+
+		if !found || _seq > seq {
+			ih, seq, found = _ih, _seq, true
+		}
+	}
+
+	if found {
+		return ih, seq, nil
+	}
+
+	if err == nil {
+		err = errorsx.Errorf("unable to resolve %s, dht is disabled", m)
+	}
+
+	return ih, seq, err
+}
+
+// start the version of the mutable torrent, reusing the data of the previous version
+// which is stopped once the new version is running.
+func (cl *Client) migrateMutable(ctx context.Context, from *torrent, ih metainfo.Hash, f mutableFollower) (to *torrent, err error) {
+	md, err := New(ih, f.options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if to, _, err = cl.start(md); err != nil {
+		return nil, err
+	}
+
+	if err = to.Tune(TuneNewConns); err != nil {
+		return nil, errorsx.Compact(err, cl.Stop(md))
+	}
+
+	select {
+	case <-to.GotInfo():
+	case <-ctx.Done():
+		return nil, errorsx.Compact(context.Cause(ctx), cl.Stop(md))
+	}
+
+	if from != nil {
+		if err = reuseMutable(from, to); err != nil {
+			cl.config.info().Printf("%s: unable to reuse the data of the previous version: %v\n", to, err)
+		}
+	}
+
+	if err = to.Tune(f.tuners...); err != nil {
+		return nil, errorsx.Compact(err, cl.Stop(md))
+	}
+
+	if from != nil {
+		if err = cl.Stop(from.md); err != nil {
+			cl.config.info().Printf("%s: unable to stop the previous version: %v\n", from, err)
+		}
+	}
+
+	return to, nil
+}
+
+// copy the completed files of the previous version into the new version, files are
+// matched by their path and length. the copied data is verified before it is used,
+// so files that changed without changing length are downloaded as usual.
+func reuseMutable(from, to *torrent) error {
+	completed := make(map[string]*File, len(from.Files()))
+	for _, f := range from.Files() {
+		if f.Length() > 0 && f.BytesCompleted() == f.Length() {
+			completed[f.DisplayPath()] = f
+		}
+	}
+
+	verify := make([]Tuner, 0, len(completed))
+	for _, f := range to.Files() {
+		prev, ok := completed[f.DisplayPath()]
+		if !ok || prev.Length() != f.Length() {
+			continue
+		}
+
+		src := io.NewSectionReader(from.storage, prev.Offset(), prev.Length())
+		if _, err := io.Copy(io.NewOffsetWriter(to.storage, f.Offset()), src); err != nil {
+			return errorsx.Wrapf(err, "unable to copy %s", f.DisplayPath())
+		}
+
+		verify = append(verify, TuneVerifyRange(f.Offset(), f.Length()))
+	}
+
+	return to.Tune(verify...)
+}
@@ -0,0 +1,209 @@
+package torrent
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/dht"
+	"github.com/james-lawrence/torrent/dht/dhttest"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/metainfo"
+	"github.com/james-lawrence/torrent/sockets"
+)
+
+func TestMutable(t *testing.T) {
+	pk, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	m := Mutable{PublicKey: [32]byte(pk), Salt: []byte("nightly")}
+	parsed, err := ParseMutable(m.String())
+	require.NoError(t, err)
+	require.Equal(t, m, parsed)
+
+	_, err = ParseMutable("magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567")
+	require.Error(t, err)
+	_, err = ParseMutable("magnet:?xs=urn:btpk:0123")
+	require.Error(t, err)
+}
+
+// muxer of servers storing BEP 44 items.
+func mutableMuxer() dht.Muxer {
+	return dht.DefaultMuxer().Method("put", dht.Bep44Put{}).Method("get", dht.Bep44Get{})
+}
+
+func TestPublishMutable(t *testing.T) {
+	publisher := dhttest.NewServer(t, mutableMuxer())
+	storer := dhttest.NewServer(t, mutableMuxer())
+	follower := dhttest.NewServer(t, mutableMuxer())
+
+	for _, s := range []*dht.Server{publisher, follower} {
+		require.NoError(t, s.AddNode(dhttest.NodeInfo(storer)))
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	m := Mutable{PublicKey: [32]byte(key.Public().(ed25519.PublicKey)), Salt: []byte("nightly")}
+
+	_, _, err = ResolveMutable(t.Context(), follower, m)
+	require.Error(t, err)
+
+	for i, ih := range []metainfo.Hash{{1}, {2}} {
+		seq, err := PublishMutable(t.Context(), publisher, key, m.Salt, ih)
+		require.NoError(t, err)
+		require.EqualValues(t, i+1, seq)
+
+		resolved, latest, err := ResolveMutable(t.Context(), follower, m)
+		require.NoError(t, err)
+		require.Equal(t, ih, resolved)
+		require.Equal(t, seq, latest)
+
+		md, err := NewFromMutable(t.Context(), follower, m)
+		require.NoError(t, err)
+		require.Equal(t, int160.FromByteArray(ih), md.ID)
+	}
+}
+
+const mutablePieceLength = 16 * bytesx.KiB
+
+func random(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+// create a version of a mutable torrent from the files, when dir is provided the data
+// is stored where the file storage of a client rooted at dir expects it.
+func mutableVersion(t *testing.T, dir string, files map[string][]byte) Metadata {
+	root := t.TempDir()
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), data, 0600))
+	}
+
+	info, err := metainfo.NewFromPath(root, metainfo.OptionPieceLength(mutablePieceLength))
+	require.NoError(t, err)
+	md, err := NewFromInfo(info)
+	require.NoError(t, err)
+
+	if dir != "" {
+		require.NoError(t, os.Rename(root, filepath.Join(dir, md.ID.String())))
+	}
+
+	return md
+}
+
+func completedFiles(dl Torrent) map[string]int64 {
+	completed := make(map[string]int64)
+	for _, f := range dl.Files() {
+		completed[f.DisplayPath()] = f.BytesCompleted()
+	}
+	return completed
+}
+
+func TestReuseMutable(t *testing.T) {
+	dir := t.TempDir()
+	cl, err := NewClient(TestingConfig(t, dir))
+	require.NoError(t, err)
+	defer cl.Close()
+
+	start := func(md Metadata) *torrent {
+		tt, _, err := cl.start(md)
+		require.NoError(t, err)
+		require.NoError(t, Verify(t.Context(), tt))
+		return tt
+	}
+
+	unchanged := random(t, 2*mutablePieceLength)
+
+	// the previous version is completed within the storage of the client.
+	from := start(mutableVersion(t, dir, map[string][]byte{"a": unchanged, "b": random(t, mutablePieceLength)}))
+	require.Equal(t, from.Info().TotalLength(), from.BytesCompleted())
+
+	// the new version shifts the unchanged file and modifies the other.
+	to := start(mutableVersion(t, "", map[string][]byte{"0": random(t, mutablePieceLength), "a": unchanged, "b": random(t, mutablePieceLength)}))
+	require.Zero(t, to.BytesCompleted())
+
+	require.NoError(t, reuseMutable(from, to))
+	require.Equal(t, map[string]int64{"0": 0, "a": 2 * mutablePieceLength, "b": 0}, completedFiles(to))
+}
+
+func TestFollowMutable(t *testing.T) {
+	storer := dhttest.NewServer(t, mutableMuxer())
+	publisher := dhttest.NewServer(t, mutableMuxer())
+	require.NoError(t, publisher.AddNode(dhttest.NodeInfo(storer)))
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	m := Mutable{PublicKey: [32]byte(key.Public().(ed25519.PublicKey)), Salt: []byte("nightly")}
+
+	dir := t.TempDir()
+	seeder, err := Autosocket(t).Bind(NewClient(TestingConfig(t, dir, ClientConfigSeed(true))))
+	require.NoError(t, err)
+	defer seeder.Close()
+
+	// the seeder has the data of the first version, but only the info of the second.
+	unchanged := random(t, 2*mutablePieceLength)
+	v1 := mutableVersion(t, dir, map[string][]byte{"a": unchanged, "b": random(t, mutablePieceLength)})
+	v2 := mutableVersion(t, "", map[string][]byte{"0": random(t, mutablePieceLength), "a": unchanged, "b": random(t, mutablePieceLength)})
+	for _, md := range []Metadata{v1, v2} {
+		dl, _, err := seeder.Start(md)
+		require.NoError(t, err)
+		require.NoError(t, Verify(t.Context(), dl))
+	}
+
+	var addr string
+	seeder.eachListener(func(l sockets.Socket) bool {
+		addr = l.Addr().String()
+		return false
+	})
+
+	bootstrap := ClientConfigBootstrapFn(func(string) dht.StartingNodesGetter {
+		return func() ([]dht.Addr, error) { return []dht.Addr{dht.NewAddr(storer.Addr())}, nil }
+	})
+	follower, err := Autosocket(t, BinderOptionDHT).Bind(NewClient(TestingConfig(t, t.TempDir(), bootstrap)))
+	require.NoError(t, err)
+	defer follower.Close()
+	require.NotEmpty(t, follower.DhtServers())
+
+	_, err = PublishMutable(t.Context(), publisher, key, m.Salt, v1.ID.AsByteArray())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(t.Context(), 30*time.Second)
+	defer done()
+
+	var versions []Torrent
+	for dl, err := range follower.FollowMutable(ctx, m, MutableOptionInterval(50*time.Millisecond), MutableOptionMetadata(OptionPeers(addr)), MutableOptionTuners(TuneAutoDownload)) {
+		require.NoError(t, err)
+		versions = append(versions, dl)
+
+		if len(versions) == 2 {
+			break
+		}
+
+		require.Eventually(t, func() bool { return dl.BytesCompleted() == dl.Info().TotalLength() }, 10*time.Second, 10*time.Millisecond)
+		_, err = PublishMutable(t.Context(), publisher, key, m.Salt, v2.ID.AsByteArray())
+		require.NoError(t, err)
+	}
+
+	require.Len(t, versions, 2)
+	require.Equal(t, v1.ID, versions[0].Metadata().ID)
+	require.Equal(t, v2.ID, versions[1].Metadata().ID)
+
+	// the shared file is reused, the remaining data is unavailable from the seeder.
+	require.Equal(t, map[string]int64{"0": 0, "a": 2 * mutablePieceLength, "b": 0}, completedFiles(versions[1]))
+
+	// the previous version is stopped.
+	active := make([]int160.T, 0, 1)
+	for _, tt := range follower.torrents.active() {
+		active = append(active, tt.md.ID)
+	}
+	require.Equal(t, []int160.T{v2.ID}, active)
+}
@@ -0,0 +1,40 @@
+package torrent
+
+import (
+	"iter"
+
+	"github.com/james-lawrence/torrent/bep0051"
+	"github.com/james-lawrence/torrent/dht/int160"
+)
+
+// infohashes the client is willing to reveal to BEP 51 sample_infohashes queries,
+// the swarms of the active public torrents along with the infohashes announced to
+// the peer stores of the DHT servers. private torrents are never revealed (BEP 27).
+func (cl *Client) sampleable() iter.Seq[int160.T] {
+	return func(yield func(int160.T) bool) {
+		for _, t := range cl.torrents.active() {
+			if t.private() {
+				continue
+			}
+
+			for _, id := range t.md.Swarms() {
+				if !yield(id) {
+					return
+				}
+			}
+		}
+
+		for _, s := range cl.DhtServers() {
+			ps := s.PeerStore()
+			if ps == nil {
+				continue
+			}
+
+			for id := range bep0051.SourcePeerStore(ps)() {
+				if !yield(id) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package torrent
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/bep0051"
+	"github.com/james-lawrence/torrent/dht/krpc"
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/metainfo"
+	"github.com/james-lawrence/torrent/torrenttest"
+)
+
+func TestSampleInfohashes(t *testing.T) {
+	cl, err := NewClient(TestingConfig(t, t.TempDir(), ClientConfigSampleInfohashes(time.Minute)))
+	require.NoError(t, err)
+	defer cl.Close()
+
+	start := func(t *testing.T, options ...metainfo.Option) *torrent {
+		info, _, err := torrenttest.Random(t.TempDir(), 32, options...)
+		require.NoError(t, err)
+		md, err := NewFromInfo(info)
+		require.NoError(t, err)
+		tt, _, err := cl.start(md, TuneMaxConnections(0))
+		require.NoError(t, err)
+		return tt
+	}
+
+	public := start(t)
+	start(t, func(i *metainfo.Info) { i.Private = langx.Autoptr(true) })
+
+	// private torrents are never revealed.
+	require.Equal(t, public.md.Swarms(), slices.Collect(cl.sampleable()))
+
+	_, fn := cl.config.DHTMuxer.Handler(nil, &krpc.Msg{Q: bep0051.Query})
+	require.IsType(t, bep0051.Endpoint{}, fn)
+}
@@ -0,0 +1,300 @@
+package torrent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/v2"
+
+	pp "github.com/james-lawrence/torrent/btprotocol"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/merkle"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+// maximum number of hashes that can be requested in a single hash request.
+const hashRequestMaximum = 512
+
+var errPieceLayerUnavailable = errors.New("piece layer unavailable")
+
+func newPieceLayers(info *metainfo.Info, encoded metainfo.PieceLayers) *pieceLayers {
+	pl := &pieceLayers{
+		plength: info.PieceLength,
+		base:    pp.Integer(bits.TrailingZeros64(uint64(info.PieceLength / merkle.BlockSize))),
+		hashes:  make(map[metainfo.HashV2][]metainfo.HashV2),
+	}
+
+	for _, f := range info.FilesV2() {
+		if f.Length == 0 {
+			continue
+		}
+
+		pl.files = append(pl.files, f)
+
+		if f.Length <= info.PieceLength {
+			continue
+		}
+
+		n := (f.Length + info.PieceLength - 1) / info.PieceLength
+		if hashes, ok := encoded.Layer(f.Root()); ok && int64(len(hashes)) == n && metainfo.LayerRoot(info.PieceLength, hashes) == f.Root() {
+			pl.hashes[f.Root()] = hashes
+			continue
+		}
+
+		pl.hashes[f.Root()] = make([]metainfo.HashV2, n)
+	}
+
+	return pl
+}
+
+// pieceLayers tracks the BEP 52 piece layers of the files within a torrent,
+// they're used to verify pieces of v2 torrents. Missing layers are retrieved
+// from peers using hash requests.
+type pieceLayers struct {
+	mu      sync.RWMutex
+	plength int64
+	base    pp.Integer // the tree layer of the piece hashes, counted from the leaves.
+	files   []metainfo.FileV2
+	// piece layers of files larger than a single piece, unknown hashes are zero.
+	hashes map[metainfo.HashV2][]metainfo.HashV2
+}
+
+// returns the file containing the given piece.
+func (t *pieceLayers) file(pid uint64) (f metainfo.FileV2, ok bool) {
+	offset := int64(pid) * t.plength
+	i := sort.Search(len(t.files), func(i int) bool {
+		return t.files[i].Offset+t.files[i].Length > offset
+	})
+
+	if i == len(t.files) || t.files[i].Offset > offset {
+		return f, false
+	}
+
+	return t.files[i], true
+}
+
+// Verify the piece against the file's merkle tree, buf is used to read the piece.
+func (t *pieceLayers) Verify(src io.ReaderAt, p *metainfo.Piece, buf []byte) error {
+	f, ok := t.file(uint64(p.Index()))
+	if !ok {
+		return fmt.Errorf("piece %d is not part of any file", p.Index())
+	}
+
+	digest := merkle.New()
+	length := min(p.Length(), f.Offset+f.Length-p.Offset())
+	if err := digestRegion(digest, src, p.Offset(), length, buf); err != nil {
+		return errorsx.Wrapf(err, "piece %d", p.Index())
+	}
+
+	// files no larger than a piece are verified directly against their root.
+	if f.Length <= t.plength {
+		if computed := metainfo.HashV2(digest.Root(0)); computed != f.Root() {
+			return fmt.Errorf("piece %d digest mismatch %s != %s", p.Index(), computed, f.Root())
+		}
+
+		return nil
+	}
+
+	t.mu.RLock()
+	expected := t.hashes[f.Root()][(p.Offset()-f.Offset)/t.plength]
+	t.mu.RUnlock()
+
+	if expected.IsZero() {
+		return errPieceLayerUnavailable
+	}
+
+	if computed := metainfo.HashV2(digest.Root(int(t.plength / merkle.BlockSize))); computed != expected {
+		return fmt.Errorf("piece %d digest mismatch %s != %s", p.Index(), computed, expected)
+	}
+
+	return nil
+}
+
+// Requests generates the hash requests for the unknown portions of the piece layers.
+func (t *pieceLayers) Requests() (reqs []pp.Message) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, f := range t.files {
+		layer, ok := t.hashes[f.Root()]
+		if !ok {
+			continue
+		}
+
+		width := merkle.RoundUp(len(layer))
+		length := min(width, hashRequestMaximum)
+		proofs := bits.Len(uint(width)) - bits.Len(uint(length))
+		for idx := 0; idx < len(layer); idx += length {
+			if !hasZero(layer[idx:min(idx+length, len(layer))]) {
+				continue
+			}
+
+			reqs = append(reqs, pp.NewHashRequest(f.Root(), t.base, pp.Integer(idx), pp.Integer(length), pp.Integer(proofs)))
+		}
+	}
+
+	return reqs
+}
+
+// Hashes responds to a hash request, only requests for complete piece layers are served.
+func (t *pieceLayers) Hashes(req pp.Message) (hashes [][merkle.Size]byte, ok bool) {
+	t.mu.RLock()
+	layer, ok := t.hashes[req.PiecesRoot]
+	t.mu.RUnlock()
+
+	if !ok || hasZero(layer) || req.BaseLayer != t.base || !validHashRange(req, len(layer)) {
+		return nil, false
+	}
+
+	tree := merkle.Tree(leaves(layer), 0, merkle.Pad(int(t.plength/merkle.BlockSize)))
+	hashes = append(hashes, tree[0][req.Index:req.Index+req.Length]...)
+	hashes = append(hashes, merkle.Proof(tree, int(req.Index), int(req.Length), int(req.ProofLayers))...)
+
+	return hashes, true
+}
+
+// Receive the hashes from a peer, returning the pieces whose hashes were learned.
+func (t *pieceLayers) Receive(msg pp.Message) (learned *roaring.Bitmap, err error) {
+	var (
+		root = metainfo.HashV2(msg.PiecesRoot)
+	)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	layer, ok := t.hashes[root]
+	if !ok {
+		return nil, fmt.Errorf("received hashes for unknown pieces root %s", root)
+	}
+
+	if msg.BaseLayer != t.base || !validHashRange(msg, len(layer)) || len(msg.Hashes) < int(msg.Length) {
+		return nil, fmt.Errorf("received invalid hashes for %s", root)
+	}
+
+	if !merkle.VerifyProof(msg.PiecesRoot, msg.Hashes[:msg.Length], int(msg.Index), msg.Hashes[msg.Length:]) {
+		return nil, fmt.Errorf("received hashes for %s failed verification", root)
+	}
+
+	learned = roaring.New()
+	first := uint64(t.fileOffset(root) / t.plength)
+	for i := int(msg.Index); i < min(int(msg.Index+msg.Length), len(layer)); i++ {
+		if layer[i].IsZero() {
+			layer[i] = msg.Hashes[i-int(msg.Index)]
+			learned.Add(uint32(first) + uint32(i))
+		}
+	}
+
+	return learned, nil
+}
+
+func (t *pieceLayers) fileOffset(root metainfo.HashV2) int64 {
+	for _, f := range t.files {
+		if f.Root() == root {
+			return f.Offset
+		}
+	}
+
+	return 0
+}
+
+// Encode the known piece layers.
+func (t *pieceLayers) Encode() metainfo.PieceLayers {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	encoded := make(metainfo.PieceLayers, len(t.hashes))
+	for root, layer := range t.hashes {
+		if hasZero(layer) {
+			continue
+		}
+
+		encoded.Set(root, layer)
+	}
+
+	return encoded
+}
+
+func validHashRange(msg pp.Message, n int) bool {
+	length := int(msg.Length)
+	return length >= 2 && length <= hashRequestMaximum && length == merkle.RoundUp(length) &&
+		int(msg.Index)%length == 0 && int(msg.Index) < n && int(msg.Index)+length <= merkle.RoundUp(n)
+}
+
+func hasZero(hashes []metainfo.HashV2) bool {
+	for _, h := range hashes {
+		if h.IsZero() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func leaves(hashes []metainfo.HashV2) (converted [][merkle.Size]byte) {
+	converted = make([][merkle.Size]byte, 0, len(hashes))
+	for _, h := range hashes {
+		converted = append(converted, h)
+	}
+
+	return converted
+}
+
+// request the unknown piece layers from the peer.
+func (cn *connection) requestPieceLayers() error {
+	if cn.t.layers == nil || !cn.supported(pp.ExtensionBitV2) {
+		return nil
+	}
+
+	for _, req := range cn.t.layers.Requests() {
+		if _, err := cn.Post(req); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cn *connection) onHashRequest(msg pp.Message) error {
+	if cn.t.layers == nil {
+		_, err := cn.Post(pp.NewHashReject(msg))
+		return err
+	}
+
+	hashes, ok := cn.t.layers.Hashes(msg)
+	if !ok {
+		_, err := cn.Post(pp.NewHashReject(msg))
+		return err
+	}
+
+	_, err := cn.Post(pp.NewHashes(msg, hashes...))
+	return err
+}
+
+func (cn *connection) onHashes(msg pp.Message) error {
+	if cn.t.layers == nil {
+		return nil
+	}
+
+	learned, err := cn.t.layers.Receive(msg)
+	if err != nil {
+		return err
+	}
+
+	// hybrid torrents verify pieces using the v1 hashes.
+	if cn.t.info.HasV1() {
+		return nil
+	}
+
+	// pieces that were awaiting their hashes can now be verified.
+	for _, pid := range learned.ToArray() {
+		if cn.t.chunks.ChunksAvailable(uint64(pid)) && !cn.t.chunks.ChunksComplete(uint64(pid)) {
+			cn.t.digests.Enqueue(uint64(pid))
+		}
+	}
+
+	return nil
+}
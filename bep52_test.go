@@ -0,0 +1,90 @@
+package torrent
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	pp "github.com/james-lawrence/torrent/btprotocol"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/internal/cryptox"
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/internal/testutil"
+)
+
+func TestPieceLayers(t *testing.T) {
+	const plength = 16 * bytesx.KiB
+
+	root := t.TempDir()
+	large := make([]byte, 600*plength+7)
+	_, err := io.ReadFull(cryptox.NewChaCha8(t.Name()), large)
+	require.NoError(t, err)
+	small := large[:bytesx.KiB]
+	require.NoError(t, os.WriteFile(filepath.Join(root, "large.bin"), large, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "small.bin"), small, 0600))
+
+	info := testutil.InfoV2(t, root, plength)
+	data := bytes.NewReader(append(append(large, make([]byte, plength-7)...), small...))
+	require.Equal(t, info.TotalLength(), data.Size())
+
+	seeder := newPieceLayers(info, info.PieceLayers)
+	leecher := newPieceLayers(info, nil)
+
+	t.Run("verify", func(t *testing.T) {
+		for i := 0; i < int(info.NumPieces()); i++ {
+			require.NoError(t, seeder.Verify(data, langx.Autoptr(info.Piece(i)), nil), "piece %d", i)
+		}
+
+		require.ErrorIs(t, leecher.Verify(data, langx.Autoptr(info.Piece(0)), nil), errPieceLayerUnavailable)
+		// single piece files are verified against the pieces root.
+		require.NoError(t, leecher.Verify(data, langx.Autoptr(info.Piece(601)), nil))
+
+		corrupted := bytes.NewReader(append([]byte{0}, large[1:]...))
+		require.Error(t, seeder.Verify(corrupted, langx.Autoptr(info.Piece(0)), nil))
+	})
+
+	t.Run("unable to serve unknown layers", func(t *testing.T) {
+		for _, req := range leecher.Requests() {
+			_, ok := leecher.Hashes(req)
+			require.False(t, ok)
+		}
+	})
+
+	t.Run("reject tampered hashes", func(t *testing.T) {
+		req := leecher.Requests()[1]
+		hashes, ok := seeder.Hashes(req)
+		require.True(t, ok)
+		hashes[3][0] ^= 0xff
+
+		_, err := leecher.Receive(pp.NewHashes(req, hashes...))
+		require.Error(t, err)
+	})
+
+	t.Run("retrieve layers", func(t *testing.T) {
+		reqs := leecher.Requests()
+		require.Len(t, reqs, 2)
+
+		learned := 0
+		for _, req := range reqs {
+			require.Equal(t, pp.Integer(hashRequestMaximum), req.Length)
+			require.Equal(t, pp.Integer(1), req.ProofLayers)
+
+			hashes, ok := seeder.Hashes(req)
+			require.True(t, ok)
+			require.Len(t, hashes, hashRequestMaximum+1)
+
+			pieces, err := leecher.Receive(pp.NewHashes(req, hashes...))
+			require.NoError(t, err)
+			learned += int(pieces.GetCardinality())
+		}
+
+		require.Equal(t, 601, learned)
+		require.Empty(t, leecher.Requests())
+		require.Equal(t, seeder.Encode(), leecher.Encode())
+		require.NoError(t, leecher.Verify(data, langx.Autoptr(info.Piece(600)), nil))
+	})
+}
@@ -108,6 +108,33 @@ func (d *Decoder) Decode(msg *Message) (err error) {
 		return err
 	case Port:
 		return binary.Read(r, binary.BigEndian, &msg.Port)
+	case HashRequest, Hashes, HashReject:
+		if _, err = io.ReadFull(r, msg.PiecesRoot[:]); err != nil {
+			return err
+		}
+
+		for _, data := range []*Integer{&msg.BaseLayer, &msg.Index, &msg.Length, &msg.ProofLayers} {
+			if err = data.Read(r); err != nil {
+				return err
+			}
+		}
+
+		if msg.Type != Hashes {
+			return nil
+		}
+
+		if r.N%int64(len(msg.PiecesRoot)) != 0 {
+			return fmt.Errorf("hashes message has invalid length %d", length)
+		}
+
+		msg.Hashes = make([][32]byte, r.N/int64(len(msg.PiecesRoot)))
+		for i := range msg.Hashes {
+			if _, err = io.ReadFull(r, msg.Hashes[i][:]); err != nil {
+				return err
+			}
+		}
+
+		return nil
 	default:
 		return fmt.Errorf("unknown message type %#v", c)
 	}
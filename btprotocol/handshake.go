@@ -124,6 +124,7 @@ func (t *HandshakeInfoMessage) ReadFrom(src io.Reader) (n int64, err error) {
 const (
 	ExtensionBitDHT      uint = 0  // http://www.bittorrent.org/beps/bep_0005.html
 	ExtensionBitFast     uint = 2  // http://www.bittorrent.org/beps/bep_0006.html
+	ExtensionBitV2       uint = 4  // http://www.bittorrent.org/beps/bep_0052.html
 	ExtensionBitExtended uint = 20 // http://www.bittorrent.org/beps/bep_0010.html
 )
 
@@ -158,6 +159,11 @@ func (pex ExtensionBits) SupportsFast() bool {
 	return pex.GetBit(ExtensionBitFast)
 }
 
+// SupportsV2 ...
+func (pex ExtensionBits) SupportsV2() bool {
+	return pex.GetBit(ExtensionBitV2)
+}
+
 // SetBit ...
 func (pex *ExtensionBits) SetBit(bit uint) {
 	pex[7-bit/8] |= 1 << (bit % 8)
@@ -20,6 +20,11 @@ type Message struct {
 	ExtendedID           ExtensionNumber
 	ExtendedPayload      []byte
 	Port                 uint16
+	// BEP 52 hash request, hashes and hash reject fields, Index and Length
+	// are the offset and number of hashes within the base layer.
+	PiecesRoot             [32]byte
+	BaseLayer, ProofLayers Integer
+	Hashes                 [][32]byte
 }
 
 func MakeCancelMessage(piece, offset, length Integer) Message {
@@ -97,6 +102,21 @@ func (msg Message) MarshalBinary() (data []byte, err error) {
 			_, err = buf.Write(msg.ExtendedPayload)
 		case Port:
 			err = binary.Write(buf, binary.BigEndian, msg.Port)
+		case HashRequest, Hashes, HashReject:
+			buf.Write(msg.PiecesRoot[:])
+			for _, i := range []Integer{msg.BaseLayer, msg.Index, msg.Length, msg.ProofLayers} {
+				if err = binary.Write(buf, binary.BigEndian, i); err != nil {
+					return nil, err
+				}
+			}
+
+			if msg.Type != Hashes {
+				break
+			}
+
+			for _, h := range msg.Hashes {
+				buf.Write(h[:])
+			}
 		default:
 			err = fmt.Errorf("unknown message type: %v", msg.Type)
 		}
@@ -207,6 +227,31 @@ func NewPiece(index Integer, begin Integer, bin []byte) Message {
 	}
 }
 
+func NewHashRequest(root [32]byte, base, index, length, proofs Integer) Message {
+	return Message{
+		Type:        HashRequest,
+		PiecesRoot:  root,
+		BaseLayer:   base,
+		Index:       index,
+		Length:      length,
+		ProofLayers: proofs,
+	}
+}
+
+// NewHashes responds to the hash request with the given hashes.
+func NewHashes(req Message, hashes ...[32]byte) Message {
+	req.Type = Hashes
+	req.Hashes = hashes
+	return req
+}
+
+// NewHashReject rejects the hash request.
+func NewHashReject(req Message) Message {
+	req.Type = HashReject
+	req.Hashes = nil
+	return req
+}
+
 func NewHavePiece(p uint64) Message {
 	return Message{
 		Type:  Have,
@@ -36,6 +36,11 @@ const (
 
 	// BEP 10
 	Extended MessageType = 0x14 // 20
+
+	// BEP 52
+	HashRequest MessageType = 0x15 // 21
+	Hashes      MessageType = 0x16 // 22
+	HashReject  MessageType = 0x17 // 23
 )
 
 const (
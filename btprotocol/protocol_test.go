@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBinaryReadSliceOfPointers(t *testing.T) {
@@ -152,3 +153,20 @@ func TestUnmarshalPortMsg(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestHashesMsgRoundTrip(t *testing.T) {
+	req := NewHashRequest([32]byte{1, 2, 3}, 2, 4, 2, 1)
+
+	for _, msg := range []Message{req, NewHashes(req, [32]byte{4}, [32]byte{5}, [32]byte{6}), NewHashReject(req)} {
+		b, err := msg.MarshalBinary()
+		require.NoError(t, err)
+
+		var decoded Message
+		d := Decoder{
+			R:         bufio.NewReader(bytes.NewBuffer(b)),
+			MaxLength: 256,
+		}
+		require.NoError(t, d.Decode(&decoded))
+		require.Equal(t, msg, decoded)
+	}
+}
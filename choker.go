@@ -0,0 +1,296 @@
+package torrent
+
+import (
+	"cmp"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/langx"
+)
+
+const (
+	// how frequently the choker reevaluates the peers of a torrent.
+	chokeInterval = 10 * time.Second
+	// number of rounds an optimistic unchoke lasts before it's rotated.
+	optimisticRounds = 3
+	// default number of upload slots, including the optimistic unchoke.
+	defaultUploadSlots = 8
+)
+
+// ChokeState of a peer as decided by a Choker.
+type ChokeState uint8
+
+const (
+	// ChokeStateChoked the peer is not allowed to make requests.
+	ChokeStateChoked ChokeState = iota
+	// ChokeStateUnchoked the peer was granted an upload slot.
+	ChokeStateUnchoked
+	// ChokeStateOptimistic the peer was optimistically unchoked.
+	ChokeStateOptimistic
+)
+
+func (s ChokeState) String() string {
+	switch s {
+	case ChokeStateUnchoked:
+		return "unchoked"
+	case ChokeStateOptimistic:
+		return "optimistic"
+	default:
+		return "choked"
+	}
+}
+
+// ChokePeer is a snapshot of a connection provided to the Choker.
+type ChokePeer struct {
+	ID         int160.T
+	Addr       netip.AddrPort
+	Interested bool       // the peer is interested in our data.
+	Complete   bool       // the peer has all the data, it has no need for an upload slot.
+	State      ChokeState // the state decided by the previous round.
+	Rounds     int        // number of periodic rounds the peer has been in its current state.
+	Downloaded int64      // bytes of useful data received from the peer during the previous interval.
+	Uploaded   int64      // bytes of data sent to the peer during the previous interval.
+}
+
+// Choker decides which peers of a torrent are allowed to make requests. It's
+// consulted periodically, and whenever peers connect or become interested.
+type Choker interface {
+	// Choke returns the state of each of the peers, in the same order.
+	Choke(seeding bool, peers []ChokePeer) []ChokeState
+}
+
+// NewChoker creates the default tit-for-tat choker with the given number of upload
+// slots, while seeding peers are ranked by how fast we upload to them.
+func NewChoker(slots int) Choker {
+	return ChokerTitForTat{
+		Slots:   slots,
+		Seeding: ChokerSeeding{Slots: slots},
+	}
+}
+
+// ChokerTitForTat grants upload slots to the peers we download from the fastest.
+// One of the slots is reserved for an optimistic unchoke, which rotates amongst
+// the choked peers every few rounds to discover better peers. Slots <= 0 unchokes
+// every peer.
+type ChokerTitForTat struct {
+	Slots int
+	// choker to use while seeding, when nil peers are ranked by download rate.
+	Seeding Choker
+}
+
+func (t ChokerTitForTat) Choke(seeding bool, peers []ChokePeer) []ChokeState {
+	if seeding && t.Seeding != nil {
+		return t.Seeding.Choke(seeding, peers)
+	}
+
+	return chokerank(t.Slots, peers, func(p ChokePeer) int64 { return p.Downloaded })
+}
+
+// ChokerSeeding grants upload slots to the peers we upload to the fastest, along
+// with an optimistic unchoke. Slots <= 0 unchokes every peer.
+type ChokerSeeding struct {
+	Slots int
+}
+
+func (t ChokerSeeding) Choke(_ bool, peers []ChokePeer) []ChokeState {
+	return chokerank(t.Slots, peers, func(p ChokePeer) int64 { return p.Uploaded })
+}
+
+// unchokes the highest ranked peers, interested peers are preferred. the peer
+// choked the longest is optimistically unchoked.
+func chokerank(slots int, peers []ChokePeer, rate func(ChokePeer) int64) []ChokeState {
+	states := make([]ChokeState, len(peers))
+
+	candidates := make([]int, 0, len(peers))
+	for i, p := range peers {
+		if p.Complete {
+			continue
+		}
+
+		if slots <= 0 {
+			states[i] = ChokeStateUnchoked
+			continue
+		}
+
+		candidates = append(candidates, i)
+	}
+
+	if len(candidates) == 0 {
+		return states
+	}
+
+	// retain the optimistic unchoke until its rotated.
+	optimistic := -1
+	for _, i := range candidates {
+		if peers[i].State == ChokeStateOptimistic && peers[i].Rounds < optimisticRounds {
+			optimistic = i
+			break
+		}
+	}
+
+	regular := slots
+	if slots > 1 {
+		regular--
+	}
+
+	slices.SortStableFunc(candidates, func(a, b int) int {
+		if peers[a].Interested != peers[b].Interested {
+			if peers[a].Interested {
+				return -1
+			}
+			return 1
+		}
+
+		return cmp.Compare(rate(peers[b]), rate(peers[a]))
+	})
+
+	choked := make([]int, 0, len(candidates))
+	for _, i := range candidates {
+		if i == optimistic {
+			continue
+		}
+
+		if regular > 0 {
+			states[i] = ChokeStateUnchoked
+			regular--
+			continue
+		}
+
+		choked = append(choked, i)
+	}
+
+	if slots > 1 && optimistic == -1 && len(choked) > 0 {
+		optimistic = slices.MaxFunc(choked, func(a, b int) int {
+			if peers[a].Interested != peers[b].Interested {
+				if peers[a].Interested {
+					return 1
+				}
+				return -1
+			}
+
+			return cmp.Compare(chokedRounds(peers[a]), chokedRounds(peers[b]))
+		})
+	}
+
+	if optimistic != -1 {
+		states[optimistic] = ChokeStateOptimistic
+	}
+
+	return states
+}
+
+func chokedRounds(p ChokePeer) int {
+	if p.State != ChokeStateChoked {
+		return 0
+	}
+
+	return p.Rounds
+}
+
+// request the choker reevaluates the torrent's peers. peers remain choked until
+// we have data to offer them, so the choker is only started once we do.
+func (t *torrent) rechoke() {
+	if t.chunks.Readable() == 0 {
+		return
+	}
+
+	t.chokerstart.Do(func() {
+		go t.chokerloop()
+	})
+
+	select {
+	case t.chokeEvent <- struct{}{}:
+	default:
+	}
+}
+
+func (t *torrent) chokerloop() {
+	ticker := time.NewTicker(chokeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.closed:
+			return
+		case <-ticker.C:
+			t.choke(true)
+		case <-t.chokeEvent:
+			t.choke(false)
+		}
+	}
+}
+
+// evaluate the connections using the choker, periodic rounds update the transfer
+// rates of the peers and the number of rounds they've spent in their state.
+func (t *torrent) choke(periodic bool) {
+	t.chokemu.Lock()
+	defer t.chokemu.Unlock()
+
+	conns := t.conns.list()
+	cmaximum := uint64(t.chunks.cmaximum)
+	peers := make([]ChokePeer, 0, len(conns))
+	for _, c := range conns {
+		if periodic {
+			read, written := c.stats.BytesReadUsefulData.Int64(), c.stats.BytesWrittenData.Int64()
+			c.choking.Downloaded, c.choking.Uploaded = read-c.chokingread, written-c.chokingwritten
+			c.chokingread, c.chokingwritten = read, written
+			c.choking.Rounds++
+		}
+
+		c._mu.RLock()
+		c.choking.ID = c.PeerID
+		c.choking.Addr = c.remoteAddr
+		c.choking.Interested = c.PeerInterested
+		c.choking.Complete = c.peerSentHaveAll || (cmaximum > 0 && c.claimed.GetCardinality() >= cmaximum)
+		c._mu.RUnlock()
+
+		peers = append(peers, c.choking)
+	}
+
+	// peers remain choked until we have data to offer them, and peers that stopped
+	// sending us chunks remain choked until their back off expires.
+	states := make([]ChokeState, len(peers))
+	if t.chunks.Readable() > 0 {
+		now := time.Now()
+		eligible := make([]int, 0, len(conns))
+		candidates := make([]ChokePeer, 0, len(conns))
+		for i, c := range conns {
+			if langx.Autoderef(c.chokeduntil.Load()).After(now) {
+				continue
+			}
+
+			eligible = append(eligible, i)
+			candidates = append(candidates, peers[i])
+		}
+
+		for i, s := range t.choker().Choke(t.seeding(), candidates) {
+			states[eligible[i]] = s
+		}
+	}
+
+	for i, c := range conns {
+		if states[i] != c.choking.State {
+			c.choking.State = states[i]
+			c.choking.Rounds = 0
+		}
+		peers[i] = c.choking
+
+		if c.unchoked.Swap(states[i] != ChokeStateChoked) != (states[i] != ChokeStateChoked) {
+			t.cln.config.debug().Printf("c(%p) seed(%t) choker %s\n", c, t.seeding(), states[i])
+			c.upload.Broadcast()
+			c.request.Broadcast()
+		}
+	}
+
+	t.choking = peers
+}
+
+func (t *torrent) choker() Choker {
+	if c := t.cln.config.choker; c != nil {
+		return c
+	}
+
+	return NewChoker(defaultUploadSlots)
+}
@@ -0,0 +1,127 @@
+package torrent
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+// apply a choker's decisions to the peers as the torrent would for a periodic round.
+func chokeround(c Choker, seeding bool, peers []ChokePeer) []ChokeState {
+	for i := range peers {
+		peers[i].Rounds++
+	}
+
+	states := c.Choke(seeding, peers)
+	for i := range peers {
+		if peers[i].State != states[i] {
+			peers[i].State, peers[i].Rounds = states[i], 0
+		}
+	}
+
+	return states
+}
+
+func TestChoker(t *testing.T) {
+	t.Run("unchoke the fastest peers", func(t *testing.T) {
+		peers := []ChokePeer{
+			{Interested: true, Downloaded: 10},
+			{Interested: true, Downloaded: 40},
+			{Interested: true, Downloaded: 30},
+			{Interested: true, Downloaded: 20},
+			{Interested: false, Downloaded: 50},
+		}
+
+		// uninterested peers are considered last.
+		require.Equal(t, []ChokeState{
+			ChokeStateChoked,
+			ChokeStateUnchoked,
+			ChokeStateUnchoked,
+			ChokeStateOptimistic,
+			ChokeStateChoked,
+		}, NewChoker(3).Choke(false, peers))
+	})
+
+	t.Run("seeding ranks by upload", func(t *testing.T) {
+		peers := []ChokePeer{
+			{Interested: true, Downloaded: 100, Uploaded: 1},
+			{Interested: true, Uploaded: 50},
+			{Interested: true, Uploaded: 20},
+		}
+
+		require.Equal(t, []ChokeState{ChokeStateChoked, ChokeStateUnchoked, ChokeStateOptimistic}, NewChoker(2).Choke(true, peers))
+		require.Equal(t, []ChokeState{ChokeStateUnchoked, ChokeStateOptimistic, ChokeStateChoked}, NewChoker(2).Choke(false, peers))
+	})
+
+	t.Run("complete peers remain choked", func(t *testing.T) {
+		peers := []ChokePeer{
+			{Interested: true, Complete: true, Downloaded: 100},
+			{Interested: true},
+		}
+
+		require.Equal(t, []ChokeState{ChokeStateChoked, ChokeStateUnchoked}, NewChoker(4).Choke(false, peers))
+		require.Equal(t, []ChokeState{ChokeStateChoked, ChokeStateUnchoked}, NewChoker(0).Choke(false, peers))
+	})
+
+	t.Run("rotate the optimistic unchoke", func(t *testing.T) {
+		peers := make([]ChokePeer, 5)
+		for i := range peers {
+			peers[i] = ChokePeer{Interested: true, Downloaded: int64(100 - i)}
+		}
+
+		optimistic := map[int]int{}
+		for range 4 * optimisticRounds * (len(peers) - 1) {
+			states := chokeround(NewChoker(2), false, peers)
+			require.Equal(t, ChokeStateUnchoked, states[0])
+			for i, s := range states {
+				if s == ChokeStateOptimistic {
+					optimistic[i]++
+				}
+			}
+		}
+
+		// every choked peer is eventually given a chance for the same number of rounds.
+		require.Equal(t, map[int]int{1: 12, 2: 12, 3: 12, 4: 12}, optimistic)
+	})
+}
+
+func TestTorrentChokeBackoff(t *testing.T) {
+	cl := &Client{config: TestingConfig(t, t.TempDir())}
+	md, err := New(metainfo.Hash{})
+	require.NoError(t, err)
+	tt := newTorrent(cl, md)
+	require.NoError(t, tt.setInfo(&metainfo.Info{
+		Pieces:      make([]byte, metainfo.HashSize*3),
+		Length:      24 * bytesx.KiB,
+		PieceLength: 8 * bytesx.KiB,
+	}))
+
+	conns := make([]*connection, 2)
+	for i := range conns {
+		conns[i] = cl.newConnection(nil, false, netip.AddrPortFrom(netip.IPv4Unspecified(), uint16(i+1)))
+		conns[i].setTorrent(tt)
+		conns[i].PeerInterested = true
+		tt.conns.insert(conns[i])
+	}
+
+	// the choker isn't consulted until there is data to upload.
+	tt.rechoke()
+	require.Empty(t, tt.chokeEvent)
+
+	tt.chunks.Complete(0)
+	conns[1].chokeduntil.Store(langx.Autoptr(time.Now().Add(time.Hour)))
+	tt.choke(false)
+	require.True(t, conns[0].unchoked.Load())
+	require.False(t, conns[1].unchoked.Load(), "peers remain choked while backing off")
+
+	conns[1].chokeduntil.Store(langx.Autoptr(time.Now().Add(-time.Second)))
+	tt.choke(false)
+	require.True(t, conns[0].unchoked.Load())
+	require.True(t, conns[1].unchoked.Load())
+}
@@ -1,8 +1,11 @@
 package torrent
 
 import (
+	"cmp"
 	"fmt"
 	"math"
+	"math/rand/v2"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -97,18 +100,21 @@ func newChunks(clength uint64, m *metainfo.Info, options ...chunkopt) *chunks {
 	}
 
 	p := langx.Autoptr(langx.Clone(chunks{
-		cond:        sync.NewCond(&sync.Mutex{}),
-		mu:          &sync.RWMutex{},
-		meta:        m,
-		pieces:      uint64(m.NumPieces()),
-		cmaximum:    numChunks(m.TotalLength(), m.PieceLength, int64(clength)),
-		clength:     int64(clength),
-		gracePeriod: 2 * time.Minute,
-		outstanding: make(map[uint64]request),
-		missing:     roaring.New(),
-		unverified:  roaring.New(),
-		failed:      roaring.New(),
-		completed:   roaring.New(),
+		cond:         sync.NewCond(&sync.Mutex{}),
+		mu:           &sync.RWMutex{},
+		meta:         m,
+		pieces:       uint64(m.NumPieces()),
+		cmaximum:     numChunks(m.TotalLength(), m.PieceLength, int64(clength)),
+		clength:      int64(clength),
+		gracePeriod:  2 * time.Minute,
+		outstanding:  make(map[uint64]request),
+		duplicated:   make(map[uint64]struct{}),
+		missing:      roaring.New(),
+		unverified:   roaring.New(),
+		failed:       roaring.New(),
+		completed:    roaring.New(),
+		availability: make([]atomic.Int32, m.NumPieces()),
+		salt:         rand.Uint64(),
 		pool: &sync.Pool{
 			New: func() interface{} {
 				b := make([]byte, clength)
@@ -161,6 +167,20 @@ type chunks struct {
 	// next time to reap the outstanding requests
 	nextReap time.Time
 
+	// outstanding requests that have been requested from multiple peers.
+	duplicated map[uint64]struct{}
+
+	// number of peers claiming each piece, peers that have every piece are
+	// tracked separately by seeds since they do not alter the rarity of a piece.
+	availability []atomic.Int32
+	seeds        atomic.Int32
+	// randomizes the order of equally available pieces.
+	salt uint64
+
+	// chunk indices grouped by their download priority, chunks without an
+	// explicit priority are normal priority.
+	priorities map[Priority]*roaring.Bitmap
+
 	// buffer pool for storing chunks
 	pool *sync.Pool
 
@@ -276,24 +296,172 @@ func (t *chunks) peekn(available *roaring.Bitmap, dst []peeked) (int, error) {
 	union := available.Clone()
 	union.And(t.missing)
 
+	if skipped, ok := t.priorities[PrioritySkip]; ok {
+		union.AndNot(skipped)
+	}
+
 	if union.IsEmpty() {
 		return 0, empty{Outstanding: len(t.outstanding), Missing: int(t.missing.GetCardinality())}
 	}
 
-	it := union.Iterator()
 	i := 0
-	for ; i < len(dst) && it.HasNext(); i++ {
-		ucidx := it.Next()
-		cidx := int64(ucidx)
-		req, reqerr := t.request(cidx)
-		if reqerr != nil {
-			return i, reqerr
+	for _, level := range []struct {
+		p    Priority
+		peek func(*roaring.Bitmap, []peeked) (int, error)
+	}{
+		// urgent chunks are being waited on by readers, so they're retrieved in order.
+		{p: PriorityUrgent, peek: t.peekordered},
+		{p: PriorityHigh, peek: t.peekinto},
+	} {
+		prioritized, ok := t.priorities[level.p]
+		if !ok {
+			continue
+		}
+
+		subset := roaring.And(union, prioritized)
+		union.AndNot(subset)
+
+		n, err := level.peek(subset, dst[i:])
+		if i += n; err != nil {
+			return i, err
+		}
+	}
+
+	n, err := t.peekinto(union, dst[i:])
+	return i + n, err
+}
+
+// fills dst with chunks from the bitmap in offset order.
+func (t *chunks) peekordered(b *roaring.Bitmap, dst []peeked) (i int, _ error) {
+	for it := b.Iterator(); i < len(dst) && it.HasNext(); i++ {
+		cidx := int64(it.Next())
+		req, err := t.request(cidx)
+		if err != nil {
+			return i, err
 		}
 		dst[i] = peeked{cidx: cidx, req: req}
 	}
+
 	return i, nil
 }
 
+// fills dst with chunks from the bitmap, rarest pieces first.
+func (t *chunks) peekinto(b *roaring.Bitmap, dst []peeked) (i int, _ error) {
+	for _, pid := range t.rarest(b, len(dst)) {
+		cmin, cmax := t.Range(pid)
+		it := b.Iterator()
+		it.AdvanceIfNeeded(uint32(cmin))
+		for ; i < len(dst) && it.HasNext() && uint64(it.PeekNext()) < cmax; i++ {
+			cidx := int64(it.Next())
+			req, reqerr := t.request(cidx)
+			if reqerr != nil {
+				return i, reqerr
+			}
+			dst[i] = peeked{cidx: cidx, req: req}
+		}
+	}
+
+	return i, nil
+}
+
+// maximum number of pieces examined when selecting the rarest pieces, this bounds
+// the cost of each request batch for torrents with many pieces.
+const rarestScanMaximum = 1024
+
+// returns up to n pieces with chunks in the bitmap ordered by their availability,
+// equally available pieces are ordered randomly. at most rarestScanMaximum pieces
+// are examined, large bitmaps are examined from a random piece onwards.
+func (t *chunks) rarest(b *roaring.Bitmap, n int) []uint64 {
+	type ranked struct {
+		pid  uint64
+		rank uint64
+	}
+
+	byrank := func(a, b ranked) int {
+		return cmp.Compare(a.rank, b.rank)
+	}
+
+	if n <= 0 || b.IsEmpty() {
+		return nil
+	}
+
+	cpp := uint64(chunksPerPiece(t.meta.PieceLength, t.clength))
+	selected := make([]ranked, 0, n+1)
+	scan := func(it roaring.IntPeekable, end uint64, limit int) (scanned int) {
+		for ; scanned < limit && it.HasNext() && uint64(it.PeekNext()) < end; scanned++ {
+			pid := uint64(it.Next()) / cpp
+			it.AdvanceIfNeeded(uint32((pid + 1) * cpp))
+
+			r := ranked{pid: pid, rank: uint64(t.rarity(pid))<<32 | t.tiebreak(pid)}
+			if len(selected) == n && byrank(r, selected[n-1]) >= 0 {
+				continue
+			}
+
+			idx, _ := slices.BinarySearchFunc(selected, r, byrank)
+			selected = slices.Insert(selected, idx, r)
+			selected = selected[:min(len(selected), n)]
+		}
+
+		return scanned
+	}
+
+	start := uint64(b.Minimum())
+	if span := uint64(b.Maximum()) - start; span/cpp >= rarestScanMaximum {
+		start = (start + rand.Uint64N(span)) / cpp * cpp
+	}
+
+	it := b.Iterator()
+	it.AdvanceIfNeeded(uint32(start))
+	scanned := scan(it, math.MaxUint64, rarestScanMaximum)
+	// wrap around to the pieces before the starting piece.
+	scan(b.Iterator(), start, rarestScanMaximum-scanned)
+
+	pids := make([]uint64, 0, len(selected))
+	for _, r := range selected {
+		pids = append(pids, r.pid)
+	}
+
+	return pids
+}
+
+// number of peers with the given piece, excluding seeds.
+func (t *chunks) rarity(pid uint64) int32 {
+	if pid >= uint64(len(t.availability)) {
+		return 0
+	}
+
+	return t.availability[pid].Load()
+}
+
+// splitmix64 finalizer of the salted piece id.
+func (t *chunks) tiebreak(pid uint64) uint64 {
+	z := pid + t.salt + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return (z ^ (z >> 31)) >> 32
+}
+
+// Claimed adjusts the number of peers that have the given pieces.
+func (t *chunks) Claimed(delta int32, pids ...uint64) {
+	for _, pid := range pids {
+		if pid >= uint64(len(t.availability)) {
+			continue
+		}
+
+		t.availability[pid].Add(delta)
+	}
+}
+
+// ClaimedAll adjusts the number of peers that have every piece.
+func (t *chunks) ClaimedAll(delta int32) {
+	t.seeds.Add(delta)
+}
+
+// Availability returns the number of peers that have the given piece.
+func (t *chunks) Availability(pid uint64) int {
+	return int(t.rarity(pid) + t.seeds.Load())
+}
+
 func (t *chunks) peek(available *roaring.Bitmap) (cidx int, req request, err error) {
 	var buf [1]peeked
 	n, err := t.peekn(available, buf[:])
@@ -342,6 +510,136 @@ func (t *chunks) Intersects(a, b *roaring.Bitmap) bool {
 	return a.Intersects(b)
 }
 
+// Wanted returns true if the bitmap contains any missing chunks that are not being skipped.
+func (t *chunks) Wanted(a *roaring.Bitmap) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	wanted := roaring.And(a, t.missing)
+	if skipped, ok := t.priorities[PrioritySkip]; ok {
+		wanted.AndNot(skipped)
+	}
+
+	return !wanted.IsEmpty()
+}
+
+// Endgame returns up to n outstanding requests within the available set once the
+// remaining chunks are at or below the threshold. these requests are expected to
+// be requested from an additional peer.
+func (t *chunks) Endgame(threshold int, n int, available *roaring.Bitmap) (reqs []request) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if n <= 0 || len(t.outstanding) == 0 {
+		return nil
+	}
+
+	wanted := t.missing.Clone()
+	if skipped, ok := t.priorities[PrioritySkip]; ok {
+		wanted.AndNot(skipped)
+	}
+
+	if int(wanted.GetCardinality())+len(t.outstanding) > threshold {
+		return nil
+	}
+
+	for _, r := range t.outstanding {
+		if len(reqs) >= n {
+			break
+		}
+
+		if !available.ContainsInt(t.requestCID(r)) {
+			continue
+		}
+
+		reqs = append(reqs, r)
+	}
+
+	return reqs
+}
+
+// Duplicated records the requests were requested from an additional peer.
+func (t *chunks) Duplicated(reqs ...request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, r := range reqs {
+		t.duplicated[r.Digest] = struct{}{}
+	}
+}
+
+// Deduplicate returns true if the request had been requested from multiple peers,
+// clearing the record.
+func (t *chunks) Deduplicate(r request) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.duplicated[r.Digest]
+	delete(t.duplicated, r.Digest)
+	return ok
+}
+
+// Want marks the provided chunks as missing unless they're outstanding, awaiting
+// verification, or already completed.
+func (t *chunks) Want(b *roaring.Bitmap) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.want(b)
+}
+
+func (t *chunks) want(b *roaring.Bitmap) {
+	for it := b.Iterator(); it.HasNext(); {
+		cidx := it.Next()
+		if t.unverified.Contains(cidx) || t.completed.ContainsInt(t.pindex(int(cidx))) {
+			continue
+		}
+
+		req, err := t.request(int64(cidx))
+		if err != nil {
+			continue
+		}
+
+		if _, ok := t.outstanding[req.Digest]; ok {
+			continue
+		}
+
+		t.missing.Add(cidx)
+	}
+}
+
+// Prioritize replaces the chunk priorities, when a chunk is present in
+// multiple levels the highest priority wins. skipped chunks are no longer
+// missing, and chunks that are no longer skipped are wanted again.
+func (t *chunks) Prioritize(levels map[Priority]*roaring.Bitmap) {
+	claimed := roaring.New()
+	normalized := make(map[Priority]*roaring.Bitmap, len(levels))
+	for _, p := range []Priority{PriorityUrgent, PriorityHigh, PriorityNormal, PrioritySkip} {
+		b, ok := levels[p]
+		if !ok {
+			continue
+		}
+
+		b = roaring.AndNot(b, claimed)
+		claimed.Or(b)
+		normalized[p] = b
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	skipped, ok := normalized[PrioritySkip]
+	if !ok {
+		skipped = roaring.New()
+	}
+
+	if previous, ok := t.priorities[PrioritySkip]; ok {
+		t.want(roaring.AndNot(previous, skipped))
+	}
+
+	t.missing.AndNot(skipped)
+	t.priorities = normalized
+}
+
 // used to clone bitmaps owned by chunks safely
 func (t *chunks) Clone(a *roaring.Bitmap) *roaring.Bitmap {
 	t.mu.RLock()
@@ -554,8 +852,8 @@ func (t *chunks) retry(r request) {
 	cidx := t.requestCID(r)
 
 	delete(t.outstanding, r.Digest)
+	delete(t.duplicated, r.Digest)
 	t.missing.AddInt(cidx)
-
 }
 
 func (t *chunks) release(r request) bool {
@@ -620,7 +918,13 @@ func (t *chunks) Incomplete() bool {
 	}
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	return (int(t.missing.GetCardinality()) + len(t.outstanding) + int(t.unverified.GetCardinality())) > 0
+
+	missing := t.missing.GetCardinality()
+	if skipped, ok := t.priorities[PrioritySkip]; ok {
+		missing -= t.missing.AndCardinality(skipped)
+	}
+
+	return (int(missing) + len(t.outstanding) + int(t.unverified.GetCardinality())) > 0
 }
 
 func (t *chunks) Snapshot(s *Stats) *Stats {
@@ -631,6 +935,7 @@ func (t *chunks) Snapshot(s *Stats) *Stats {
 	s.Unverified = int(t.unverified.GetCardinality())
 	s.Failed = int(t.failed.GetCardinality())
 	s.Completed = int(t.completed.GetCardinality())
+	s.Endgame = len(t.duplicated)
 	return s
 }
 
@@ -1,6 +1,7 @@
 package torrent
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -48,6 +49,16 @@ func fromFile(path string) (info metainfo.Info, err error) {
 	return info, nil
 }
 
+// returns the distinct pieces of the requests in the order they first appear.
+func requestPieces(reqs ...request) (pids []int) {
+	for _, r := range reqs {
+		if len(pids) == 0 || pids[len(pids)-1] != int(r.Index) {
+			pids = append(pids, int(r.Index))
+		}
+	}
+	return pids
+}
+
 func quickpopulate(p *chunks) *chunks {
 	p.fill(p.missing, uint64(p.cmaximum))
 	return p
@@ -331,7 +342,7 @@ func TestChunksFailed(t *testing.T) {
 
 	require.Equal(t, 13, c.Cardinality(c.missing))
 
-	reqs, err := c.Pop(5, c.missing.Clone())
+	reqs, err := c.Pop(5, bitmapx.Range(c.Range(0)))
 	require.NoError(t, err)
 	for _, r := range reqs {
 		touched.AddInt(c.requestCID(r))
@@ -349,21 +360,88 @@ func TestChunksPop(t *testing.T) {
 	require.NoError(t, err)
 	p := quickpopulate(newChunks(uint64(info.PieceLength), &info))
 
-	reqs, err := p.Pop(1, p.missing.Clone())
+	first, err := p.Pop(1, p.missing.Clone())
 	require.NoError(t, err)
-	for _, req := range reqs {
-		require.Equal(t, 0, int(req.Index))
+	require.Len(t, first, 1)
+	for _, req := range first {
 		require.Equal(t, true, req.Reserved.Before(time.Now()))
 	}
 
-	reqs, err = p.Pop(1, p.missing.Clone())
+	second, err := p.Pop(1, p.missing.Clone())
 	require.NoError(t, err)
-	for _, req := range reqs {
-		require.Equal(t, 1, int(req.Index))
+	require.Len(t, second, 1)
+	for _, req := range second {
+		require.NotEqual(t, first[0].Index, req.Index)
 		require.Equal(t, true, req.Reserved.Before(time.Now()))
 	}
 }
 
+func TestChunksRarest(t *testing.T) {
+	t.Run("least available pieces are popped first", func(t *testing.T) {
+		p := quickpopulate(newChunks(bytesx.KiB, tinyTorrentInfo()))
+		for pid := range p.pieces {
+			p.Claimed(2, pid)
+		}
+		p.Claimed(-1, 9, 3)
+		p.Claimed(-2, 12)
+		p.Claimed(1, 0)
+
+		reqs, err := p.Pop(3, bitmapx.Fill(p.cmaximum))
+		require.NoError(t, err)
+		pids := requestPieces(reqs...)
+		require.Equal(t, 12, pids[0])
+		require.ElementsMatch(t, []int{3, 9}, pids[1:])
+	})
+
+	t.Run("equally available pieces are randomly ordered", func(t *testing.T) {
+		orders := make(map[string]struct{})
+		for range 8 {
+			p := quickpopulate(newChunks(bytesx.KiB, tinyTorrentInfo()))
+			reqs, err := p.Pop(int(p.cmaximum), bitmapx.Fill(p.cmaximum))
+			require.NoError(t, err)
+			require.Len(t, reqs, int(p.cmaximum))
+			orders[fmt.Sprint(requestPieces(reqs...))] = struct{}{}
+		}
+		require.Greater(t, len(orders), 1)
+	})
+
+	t.Run("priority overrides availability", func(t *testing.T) {
+		p := quickpopulate(newChunks(bytesx.KiB, tinyTorrentInfo()))
+		p.Claimed(1, 5)
+		p.Prioritize(map[Priority]*roaring.Bitmap{
+			PriorityUrgent: bitmapx.Range(5, 6),
+		})
+
+		reqs, err := p.Pop(1, bitmapx.Fill(p.cmaximum))
+		require.NoError(t, err)
+		require.Equal(t, []int{5}, requestPieces(reqs...))
+	})
+
+	t.Run("large torrents examine a bounded window of pieces", func(t *testing.T) {
+		p := newChunks(bytesx.KiB, torrentInfoN(4*rarestScanMaximum*bytesx.KiB, bytesx.KiB))
+		available := bitmapx.Fill(p.cmaximum)
+		require.Len(t, p.rarest(available, rarestScanMaximum+1), rarestScanMaximum)
+
+		// the window starts at a random piece so every piece is eventually considered.
+		beyond := false
+		for range 16 {
+			pids := p.rarest(available, 1)
+			require.Len(t, pids, 1)
+			beyond = beyond || pids[0] >= rarestScanMaximum
+		}
+		require.True(t, beyond)
+	})
+
+	t.Run("seeds do not alter rarity", func(t *testing.T) {
+		p := newChunks(bytesx.KiB, tinyTorrentInfo())
+		p.ClaimedAll(2)
+		p.Claimed(1, 3)
+		require.Equal(t, 3, p.Availability(3))
+		require.Equal(t, 2, p.Availability(4))
+		require.Equal(t, int32(0), p.rarity(4))
+	})
+}
+
 func TestChunksGraceWindow(t *testing.T) {
 	info, err := fromFile("testdata/bootstrap.dat.torrent")
 	require.NoError(t, err)
@@ -581,3 +659,97 @@ func BenchmarkChunksPopBatch(b *testing.B) {
 		}
 	}
 }
+
+func TestChunksPrioritize(t *testing.T) {
+	t.Run("higher priorities are popped first", func(t *testing.T) {
+		p := quickpopulate(newChunks(256, tinyTorrentInfo()))
+		prioritized := roaring.Or(bitmapx.Range(8, 12), bitmapx.Range(60, 62))
+		p.Prioritize(map[Priority]*roaring.Bitmap{
+			PriorityHigh:   bitmapx.Range(8, 12),
+			PriorityUrgent: bitmapx.Range(60, 62),
+		})
+
+		reqs, err := p.Pop(8, bitmapx.Fill(p.cmaximum))
+		require.NoError(t, err)
+		cidxs := make([]int, 0, len(reqs))
+		for _, r := range reqs {
+			cidxs = append(cidxs, p.requestCID(r))
+		}
+		require.Equal(t, []int{60, 61, 8, 9, 10, 11}, cidxs[:6])
+
+		// normal priority chunks are picked at random, but only after the urgent and high chunks.
+		for _, cidx := range cidxs[6:] {
+			require.False(t, prioritized.Contains(uint32(cidx)), "chunk %d popped after lower priorities", cidx)
+		}
+	})
+
+	t.Run("skipped chunks are never popped", func(t *testing.T) {
+		p := quickpopulate(newChunks(256, tinyTorrentInfo()))
+		p.Prioritize(map[Priority]*roaring.Bitmap{
+			PrioritySkip: bitmapx.Range(0, 60),
+		})
+
+		reqs, err := p.Pop(int(p.cmaximum), bitmapx.Fill(p.cmaximum))
+		require.NoError(t, err)
+		require.Len(t, reqs, 4)
+		for _, r := range reqs {
+			require.GreaterOrEqual(t, p.requestCID(r), 60)
+		}
+		require.False(t, p.Wanted(bitmapx.Range(0, 60)))
+
+		_, err = p.Pop(1, bitmapx.Range(0, 60))
+		require.ErrorAs(t, err, &empty{})
+	})
+
+	t.Run("highest priority wins for overlapping chunks", func(t *testing.T) {
+		p := quickpopulate(newChunks(256, tinyTorrentInfo()))
+		p.Prioritize(map[Priority]*roaring.Bitmap{
+			PrioritySkip:   bitmapx.Range(0, 8),
+			PriorityNormal: bitmapx.Range(4, 12),
+		})
+
+		require.True(t, p.Wanted(bitmapx.Range(4, 8)))
+		require.False(t, p.Wanted(bitmapx.Range(0, 4)))
+	})
+}
+
+func TestChunksWant(t *testing.T) {
+	p := newChunks(256, tinyTorrentInfo())
+	p.completed.Add(0)
+	p.unverified.Add(4)
+	p.missing.Add(5)
+
+	p.Want(bitmapx.Range(0, 8))
+	require.Equal(t, []uint32{5, 6, 7}, p.missing.ToArray())
+}
+
+func TestChunksEndgame(t *testing.T) {
+	p := quickpopulate(newChunks(256, tinyTorrentInfo()))
+	available := bitmapx.Fill(p.cmaximum)
+
+	reqs, err := p.Pop(60, available)
+	require.NoError(t, err)
+	require.Empty(t, p.Endgame(8, 64, available), "remaining work exceeds the threshold")
+
+	for _, r := range reqs {
+		require.NoError(t, p.Verify(r))
+	}
+
+	reqs, err = p.Pop(4, available)
+	require.NoError(t, err)
+	popped := roaring.New()
+	for _, r := range reqs {
+		popped.AddInt(p.requestCID(r))
+	}
+	require.Empty(t, p.Endgame(8, 64, roaring.AndNot(available, popped)), "outstanding chunks are not available")
+
+	dups := p.Endgame(8, 64, available)
+	require.ElementsMatch(t, reqs, dups)
+	require.Len(t, p.Endgame(8, 2, available), 2)
+
+	p.Duplicated(dups...)
+	require.Equal(t, 4, p.Snapshot(&Stats{}).Endgame)
+	require.True(t, p.Deduplicate(dups[0]))
+	require.False(t, p.Deduplicate(dups[0]))
+	require.Equal(t, 3, p.Snapshot(&Stats{}).Endgame)
+}
@@ -16,6 +16,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/james-lawrence/torrent/bep0051"
 	"github.com/james-lawrence/torrent/connections"
 	"github.com/james-lawrence/torrent/dht"
 	"github.com/james-lawrence/torrent/dht/int160"
@@ -72,8 +73,15 @@ type Client struct {
 	conns      []sockets.Socket
 	dhtServers []*dht.Server
 
+	// local service discovery sockets and the cookie identifying our announcements.
+	lsdconns  []lsdconn
+	lsdcookie string
+	lsdEvent  chan struct{}
+
 	dialing  *netx.RacingDialer
 	torrents *memoryseeding
+	// hashing workers shared by the torrents.
+	hashing *hashpool
 }
 
 // Query torrent info from the dht
@@ -155,6 +163,20 @@ func (cl *Client) start(md Metadata, options ...Tuner) (dlt *torrent, added bool
 		go dlt.dhtAnnouncer(s)
 	})
 
+	for _, uri := range dlt.md.Webseeds {
+		go newWebseed(dlt, uri).run()
+	}
+
+	if len(dlt.md.Peers) > 0 {
+		go dlt.addHintedPeers(context.Background(), dlt.md.Peers...)
+	}
+
+	if len(dlt.md.Sources) > 0 {
+		go dlt.fetchSources(context.Background(), newHTTPClient(cl.config), dlt.md.Sources...)
+	}
+
+	cl.lsdAnnounceSoon()
+
 	dlt.updateWantPeersEvent()
 
 	return dlt, true, nil
@@ -219,6 +241,8 @@ func NewClient(cfg *ClientConfig) (_ *Client, err error) {
 		torrents: NewCache(cfg.defaultMetadata, NewBitmapCache(cfg.defaultCacheDirectory)),
 		_mu:      &sync.RWMutex{},
 		dialing:  netx.NewRacing(cfg.dialPoolSize), // four concurrent dials per cpu seems a reasonable starting point.
+		hashing:  newHashPool(cfg.hashingWorkers, cfg.hashingBuffer, cfg.sha1),
+		lsdEvent: make(chan struct{}, 1),
 	}
 
 	defer func() {
@@ -231,6 +255,16 @@ func NewClient(cfg *ClientConfig) (_ *Client, err error) {
 		return nil, errorsx.Wrap(err, "error generating peer id")
 	}
 
+	cl.lsdcookie = int160.Random().String()[:8]
+
+	if cfg.sampleInterval > 0 {
+		cfg.DHTMuxer = cfg.DHTMuxer.Method(bep0051.Query, bep0051.NewEndpoint(bep0051.NewSampler(cfg.sampleInterval, cl.sampleable)))
+	}
+
+	if cfg.dhtSnapshotPath != "" && cfg.dhtSnapshotInterval > 0 {
+		go cl.dhtSnapshotter()
+	}
+
 	return cl, nil
 }
 
@@ -268,6 +302,15 @@ func (cl *Client) newDhtServer(conn net.PacketConn) (s *dht.Server, err error) {
 	}()
 
 	go func() {
+		restored, err := cl.dhtRestore(s, conn)
+		if err != nil {
+			cl.config.errors().Println(err)
+		}
+		cl.config.debug().Printf("%v restored %d nodes\n", s, len(restored))
+
+		// the restored nodes are validated while bootstrapping.
+		go cl.dhtValidateRestored(s, restored)
+
 		ts, err := s.Bootstrap(context.Background())
 		if err != nil {
 			cl.config.errors().Println(errorsx.Wrap(err, "error bootstrapping dht"))
@@ -358,6 +401,11 @@ func (cl *Client) Close() error {
 	default:
 		close(cl.closed)
 	}
+
+	if err := cl.dhtSnapshot(); err != nil {
+		cl.config.errors().Println(err)
+	}
+
 	cl.eachDhtServer(func(s *dht.Server) { s.Close() })
 	cl.closeSockets()
 
@@ -428,7 +476,7 @@ func reducedDialTimeout(minDialTimeout, maximum time.Duration, halfOpenLimit int
 
 // Returns nil connection and nil error if no connection could be established
 // for valid reasons.
-func (cl *Client) establishOutgoingConnEx(ctx context.Context, t *torrent, addr netip.AddrPort, obfuscatedHeader bool) (c *connection, err error) {
+func (cl *Client) establishOutgoingConnEx(ctx context.Context, t *torrent, swarm int160.T, addr netip.AddrPort, obfuscatedHeader bool) (c *connection, err error) {
 	var (
 		nc net.Conn
 	)
@@ -471,7 +519,7 @@ func (cl *Client) establishOutgoingConnEx(ctx context.Context, t *torrent, addr
 	c = cl.newConnection(nc, true, addr)
 	c.headerEncrypted = obfuscatedHeader
 
-	if err = cl.initiateHandshakes(c, t); err != nil {
+	if err = cl.initiateHandshakes(c, swarm); err != nil {
 		return nil, err
 	}
 
@@ -480,9 +528,9 @@ func (cl *Client) establishOutgoingConnEx(ctx context.Context, t *torrent, addr
 
 // Returns nil connection and nil error if no connection could be established
 // for valid reasons.
-func (cl *Client) establishOutgoingConn(ctx context.Context, t *torrent, addr netip.AddrPort) (c *connection, err error) {
+func (cl *Client) establishOutgoingConn(ctx context.Context, t *torrent, swarm int160.T, addr netip.AddrPort) (c *connection, err error) {
 	obfuscatedHeaderFirst := cl.config.HeaderObfuscationPolicy.Preferred
-	if c, err = cl.establishOutgoingConnEx(ctx, t, addr, obfuscatedHeaderFirst); err == nil {
+	if c, err = cl.establishOutgoingConnEx(ctx, t, swarm, addr, obfuscatedHeaderFirst); err == nil {
 		return c, nil
 	}
 
@@ -493,7 +541,7 @@ func (cl *Client) establishOutgoingConn(ctx context.Context, t *torrent, addr ne
 	}
 
 	// Try again with encryption if we didn't earlier, or without if we did.
-	if c, err = cl.establishOutgoingConnEx(ctx, t, addr, !obfuscatedHeaderFirst); err != nil {
+	if c, err = cl.establishOutgoingConnEx(ctx, t, swarm, addr, !obfuscatedHeaderFirst); err != nil {
 		return c, err
 	}
 
@@ -521,7 +569,8 @@ func (cl *Client) outgoingConnection(ctx context.Context, t *torrent, p Peer) (e
 		return errorsx.Wrap(err, "dial rate limit failed")
 	}
 
-	if c, err = cl.establishOutgoingConn(ctx, t, p.AddrPort); err != nil {
+	// peers discovered in the v2 swarm of hybrid torrents are handshaked with the v2 infohash.
+	if c, err = cl.establishOutgoingConn(ctx, t, langx.DefaultIfZero(t.md.ID, p.Swarm), p.AddrPort); err != nil {
 		t.peers.Attempted(p, nil)
 		return errorsx.Wrapf(err, "error establishing connection to %v", p.AddrPort)
 	}
@@ -562,7 +611,7 @@ func (cl *Client) forSkeys(cb func(skey []byte) []byte) []byte {
 	return nil
 }
 
-func (cl *Client) initiateHandshakes(c *connection, t *torrent) (err error) {
+func (cl *Client) initiateHandshakes(c *connection, infohash int160.T) (err error) {
 	var (
 		rw io.ReadWriter
 	)
@@ -572,7 +621,7 @@ func (cl *Client) initiateHandshakes(c *connection, t *torrent) (err error) {
 		rw, c.cryptoMethod, err = pp.EncryptionHandshake{
 			Keys:           cl.forSkeys,
 			CryptoSelector: cl.config.CryptoSelector,
-		}.Outgoing(rw, t.md.ID.Bytes(), cl.config.CryptoProvides)
+		}.Outgoing(rw, infohash.Bytes(), cl.config.CryptoProvides)
 
 		if err != nil {
 			return errorsx.Wrap(err, "encryption handshake failed")
@@ -583,7 +632,7 @@ func (cl *Client) initiateHandshakes(c *connection, t *torrent) (err error) {
 	ebits, info, err := pp.Handshake{
 		PeerID: cl.config.localID.AsByteArray(),
 		Bits:   cl.config.extensionbits,
-	}.Outgoing(c.rw(), t.md.ID.AsByteArray())
+	}.Outgoing(c.rw(), infohash.AsByteArray())
 
 	if err != nil {
 		return errorsx.Wrap(err, "bittorrent protocol handshake failure")
@@ -730,6 +779,7 @@ func (cl *Client) onDHTAnnouncePeer(id int160.T, ip net.IP, port int, portOk boo
 		ip,
 		port,
 		PeerOptionSource(peerSourceDhtAnnouncePeer),
+		PeerOptionSwarm(id),
 	))
 }
 
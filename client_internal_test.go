@@ -40,7 +40,7 @@ func TestingConfig(t testing.TB, dir string, options ...ClientConfigOption) *Cli
 	)
 }
 
-func Autosocket(t *testing.T) Binder {
+func Autosocket(t *testing.T, options ...BinderOption) Binder {
 	var (
 		bindings []sockets.Socket
 	)
@@ -54,7 +54,7 @@ func Autosocket(t *testing.T) Binder {
 		bindings = append(bindings, sockets.New(s, &net.Dialer{}))
 	}
 
-	return NewSocketsBind(bindings...)
+	return NewSocketsBind(bindings...).Options(options...)
 }
 
 func totalConns(tts []Torrent) (ret int) {
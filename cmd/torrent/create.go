@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+type cmdCreate struct {
+	Output      string   `arg:"-o,--output" help:"path to write the .torrent to, defaults to <name>.torrent"`
+	Trackers    []string `arg:"-t,--tracker,separate" help:"trackers, each flag forms its own tier"`
+	Webseeds    []string `arg:"-w,--webseed,separate" help:"webseed urls (BEP 19)"`
+	Nodes       []string `arg:"-n,--node,separate" help:"DHT nodes in host:port form"`
+	Comment     string   `arg:"--comment"`
+	CreatedBy   string   `arg:"--created-by" default:"github.com/james-lawrence/torrent"`
+	Source      string   `arg:"--source" help:"source field of the info, changes the info hash"`
+	Name        string   `arg:"--name" help:"name of the torrent, defaults to the name of the path"`
+	Private     bool     `arg:"--private" help:"only use peers from the trackers (BEP 27)"`
+	Hybrid      bool     `arg:"--hybrid" help:"include the v2 file tree (BEP 52)"`
+	Hidden      bool     `arg:"--hidden" help:"include hidden files"`
+	Exclude     []string `arg:"-x,--exclude,separate" help:"glob patterns of the files to exclude"`
+	PieceLength int64    `arg:"--piece-length" help:"piece length in bytes, chosen automatically when zero"`
+	Path        string   `arg:"positional,required" help:"file or directory to create the torrent from"`
+}
+
+func (t cmdCreate) Run(ctx context.Context) error {
+	tiers := make([][]string, 0, len(t.Trackers))
+	for _, uri := range t.Trackers {
+		tiers = append(tiers, []string{uri})
+	}
+
+	reported := atomic.Int64{}
+	info := []metainfo.Option{}
+	if t.Name != "" {
+		info = append(info, metainfo.OptionDisplayName(t.Name))
+	}
+
+	if t.Hybrid {
+		info = append(info, metainfo.OptionHybrid)
+	}
+
+	if t.Private {
+		info = append(info, func(i *metainfo.Info) {
+			i.Private = langx.Autoptr(true)
+		})
+	}
+
+	mi, err := metainfo.NewCreator(
+		metainfo.CreatorOptionInfo(info...),
+		metainfo.CreatorOptionAnnounceList(tiers...),
+		metainfo.CreatorOptionUrlList(t.Webseeds...),
+		metainfo.CreatorOptionNodes(t.Nodes...),
+		metainfo.CreatorOptionComment(t.Comment),
+		metainfo.CreatorOptionCreatedBy(t.CreatedBy),
+		metainfo.CreatorOptionSource(t.Source),
+		metainfo.CreatorOptionPieceLength(t.PieceLength),
+		metainfo.CreatorOptionHidden(t.Hidden),
+		metainfo.CreatorOptionFilter(t.excluded),
+		metainfo.CreatorOptionProgress(func(hashed, total int64) {
+			if percent := hashed * 100 / max(total, 1); percent > reported.Swap(percent) {
+				fmt.Fprintf(os.Stderr, "\rhashed %s/%s %3d%%", humanize(hashed), humanize(total), percent)
+			}
+		}),
+	).Create(ctx, t.Path)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	decoded, err := mi.UnmarshalInfo()
+	if err != nil {
+		return err
+	}
+
+	output := t.Output
+	if output == "" {
+		output = decoded.Name + ".torrent"
+	}
+
+	encoded, err := metainfo.Encode(mi)
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(output, encoded, 0644); err != nil {
+		return err
+	}
+
+	fmt.Println(output)
+	return nil
+}
+
+func (t cmdCreate) excluded(path string, _ fs.DirEntry) bool {
+	for _, pattern := range t.Exclude {
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return false
+		}
+
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/james-lawrence/torrent"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+)
+
+type cmdDownload struct {
+	network
+	Dir      string   `arg:"-d,--dir" default:"." help:"directory to store the downloaded data in"`
+	Seed     bool     `arg:"--seed" help:"continue seeding once the downloads complete"`
+	Quiet    bool     `arg:"-q,--quiet" help:"do not display progress"`
+	Torrents []string `arg:"positional,required" help:"magnet links, .torrent files or info hashes"`
+}
+
+func (t cmdDownload) Run(ctx context.Context) error {
+	cl, err := newClient(t.Dir, t.network, torrent.ClientConfigSeed(t.Seed))
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []error
+	)
+
+	for _, source := range t.Torrents {
+		md, err := load(source)
+		if err != nil {
+			return err
+		}
+
+		dl, _, err := cl.Start(md)
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if !t.Quiet {
+				done := progress(ctx, os.Stderr, dl)
+				defer done()
+			}
+
+			if _, err := torrent.DownloadInto(ctx, io.Discard, dl); err != nil {
+				mu.Lock()
+				failures = append(failures, errorsx.Wrap(err, source))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// the readers of the downloads are not interrupted by the context.
+	completed := make(chan struct{})
+	go func() {
+		defer close(completed)
+		wg.Wait()
+	}()
+
+	select {
+	case <-completed:
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+
+	if len(failures) > 0 {
+		return failures[0]
+	}
+
+	log.Println("downloads completed")
+
+	if t.Seed {
+		<-ctx.Done()
+	}
+
+	return nil
+}
+
+// periodically display the progress of the torrent until the returned function is called.
+func progress(ctx context.Context, dst io.Writer, dl torrent.Torrent) (done func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		select {
+		case <-dl.GotInfo():
+		case <-ctx.Done():
+			return
+		}
+
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		previous := dl.BytesCompleted()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				fmt.Fprintln(dst, status(dl, 0))
+				return
+			}
+
+			completed := dl.BytesCompleted()
+			fmt.Fprint(dst, "\r", status(dl, completed-previous))
+			previous = completed
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-finished
+	}
+}
+
+func status(dl torrent.Torrent, rate int64) string {
+	const width = 40
+	total := dl.Info().TotalLength()
+	completed := dl.BytesCompleted()
+	ratio := 1.0
+	if total > 0 {
+		ratio = float64(completed) / float64(total)
+	}
+
+	filled := int(ratio * width)
+	stats := dl.Stats()
+
+	return fmt.Sprintf(
+		"%s [%s%s] %3.0f%% %s/%s %s/s peers(%d/%d)",
+		dl.Info().Name,
+		strings.Repeat("=", filled), strings.Repeat(" ", width-filled),
+		ratio*100,
+		humanize(completed), humanize(total), humanize(rate),
+		stats.ActivePeers, stats.TotalPeers,
+	)
+}
+
+func humanize(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	v := float64(n)
+	i := 0
+	for ; v >= bytesx.KiB && i < len(units)-1; i++ {
+		v /= bytesx.KiB
+	}
+
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/james-lawrence/torrent"
+)
+
+type cmdFollow struct {
+	network
+	Dir      string        `arg:"-d,--dir" default:"." help:"directory to store the downloaded data in"`
+	Interval time.Duration `arg:"--interval" default:"15m" help:"how often to check for a new version"`
+	Quiet    bool          `arg:"-q,--quiet" help:"do not display progress"`
+	Torrent  string        `arg:"positional,required" help:"mutable torrent magnet link, see publish"`
+}
+
+func (t cmdFollow) Run(ctx context.Context) error {
+	m, err := torrent.ParseMutable(t.Torrent)
+	if err != nil {
+		return err
+	}
+
+	cl, err := newClient(t.Dir, t.network, torrent.ClientConfigSeed(true))
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+
+	done := func() {}
+	defer func() { done() }()
+
+	versions := cl.FollowMutable(
+		ctx,
+		m,
+		torrent.MutableOptionInterval(t.Interval),
+		torrent.MutableOptionTuners(torrent.TuneRecordMetadata, torrent.TuneAutoDownload, torrent.TuneAnnounceUntilClosed),
+	)
+
+	for dl, err := range versions {
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		done()
+		log.Printf("following %s %s\n", dl.Metadata().ID, dl.Info().Name)
+
+		if !t.Quiet {
+			done = progress(ctx, os.Stderr, dl)
+		}
+	}
+
+	return nil
+}
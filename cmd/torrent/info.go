@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/james-lawrence/torrent"
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+// resolution of the metainfo for magnet links and info hashes from peers.
+type resolve struct {
+	network
+	Timeout time.Duration `arg:"--timeout" default:"5m" help:"maximum time to spend retrieving the info from peers"`
+}
+
+// load the metainfo from a .torrent file, or retrieve the info from peers.
+func (t resolve) metainfo(ctx context.Context, source string) (*metainfo.MetaInfo, error) {
+	if strings.HasSuffix(source, ".torrent") {
+		return metainfo.LoadFromFile(source)
+	}
+
+	md, err := load(source)
+	if err != nil {
+		return nil, err
+	}
+
+	cl, err := newClient(os.TempDir(), t.network)
+	if err != nil {
+		return nil, err
+	}
+	defer cl.Close()
+
+	ctx, done := context.WithTimeout(ctx, t.Timeout)
+	defer done()
+
+	info, err := cl.Info(ctx, md)
+	if err != nil {
+		return nil, errorsx.Wrap(err, "unable to retrieve info")
+	}
+
+	encoded, err := bencode.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	if id := metainfo.NewHashFromBytes(encoded); id != md.ID.AsByteArray() && metainfo.NewHashV2FromBytes(encoded) != md.IDv2 {
+		return nil, errorsx.Errorf("re-encoded info does not match the info hash %s != %s", id, md.ID)
+	}
+
+	mi := md.Merge(torrent.OptionInfo(encoded)).Metainfo()
+	mi.UrlList = md.Webseeds
+	return &mi, nil
+}
+
+type cmdInfo struct {
+	resolve
+	Torrent string `arg:"positional,required" help:"magnet link, .torrent file or info hash"`
+}
+
+type jsonFile struct {
+	Path   string `json:"path"`
+	Length int64  `json:"length"`
+}
+
+type jsonMetaInfo struct {
+	InfoHash     string     `json:"info_hash"`
+	InfoHashV2   string     `json:"info_hash_v2,omitempty"`
+	Name         string     `json:"name"`
+	Length       int64      `json:"length"`
+	PieceLength  int64      `json:"piece_length"`
+	Pieces       uint64     `json:"pieces"`
+	Private      bool       `json:"private"`
+	Source       string     `json:"source,omitempty"`
+	Files        []jsonFile `json:"files"`
+	AnnounceList [][]string `json:"announce_list,omitempty"`
+	UrlList      []string   `json:"url_list,omitempty"`
+	Nodes        []string   `json:"nodes,omitempty"`
+	Comment      string     `json:"comment,omitempty"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	CreationDate *time.Time `json:"creation_date,omitempty"`
+	Magnet       string     `json:"magnet"`
+}
+
+func (t cmdInfo) Run(ctx context.Context) error {
+	mi, err := t.metainfo(ctx, t.Torrent)
+	if err != nil {
+		return err
+	}
+
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return err
+	}
+
+	md, err := torrent.NewFromMetaInfo(mi)
+	if err != nil {
+		return err
+	}
+
+	dump := jsonMetaInfo{
+		InfoHash:     md.ID.String(),
+		Name:         info.Name,
+		Length:       info.TotalLength(),
+		PieceLength:  info.PieceLength,
+		Pieces:       info.NumPieces(),
+		Private:      langx.Autoderef(info.Private),
+		Source:       info.Source,
+		AnnounceList: mi.UpvertedAnnounceList(),
+		UrlList:      mi.UrlList,
+		Nodes:        mi.NodeList(),
+		Comment:      mi.Comment,
+		CreatedBy:    mi.CreatedBy,
+		Magnet:       torrent.NewMagnet(md).String(),
+	}
+
+	if !md.IDv2.IsZero() {
+		dump.InfoHashV2 = md.IDv2.String()
+	}
+
+	if mi.CreationDate > 0 {
+		dump.CreationDate = langx.Autoptr(time.Unix(mi.CreationDate, 0).UTC())
+	}
+
+	for f := range metainfo.Files(&info) {
+		dump.Files = append(dump.Files, jsonFile{Path: f.Path, Length: int64(f.Length)})
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(dump)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/james-lawrence/torrent"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+type cmdMagnet struct {
+	resolve
+	Output  string `arg:"-o,--output" help:"path to write the .torrent to when converting a magnet link, defaults to <name>.torrent"`
+	Torrent string `arg:"positional,required" help:"magnet link, .torrent file or info hash"`
+}
+
+func (t cmdMagnet) Run(ctx context.Context) error {
+	if strings.HasSuffix(t.Torrent, ".torrent") {
+		md, err := torrent.NewFromMetaInfoFile(t.Torrent)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(torrent.NewMagnet(md).String())
+		return nil
+	}
+
+	mi, err := t.metainfo(ctx, t.Torrent)
+	if err != nil {
+		return err
+	}
+
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return err
+	}
+
+	output := t.Output
+	if output == "" {
+		output = info.Name + ".torrent"
+	}
+
+	encoded, err := metainfo.Encode(mi)
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(output, encoded, 0644); err != nil {
+		return err
+	}
+
+	fmt.Println(output)
+	return nil
+}
@@ -0,0 +1,117 @@
+// Command torrent downloads, seeds, creates and inspects torrents.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/alexflint/go-arg"
+
+	"github.com/james-lawrence/torrent"
+	"github.com/james-lawrence/torrent/autobind"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/internal/userx"
+	"github.com/james-lawrence/torrent/metainfo"
+	"github.com/james-lawrence/torrent/storage"
+)
+
+type network struct {
+	Port  int  `arg:"--port" help:"port to listen on, random when zero"`
+	NoDHT bool `arg:"--no-dht" help:"disable the DHT"`
+	LSD   bool `arg:"--lsd" help:"discover peers on the local network"`
+}
+
+func main() {
+	var args struct {
+		Download *cmdDownload `arg:"subcommand:download" help:"download torrents from magnet links, .torrent files or info hashes"`
+		Seed     *cmdSeed     `arg:"subcommand:seed" help:"seed files or directories"`
+		Create   *cmdCreate   `arg:"subcommand:create" help:"create a .torrent file"`
+		Info     *cmdInfo     `arg:"subcommand:info" help:"print the metainfo of a torrent as json"`
+		Magnet   *cmdMagnet   `arg:"subcommand:magnet" help:"convert a .torrent file into a magnet link and vice versa"`
+		Verify   *cmdVerify   `arg:"subcommand:verify" help:"verify the local data of a torrent"`
+		Publish  *cmdPublish  `arg:"subcommand:publish" help:"publish the latest version of a mutable torrent to the DHT"`
+		Follow   *cmdFollow   `arg:"subcommand:follow" help:"download and seed the latest version of a mutable torrent"`
+	}
+
+	p := arg.MustParse(&args)
+	if p.Subcommand() == nil {
+		p.Fail("missing subcommand")
+	}
+
+	ctx, done := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer done()
+
+	var err error
+	switch {
+	case args.Download != nil:
+		err = args.Download.Run(ctx)
+	case args.Seed != nil:
+		err = args.Seed.Run(ctx)
+	case args.Create != nil:
+		err = args.Create.Run(ctx)
+	case args.Info != nil:
+		err = args.Info.Run(ctx)
+	case args.Magnet != nil:
+		err = args.Magnet.Run(ctx)
+	case args.Verify != nil:
+		err = args.Verify.Run(ctx)
+	case args.Publish != nil:
+		err = args.Publish.Run(ctx)
+	case args.Follow != nil:
+		err = args.Follow.Run(ctx)
+	}
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// data is stored at <dir>/<torrent name>, matching the layout of the files the
+// torrent was created from.
+func namedPathMaker(dir string, _ int160.T, info *metainfo.Info, fi *metainfo.FileInfo) string {
+	return filepath.Join(dir, langx.DerefOrZero(info).Name, filepath.Join(langx.DerefOrZero(fi).Path...))
+}
+
+func newStorage(dir string) storage.ClientImpl {
+	return storage.NewFile(dir, storage.FileOptionPathMaker(namedPathMaker))
+}
+
+func newClient(dir string, n network, options ...torrent.ClientConfigOption) (*torrent.Client, error) {
+	binding := autobind.New(
+		autobind.EnableDHT,
+		func(a *autobind.Autobind) {
+			a.ListenPort = n.Port
+			a.EnableDHT = !n.NoDHT
+			a.EnableLSD = n.LSD
+		},
+	)
+
+	return binding.Bind(torrent.NewClient(torrent.NewDefaultClientConfig(
+		torrent.NewMetadataCache(filepath.Join(userx.DefaultCacheDirectory(), "torrent", "metadata")),
+		newStorage(dir),
+		append([]torrent.ClientConfigOption{torrent.ClientConfigBootstrapGlobal}, options...)...,
+	)))
+}
+
+// load the metadata from a magnet link, .torrent file or hex encoded info hash.
+func load(source string) (torrent.Metadata, error) {
+	switch {
+	case strings.HasPrefix(source, "magnet:"):
+		return torrent.NewFromMagnet(source)
+	case strings.HasSuffix(source, ".torrent"):
+		return torrent.NewFromMetaInfoFile(source)
+	}
+
+	if id, err := int160.FromHexEncodedString(source); err == nil {
+		return torrent.New(id.AsByteArray())
+	}
+
+	return torrent.Metadata{}, errorsx.Errorf("unrecognized torrent %q, expected a magnet link, .torrent file or info hash", source)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/james-lawrence/torrent"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+)
+
+type cmdPublish struct {
+	network
+	Key      string        `arg:"-k,--key,required" help:"file containing the hex encoded ed25519 seed the torrent is signed with, generated when missing"`
+	Salt     string        `arg:"--salt" help:"salt distinguishing the torrents published with the same key"`
+	Interval time.Duration `arg:"--interval" help:"republish at the interval until interrupted, DHT nodes discard items that are not refreshed"`
+	Torrent  string        `arg:"positional,required" help:"magnet link, .torrent file or info hash of the latest version"`
+}
+
+func (t cmdPublish) Run(ctx context.Context) error {
+	key, err := loadKey(t.Key)
+	if err != nil {
+		return err
+	}
+
+	md, err := load(t.Torrent)
+	if err != nil {
+		return err
+	}
+
+	cl, err := newClient(os.TempDir(), t.network)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+
+	servers := cl.DhtServers()
+	if len(servers) == 0 {
+		return errorsx.New("unable to publish, the DHT is disabled")
+	}
+
+	m := torrent.Mutable{PublicKey: [32]byte(key.Public().(ed25519.PublicKey)), Salt: []byte(t.Salt)}
+	fmt.Println(m.String())
+
+	for {
+		seq, err := torrent.PublishMutable(ctx, servers[0], key, m.Salt, md.ID.AsByteArray())
+		if err != nil {
+			return err
+		}
+
+		log.Printf("published %s seq %d\n", md.ID, seq)
+
+		if t.Interval <= 0 {
+			return nil
+		}
+
+		select {
+		case <-time.After(t.Interval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// load the key mutable torrents are signed with, generating one when the file does not exist.
+func loadKey(path string) (ed25519.PrivateKey, error) {
+	encoded, err := os.ReadFile(path)
+	if errorsx.Is(err, fs.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = os.WriteFile(path, []byte(hex.EncodeToString(key.Seed())+"\n"), 0600); err != nil {
+			return nil, errorsx.Wrapf(err, "unable to write key %s", path)
+		}
+
+		return key, nil
+	} else if err != nil {
+		return nil, errorsx.Wrapf(err, "unable to read key %s", path)
+	}
+
+	seed, err := hex.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errorsx.Errorf("invalid key %s, expected a hex encoded %d byte seed", path, ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/james-lawrence/torrent"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+type cmdSeed struct {
+	network
+	Trackers []string `arg:"-t,--tracker,separate" help:"trackers to announce to"`
+	Quiet    bool     `arg:"-q,--quiet" help:"do not display statistics"`
+	Paths    []string `arg:"positional,required" help:"files or directories to seed, they must share a parent directory"`
+}
+
+func (t cmdSeed) Run(ctx context.Context) error {
+	dir := filepath.Dir(filepath.Clean(t.Paths[0]))
+	for _, path := range t.Paths[1:] {
+		if filepath.Dir(filepath.Clean(path)) != dir {
+			return errorsx.Errorf("%s is not in %s", path, dir)
+		}
+	}
+
+	cl, err := newClient(dir, t.network, torrent.ClientConfigSeed(true))
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+
+	options := []metainfo.CreatorOption{}
+	if len(t.Trackers) > 0 {
+		options = append(options, metainfo.CreatorOptionAnnounceList(t.Trackers))
+	}
+
+	seeding := make([]torrent.Torrent, 0, len(t.Paths))
+	for _, path := range t.Paths {
+		mi, err := metainfo.NewCreator(options...).Create(ctx, path)
+		if err != nil {
+			return err
+		}
+
+		md, err := torrent.NewFromMetaInfo(mi)
+		if err != nil {
+			return err
+		}
+
+		dl, _, err := cl.Start(md, torrent.TuneAnnounceUntilClosed)
+		if err != nil {
+			return err
+		}
+
+		if err = torrent.Verify(ctx, dl); err != nil {
+			return err
+		}
+
+		fmt.Println(torrent.NewMagnet(md).String())
+		seeding = append(seeding, dl)
+	}
+
+	if t.Quiet {
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+
+		for _, dl := range seeding {
+			stats := dl.Stats()
+			fmt.Fprintf(os.Stderr, "%s uploaded %s peers(%d/%d)\n", dl.Info().Name, humanize(stats.BytesWrittenData.Int64()), stats.ActivePeers, stats.TotalPeers)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/james-lawrence/torrent"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+type cmdVerify struct {
+	Dir     string `arg:"-d,--dir" default:"." help:"directory containing the data of the torrent"`
+	Torrent string `arg:"positional,required" help:".torrent file"`
+}
+
+func (t cmdVerify) Run(ctx context.Context) error {
+	mi, err := metainfo.LoadFromFile(t.Torrent)
+	if err != nil {
+		return err
+	}
+
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return err
+	}
+
+	data, err := newStorage(t.Dir).OpenTorrent(&info, mi.ID())
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	missing, _, err := torrent.VerifyStored(ctx, mi, data)
+	if err != nil {
+		return err
+	}
+
+	// the bitmap is of the 16KiB chunks of the torrent.
+	chunks := uint32((info.PieceLength + 16*bytesx.KiB - 1) / (16 * bytesx.KiB))
+	failed := map[uint32]struct{}{}
+	for it := missing.Iterator(); it.HasNext(); {
+		failed[it.Next()/chunks] = struct{}{}
+	}
+
+	pieces := info.NumPieces()
+	fmt.Printf("%s: %d/%d pieces verified\n", info.Name, pieces-uint64(len(failed)), pieces)
+	if len(failed) > 0 {
+		return errorsx.Errorf("%d pieces failed verification", len(failed))
+	}
+
+	return nil
+}
@@ -2,6 +2,8 @@ package torrent
 
 import (
 	"context"
+	"crypto/sha1"
+	"hash"
 	"iter"
 	"net"
 	"net/http"
@@ -16,6 +18,7 @@ import (
 	"github.com/james-lawrence/torrent/dht/int160"
 	"github.com/james-lawrence/torrent/dht/krpc"
 	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/internal/langx"
 	"github.com/james-lawrence/torrent/internal/netx"
 	"github.com/james-lawrence/torrent/internal/userx"
 	"github.com/james-lawrence/torrent/storage"
@@ -63,6 +66,10 @@ type ClientConfig struct {
 
 	maximumOutstandingRequests int
 
+	// once the remaining chunks fall to or below this threshold outstanding
+	// chunks are requested from multiple peers.
+	endgameThreshold int
+
 	// Rate limit connection dialing
 	dialRateLimiter *rate.Limiter
 	// Number of dialing routines to run.
@@ -71,8 +78,22 @@ type ClientConfig struct {
 	// rate limit for accepting connections
 	acceptRateLimiter *rate.Limiter
 
+	// number of pieces hashed concurrently across all torrents, and the
+	// buffer used by each worker.
+	hashingWorkers int
+	hashingBuffer  int
+	// SHA-1 implementation used to verify v1 pieces.
+	sha1 func() hash.Hash
+
+	// decides which peers are allowed to make requests.
+	choker Choker
+
 	bucketLimit int // maximum number of peers per bucket in the DHT.
 
+	// routing tables of the DHT servers are persisted to the path on the interval.
+	dhtSnapshotPath     string
+	dhtSnapshotInterval time.Duration
+
 	// User-provided Client peer ID. If not present, one is generated automatically.
 	PeerID string
 
@@ -138,6 +159,8 @@ type ClientConfig struct {
 	DHTOnQuery      func(query *krpc.Msg, source net.Addr) (propagate bool)
 	DHTAnnouncePeer func(ih int160.T, ip net.IP, port int, portOk bool)
 	DHTMuxer        dht.Muxer
+	// ttl of the BEP 51 infohash samples, zero when sampling is disabled.
+	sampleInterval time.Duration
 
 	ConnectionClosed func(ih int160.T, stats ConnStats, remaining int)
 	extensions       map[pp.ExtensionName]pp.ExtensionNumber
@@ -255,6 +278,19 @@ func ClientConfigBucketLimit(i int) ClientConfigOption {
 	}
 }
 
+// ClientConfigDHTSnapshot persists the good nodes of the routing tables of the DHT servers
+// to the path on the interval and when the client is closed. the nodes are restored and
+// validated before the bootstrap nodes are consulted, avoiding a cold bootstrap on restart.
+// routing tables only retain nodes with a bucket limit, a limit of 8 is used unless one
+// is configured, see ClientConfigBucketLimit.
+func ClientConfigDHTSnapshot(path string, interval time.Duration) ClientConfigOption {
+	return func(cc *ClientConfig) {
+		cc.dhtSnapshotPath = path
+		cc.dhtSnapshotInterval = interval
+		cc.bucketLimit = langx.DefaultIfZero(dhtSnapshotBucketLimit, cc.bucketLimit)
+	}
+}
+
 // ClientConfigInfoLogger set the info logger
 func ClientConfigInfoLogger(l logging) ClientConfigOption {
 	return func(c *ClientConfig) {
@@ -295,6 +331,16 @@ func ClientConfigMuxer(m dht.Muxer) ClientConfigOption {
 	}
 }
 
+// ClientConfigSampleInfohashes answers BEP 51 sample_infohashes queries with the
+// public torrents of the client and the peer stores of its DHT servers, the sample
+// is rotated every interval. registers the endpoint with the muxer when the client
+// is created.
+func ClientConfigSampleInfohashes(interval time.Duration) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.sampleInterval = interval
+	}
+}
+
 func ClientConfigPeerID(s string) ClientConfigOption {
 	return func(c *ClientConfig) {
 		c.PeerID = s
@@ -393,6 +439,13 @@ func ClientConfigMaxOutstandingRequests(n int) ClientConfigOption {
 	}
 }
 
+// number of remaining chunks at which end-game mode begins, zero disables end-game.
+func ClientConfigEndgameThreshold(n int) ClientConfigOption {
+	return func(cc *ClientConfig) {
+		cc.endgameThreshold = n
+	}
+}
+
 func ClientConfigCacheDirectory(s string) ClientConfigOption {
 	return func(cc *ClientConfig) {
 		cc.defaultCacheDirectory = s
@@ -452,6 +505,30 @@ func ClientConfigIDPrefix(prefix string) ClientConfigOption {
 	}
 }
 
+// specify the number of pieces hashed concurrently across all the torrents of the client,
+// along with the size of the buffer each worker uses to read pieces. memory used for hashing
+// is bounded by workers * buffer, storage implementing storage.Mapped is hashed without copying.
+func ClientConfigHashing(workers, buffer int) ClientConfigOption {
+	return func(cc *ClientConfig) {
+		cc.hashingWorkers = workers
+		cc.hashingBuffer = buffer
+	}
+}
+
+// specify the SHA-1 implementation used to verify pieces, e.g. a hardware accelerated one.
+func ClientConfigSHA1(fn func() hash.Hash) ClientConfigOption {
+	return func(cc *ClientConfig) {
+		cc.sha1 = fn
+	}
+}
+
+// specify the algorithm used to choke and unchoke peers, see NewChoker.
+func ClientConfigChoker(c Choker) ClientConfigOption {
+	return func(cc *ClientConfig) {
+		cc.choker = c
+	}
+}
+
 // how long we should wait for handshakes
 func ClientConfigHandshakeTimeout(d time.Duration) ClientConfigOption {
 	return func(cc *ClientConfig) {
@@ -471,6 +548,7 @@ func NewDefaultClientConfig(mdstore MetadataStore, store storage.ClientImpl, opt
 		Bep20:                          "-GT0002-",
 		UpnpID:                         "ghost.torrent",
 		maximumOutstandingRequests:     64,
+		endgameThreshold:               64,
 		NominalDialTimeout:             16 * time.Second,
 		MinDialTimeout:                 4 * time.Second,
 		HalfOpenConnsPerTorrent:        32,
@@ -484,6 +562,10 @@ func NewDefaultClientConfig(mdstore MetadataStore, store storage.ClientImpl, opt
 		dialRateLimiter:                rate.NewLimiter(rate.Limit(32), 128),
 		acceptRateLimiter:              rate.NewLimiter(rate.Limit(runtime.NumCPU()), runtime.NumCPU()),
 		dialPoolSize:                   uint16(runtime.NumCPU()),
+		hashingWorkers:                 runtime.NumCPU(),
+		hashingBuffer:                  defaultHashingBuffer,
+		sha1:                           sha1.New,
+		choker:                         NewChoker(defaultUploadSlots),
 		ConnTracker:                    conntrack.NewInstance(),
 		HeaderObfuscationPolicy: HeaderObfuscationPolicy{
 			Preferred:        false,
@@ -41,6 +41,11 @@ type ConnStats struct {
 	PiecesDirtiedBad count
 
 	DHTAnnounce count
+
+	// Number of chunks requested from an additional peer during end-game.
+	ChunksEndgameRequested count
+	// Number of end-game requests cancelled after another peer delivered the chunk.
+	ChunksEndgameCancelled count
 }
 
 // Copy returns a copy of the connection stats.
@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/netip"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -44,6 +45,8 @@ const (
 	peerSourceDhtGetPeers     = "Hg" // Peers we found by searching a DHT.
 	peerSourceDhtAnnouncePeer = "Ha" // Peers that were announced to us by a DHT.
 	peerSourcePex             = "X"
+	peerSourceLSD             = "L" // Peers announced on the local network.
+	peerSourceMagnet          = "M" // Peers provided by the magnet link.
 	writebufferscapacity      = 512 * bytesx.KiB
 )
 
@@ -69,11 +72,13 @@ func newConnection(cfg *ClientConfig, nc net.Conn, outgoing bool, remote netip.A
 		peerfastset:             roaring.NewBitmap(),
 		fastset:                 roaring.NewBitmap(),
 		claimed:                 roaring.NewBitmap(),
+		advertised:              roaring.NewBitmap(),
 		sentHaves:               roaring.NewBitmap(),
 		requests:                make(map[uint64]request, cfg.maximumOutstandingRequests),
 		PeerRequests:            make(map[request]struct{}, cfg.maximumOutstandingRequests),
 		PeerExtensionIDs:        make(map[pp.ExtensionName]pp.ExtensionNumber),
 		refreshrequestable:      atomicx.Pointer(timex.Inf()),
+		chokeduntil:             atomicx.Pointer(ts.Add(-1 * time.Minute)),
 		lastMessageReceived:     atomicx.Pointer(ts),
 		lastRejectReceived:      atomicx.Pointer(ts),
 		lastUsefulChunkReceived: ts,
@@ -160,6 +165,9 @@ type connection struct {
 	fastset     *roaring.Bitmap // represents chunks which our peer will allow us to request while choked.
 	// pieces we've accepted chunks for from the peer.
 	touched *roaring.Bitmap
+	// pieces counted towards the torrent's availability on behalf of the peer.
+	advertised    *roaring.Bitmap
+	advertisedAll bool
 
 	// The pieces the peer has claimed to have.
 	// peerPieces bitmap.Bitmap
@@ -183,6 +191,14 @@ type connection struct {
 	request       *sync.Cond  // used to wake up the connection.writer
 	needsresponse atomic.Bool // used to track when responses need to be sent that might be missed by the respond condition.
 	chunkwakefreq atomic.Uint32
+	revealing     atomic.Uint64 // piece+1 revealed to the peer while super seeding, 0 when none.
+
+	// state of the peer as decided by the torrent's choker.
+	unchoked       atomic.Bool
+	chokeduntil    *atomic.Pointer[time.Time] // the choker keeps the peer choked until this time.
+	choking        ChokePeer
+	chokingread    int64 // useful bytes read as of the previous choker round.
+	chokingwritten int64 // bytes written as of the previous choker round.
 }
 
 func (cn *connection) requestseq() []request {
@@ -231,10 +247,17 @@ func (cn *connection) cmu() sync.Locker {
 // invalid, such as by receiving badly sized BITFIELD, or invalid HAVE
 // messages.
 func (cn *connection) resetclaimed() error {
+	// the availability was reset along with the chunks.
+	cn.cmu().Lock()
+	cn.advertised.Clear()
+	cn.advertisedAll = false
+	cn.cmu().Unlock()
+
 	if cn.peerSentHaveAll {
 		cn.cmu().Lock()
 		cn.t.chunks.fill(cn.claimed, uint64(cn.t.chunks.cmaximum))
 		cn.cmu().Unlock()
+		cn.advertiseAll()
 	} else {
 		cn.cmu().Lock()
 		cn.claimed.Clear()
@@ -270,6 +293,7 @@ func (cn *connection) Close() {
 	// cn.cfg.debug().Output(2, fmt.Sprintf("c(%p) seed(%t) Close initiated\n", cn, cn.t.seeding()))
 	// defer cn.cfg.debug().Output(2, fmt.Sprintf("c(%p) seed(%t) Close initiated\n", cn, cn.t.seeding()))
 	defer cn.deleteAllRequests()
+	defer cn.unadvertise()
 	cn.cmu().Lock()
 	defer cn.cmu().Unlock()
 
@@ -519,6 +543,12 @@ func (cn *connection) peerSentHave(piece uint64) error {
 	cn.claimed.AddRange(cn.t.chunks.Range(piece))
 	cn.cmu().Unlock()
 
+	cn.advertise(piece)
+
+	if cn.t.superseed.enabled.Load() {
+		cn.t.superseedSpread()
+	}
+
 	return nil
 }
 
@@ -541,6 +571,7 @@ func (cn *connection) peerSentBitfield(bf []bool) error {
 		// bf = bf[:cn.t.chunks.pieces]
 	}
 
+	pids := make([]uint64, 0, len(bf))
 	for i, have := range bf {
 		if !have {
 			continue
@@ -552,7 +583,9 @@ func (cn *connection) peerSentBitfield(bf []bool) error {
 		cn._mu.Lock()
 		cn.claimed.AddRange(min, max)
 		cn._mu.Unlock()
+		pids = append(pids, uint64(i))
 	}
+	cn.advertise(pids...)
 	cn.peerPiecesChanged()
 	return nil
 }
@@ -562,6 +595,7 @@ func (cn *connection) onPeerSentHaveAll() {
 	cn.peerSentHaveAll = true
 	cn.t.chunks.fill(cn.claimed, uint64(cn.t.chunks.cmaximum))
 	cn.cmu().Unlock()
+	cn.advertiseAll()
 	cn.peerPiecesChanged()
 }
 
@@ -570,14 +604,69 @@ func (cn *connection) peerSentHaveNone() error {
 	cn.peerSentHaveAll = false
 	cn.claimed.Clear()
 	cn.cmu().Unlock()
+	cn.unadvertise()
 	cn.peerPiecesChanged()
 	return nil
 }
 
+// count the pieces towards the torrent's availability.
+func (cn *connection) advertise(pids ...uint64) {
+	cn.cmu().Lock()
+	defer cn.cmu().Unlock()
+
+	if cn.advertisedAll {
+		return
+	}
+
+	pids = slices.DeleteFunc(pids, func(pid uint64) bool {
+		return !cn.advertised.CheckedAdd(uint32(pid))
+	})
+
+	cn.t.chunks.Claimed(1, pids...)
+}
+
+// returns true if the piece is counted towards the torrent's availability on behalf of the peer.
+func (cn *connection) advertising(pid uint64) bool {
+	cn.cmu().Lock()
+	defer cn.cmu().Unlock()
+	return cn.advertised.Contains(uint32(pid))
+}
+
+// peer has every piece, replacing any individually counted pieces.
+func (cn *connection) advertiseAll() {
+	cn.unadvertise()
+
+	cn.cmu().Lock()
+	defer cn.cmu().Unlock()
+	cn.advertisedAll = true
+	cn.t.chunks.ClaimedAll(1)
+}
+
+// remove the peer's pieces from the torrent's availability.
+func (cn *connection) unadvertise() {
+	cn.cmu().Lock()
+	defer cn.cmu().Unlock()
+
+	if cn.t == nil {
+		return
+	}
+
+	if cn.advertisedAll {
+		cn.t.chunks.ClaimedAll(-1)
+		cn.advertisedAll = false
+	}
+
+	cn.advertised.Iterate(func(pid uint32) bool {
+		cn.t.chunks.Claimed(-1, uint64(pid))
+		return true
+	})
+	cn.advertised.Clear()
+}
+
 func (cn *connection) extensionEnabled(id pp.ExtensionName) bool {
 	cn._mu.RLock()
 	defer cn._mu.RUnlock()
-	return cn.PeerExtensionIDs[id] != 0 && cn.cfg.extensions[id] != 0
+	return cn.PeerExtensionIDs[id] != 0 && cn.localExtensions()[id] != 0
 }
 
 func (cn *connection) extension(id pp.ExtensionName) pp.ExtensionNumber {
@@ -696,6 +785,17 @@ func (cn *connection) reject(r request) bool {
 	return true
 }
 
+// cancel an outstanding request, returns false if the request was not outstanding.
+func (cn *connection) cancel(r request) bool {
+	if !cn.clearRequests(r) {
+		return false
+	}
+
+	cn.Post(r.ToMsg(pp.Cancel))
+
+	return true
+}
+
 func (cn *connection) onReadRequest(r request) error {
 	requestedChunkLengths.Add(strconv.FormatUint(r.Length.Uint64(), 10), 1)
 	cn._mu.RLock()
@@ -800,9 +900,11 @@ func (cn *connection) ReadOne(ctx context.Context, decoder *pp.Decoder) (msg pp.
 		return msg, nil
 	case pp.Interested:
 		cn.PeerInterested = true
+		cn.t.rechoke()
 		return msg, nil
 	case pp.NotInterested:
 		cn.PeerInterested = false
+		cn.t.rechoke()
 		// We don't clear their requests since it isn't clear in the spec.
 		// We'll probably choke them for this, which will clear them if
 		// appropriate, and is clearly specified.
@@ -885,6 +987,13 @@ func (cn *connection) ReadOne(ctx context.Context, decoder *pp.Decoder) (msg pp.
 
 		cn.peerPiecesChanged()
 
+		return msg, nil
+	case pp.HashRequest:
+		return msg, cn.onHashRequest(msg)
+	case pp.Hashes:
+		return msg, cn.onHashes(msg)
+	case pp.HashReject:
+		cn.cfg.debug().Printf("c(%p) seed(%t) peer rejected hash request %x\n", cn, cn.t.seeding(), msg.PiecesRoot)
 		return msg, nil
 	case pp.Extended:
 		defer cn.request.Broadcast()
@@ -958,7 +1067,7 @@ func (cn *connection) onReadExtendedMsg(id pp.ExtensionNumber, payload []byte) (
 		// log.Println("metadata extension available")
 		return errorsx.Wrap(t.gotMetadataExtensionMsg(payload, cn), "handling metadata extension message")
 	case pp.PEXExtendedID:
-		if _, ok := cn.cfg.extensions[pp.ExtensionNamePex]; !ok {
+		if _, ok := cn.localExtensions()[pp.ExtensionNamePex]; !ok {
 			// TODO: Maybe close the connection.
 			return nil
 		}
@@ -1024,6 +1133,10 @@ func (cn *connection) receiveChunk(msg *pp.Message) error {
 		return errorsx.Wrap(err, "failed to verify")
 	}
 
+	if cn.t.chunks.Deduplicate(req) {
+		cn.t.cancelRequest(cn, req)
+	}
+
 	// It's important that the piece is potentially queued before we check if
 	// the piece is still wanted, because if it is queued, it won't be wanted.
 	if idx := uint64(req.Index); cn.t.chunks.ChunksAvailable(idx) {
@@ -1044,7 +1157,7 @@ func (cn *connection) peerHasWantedPieces() bool {
 		return false
 	}
 
-	return cn.t.chunks.Intersects(cn.claimed, cn.t.chunks.missing)
+	return cn.t.chunks.Wanted(cn.claimed)
 }
 
 // clearRequests drops the requests from the local connection.
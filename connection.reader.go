@@ -30,7 +30,6 @@ func connreaderinit(ctx context.Context, cn *connection, to time.Duration) (err
 		bufferLimit:      256 * bytesx.KiB,
 		connection:       cn,
 		keepAliveTimeout: to,
-		chokeduntil:      ts.Add(-1 * time.Minute),
 		uploadavailable:  atomicx.Pointer(ts),
 		seed:             cn.t.seeding(),
 		Idler:            cstate.Idle(ctx, cn.upload),
@@ -51,7 +50,6 @@ type readerstate struct {
 	*connection
 	bufferLimit      int
 	keepAliveTimeout time.Duration
-	chokeduntil      time.Time
 	seed             bool
 	uploadavailable  *atomic.Pointer[time.Time]
 	pool             *sync.Pool    // buffer pool for storing chunks
@@ -89,8 +87,7 @@ type _connreaderAllowRequests struct {
 }
 
 func (t _connreaderAllowRequests) Update(ctx context.Context, _ *cstate.Shared) (r cstate.T) {
-	ts := time.Now()
-	if t.seed || t.chokeduntil.After(ts) {
+	if t.unchoked.Load() {
 		if t.Unchoke(messageWriter(t.readerstate.bufmsgold).Deprecated()) {
 			t.cfg.debug().Printf("c(%p) seed(%t) allowing peer to make requests\n", t.connection, t.seed)
 		}
@@ -139,9 +136,9 @@ func (t *_connreaderUpload) writechunk(r request) (ok bool, err error) {
 
 // Also handles choking and unchoking of the remote peer.
 func (t _connreaderUpload) upload() (time.Duration, error) {
-	if t.Choked && t.chokeduntil.After(time.Now()) {
+	if until := langx.Autoderef(t.chokeduntil.Load()); t.Choked && until.After(time.Now()) {
 		t.cfg.debug().Printf("c(%p) seed(%t) choked(%t) peer completed(%t) req(%d) upload restricted - disallowed\n", t.connection, t.seed, t.Choked, t.peerSentHaveAll, len(t.PeerRequests))
-		return timex.DurationMax(time.Until(t.chokeduntil), 0), nil
+		return timex.DurationMax(time.Until(until), 0), nil
 	}
 
 	if t.peerSentHaveAll {
@@ -80,7 +80,7 @@ func RunHandshookConn(c *connection, t *torrent) error {
 func ConnExtensions(ctx context.Context, cn *connection) error {
 	cn.cfg.debug().Println("conn extensions initiated")
 	defer cn.cfg.debug().Println("conn extensions completed")
-	return cstate.Run(ctx, connexinit(cn, connexfast(cn, connexdht(cn, connflush(cn, nil)))), cn.cfg.debug())
+	return cstate.Run(ctx, connexinit(cn, connexfast(cn, connexdht(cn, connexlayers(cn, connflush(cn, nil))))), cn.cfg.debug())
 }
 
 func connflush(cn *connection, n cstate.T) cstate.T {
@@ -105,7 +105,7 @@ func connexinit(cn *connection, n cstate.T) cstate.T {
 		// TODO: We can figured the port and address out specific to the socket
 		// used.
 		msg := btprotocol.ExtendedHandshakeMessage{
-			M:            cn.cfg.extensions,
+			M:            cn.localExtensions(),
 			V:            cn.cfg.ExtendedHandshakeClientVersion,
 			Reqq:         cn.cfg.maximumOutstandingRequests,
 			YourIp:       btprotocol.CompactIp(cn.remoteAddr.Addr().AsSlice()),
@@ -136,6 +136,23 @@ func connexinit(cn *connection, n cstate.T) cstate.T {
 func connexfast(cn *connection, n cstate.T) cstate.T {
 	return cstate.Fn(func(context.Context, *cstate.Shared) cstate.T {
 		defer cn.cfg.debug().Printf("c(%p) seed(%t) fast extension completed\n", cn, cn.t.seeding())
+
+		// pieces are revealed individually while super seeding.
+		if cn.t.superseeding() {
+			cn.sentHaves.Clear()
+			if !cn.supported(btprotocol.ExtensionBitFast) {
+				if _, err := cn.PostBitfield(cn.sentHaves); err != nil {
+					return cstate.Failure(err)
+				}
+				return n
+			}
+
+			if _, err := cn.Post(btprotocol.NewHaveNone()); err != nil {
+				return cstate.Failure(err)
+			}
+			return n
+		}
+
 		if !cn.supported(btprotocol.ExtensionBitFast) {
 			cn.sentHaves = cn.t.chunks.CompletedBitmap()
 			if _, err := cn.PostBitfield(cn.sentHaves); err != nil {
@@ -183,6 +200,16 @@ func connexfast(cn *connection, n cstate.T) cstate.T {
 	})
 }
 
+func connexlayers(cn *connection, n cstate.T) cstate.T {
+	return cstate.Fn(func(context.Context, *cstate.Shared) cstate.T {
+		if err := cn.requestPieceLayers(); err != nil {
+			return cstate.Failure(errorsx.Wrap(err, "unable to request piece layers"))
+		}
+
+		return n
+	})
+}
+
 func connexdht(cn *connection, n cstate.T) cstate.T {
 	return cstate.Fn(func(context.Context, *cstate.Shared) cstate.T {
 		dynamicaddr := langx.Autoderef(cn.dynamicaddr.Load())
@@ -217,7 +244,6 @@ func connwriterinit(ctx context.Context, cn *connection, to time.Duration) (err
 		bufferLimit:         writebufferscapacity,
 		connection:          cn,
 		keepAliveTimeout:    to,
-		chokeduntil:         ts.Add(-1 * time.Minute),
 		nextbitmap:          ts.Add(time.Minute),
 		keepaliverequired:   atomicx.Pointer(ts.Add(to)),
 		resyncbitfield:      atomicx.Pointer(ts.Add(time.Minute)),
@@ -239,7 +265,6 @@ type writerstate struct {
 	bufferLimit         int
 	keepAliveTimeout    time.Duration
 	nextbitmap          time.Time
-	chokeduntil         time.Time
 	seed                bool
 	keepaliverequired   *atomic.Pointer[time.Time]
 	resyncbitfield      *atomic.Pointer[time.Time]
@@ -279,6 +304,14 @@ type _connWriterSyncBitfield struct {
 func (t _connWriterSyncBitfield) Update(ctx context.Context, _ *cstate.Shared) (r cstate.T) {
 	ws := t.writerstate
 
+	if ws.t.superseeding() {
+		if err := ws.superseed(ws.bufmsg); err != nil {
+			return cstate.Failure(err)
+		}
+
+		return t.next
+	}
+
 	if ts := ws.resyncbitfield.Load(); ts.After(time.Now()) {
 		return t.next
 	}
@@ -339,8 +372,10 @@ func (t _connWriterClosed) Update(ctx context.Context, _ *cstate.Shared) (r csta
 
 		ts := time.Now()
 
-		if ws.chokeduntil.Add(time.Minute).Before(ts) {
-			ws.chokeduntil = ts.Add(backoffx.DynamicHash1m(ws.PeerID.String()) + backoffx.Random(10*time.Minute))
+		if langx.Autoderef(ws.chokeduntil.Load()).Add(time.Minute).Before(ts) {
+			ws.chokeduntil.Store(langx.Autoptr(ts.Add(backoffx.DynamicHash1m(ws.PeerID.String()) + backoffx.Random(10*time.Minute))))
+			ws.unchoked.Store(false)
+			ws.t.rechoke()
 		} else if d := time.Since(ws.lastUsefulChunkReceived); d > 4*ws.t.chunks.gracePeriod {
 			return cstate.Failure(
 				errorsx.Timedout(
@@ -388,7 +423,7 @@ type _connwriterRequests struct {
 
 func (t _connwriterRequests) determineInterest(msg messageWriter) *roaring.Bitmap {
 	// defer t.cfg.debug().Printf("c(%p) seed(%t) interest completed requestable(%d)\n", t.connection, t.seed, t.requestable.GetCardinality())
-	if t.seed || t.chokeduntil.After(time.Now()) {
+	if t.unchoked.Load() {
 		if t.Unchoke(msg.Deprecated()) {
 			t.cfg.debug().Printf("c(%p) seed(%t) allowing peer to make requests\n", t.connection, t.seed)
 		}
@@ -530,6 +565,39 @@ func (t _connwriterRequests) genrequests(available *roaring.Bitmap, msg messageW
 		t.cfg.debug().Printf("c(%p) seed(%t) filled - cleaning up %d reqs(%d)\n", t.connection, t.seed, max, len(reqs))
 		// release any unused requests back to the queue.
 		t.t.chunks.Retry(reqs...)
+		return
+	}
+
+	t.endgame(available, msg)
+}
+
+// endgame requests chunks already outstanding with other peers once the remaining
+// work is small, preventing a slow peer from holding up the completion of the torrent.
+func (t _connwriterRequests) endgame(available *roaring.Bitmap, msg messageWriter) {
+	if t.cfg.endgameThreshold <= 0 {
+		return
+	}
+
+	t.cmu().Lock()
+	room := t.lowrequestwatermark - len(t.requests)
+	t.cmu().Unlock()
+
+	for _, req := range t.t.chunks.Endgame(t.cfg.endgameThreshold, room, available) {
+		t.cmu().Lock()
+		_, requested := t.requests[req.Digest]
+		t.cmu().Unlock()
+
+		if requested {
+			continue
+		}
+
+		if filledBuffer := !t.request(req, msg); filledBuffer {
+			return
+		}
+
+		t.requestable.Remove(uint32(t.t.chunks.requestCID(req)))
+		t.t.chunks.Duplicated(req)
+		t.allStats(add(1, func(cs *ConnStats) *count { return &cs.ChunksEndgameRequested }))
 	}
 }
 
@@ -651,7 +719,7 @@ func connwriteridle(ws *writerstate) cstate.T {
 	ts := []time.Time{
 		keepalive,
 		ws.nextbitmap,
-		timex.Max(ws.chokeduntil, keepalive),
+		timex.Max(langx.Autoderef(ws.chokeduntil.Load()), keepalive),
 		langx.Autoderef(ws.keepaliverequired.Load()),
 		langx.Autoderef(ws.refreshrequestable.Load()),
 	}
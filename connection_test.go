@@ -650,3 +650,39 @@ func BenchmarkRequestIteration(b *testing.B) {
 		}
 	})
 }
+
+func TestConnectionAvailability(t *testing.T) {
+	cl := &Client{config: TestingConfig(t, t.TempDir())}
+	ts, err := New(metainfo.Hash{})
+	require.NoError(t, err)
+	tt := newTorrent(cl, ts)
+	require.NoError(t, tt.setInfo(&metainfo.Info{
+		Pieces:      make([]byte, metainfo.HashSize*3),
+		Length:      24 * (1 << 10),
+		PieceLength: 8 * (1 << 10),
+	}))
+
+	availability := func() []int {
+		return []int{tt.chunks.Availability(0), tt.chunks.Availability(1), tt.chunks.Availability(2)}
+	}
+
+	c1 := cl.newConnection(nil, false, netip.AddrPort{})
+	c1.setTorrent(tt)
+	c2 := cl.newConnection(nil, false, netip.AddrPort{})
+	c2.setTorrent(tt)
+
+	require.NoError(t, c1.peerSentBitfield([]bool{true, false, true, false, false, false, false, false}))
+	require.NoError(t, c2.peerSentHave(2))
+	require.NoError(t, c2.peerSentHave(2))
+	require.Equal(t, []int{1, 0, 2}, availability())
+
+	c2.onPeerSentHaveAll()
+	require.Equal(t, []int{2, 1, 2}, availability())
+	require.Equal(t, int32(0), tt.chunks.rarity(1))
+
+	require.NoError(t, c1.peerSentHaveNone())
+	require.Equal(t, []int{1, 1, 1}, availability())
+
+	c2.Close()
+	require.Equal(t, []int{0, 0, 0}, availability())
+}
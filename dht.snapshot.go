@@ -0,0 +1,112 @@
+package torrent
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"time"
+
+	"github.com/james-lawrence/torrent/dht"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+)
+
+const (
+	// duration restored nodes are given to respond before they're removed.
+	dhtRestoreTimeout = 30 * time.Second
+	// bucket limit of snapshotted routing tables when none is configured.
+	dhtSnapshotBucketLimit = 8
+)
+
+// restore the routing table of the DHT server from the snapshot, the nodes are filtered
+// to the address family of the connection. see ClientConfigDHTSnapshot. returns the
+// restored nodes, which are expected to be validated while the server bootstraps.
+func (cl *Client) dhtRestore(s *dht.Server, conn net.PacketConn) (restored []dht.SnapshotNode, err error) {
+	if cl.config.dhtSnapshotPath == "" {
+		return nil, nil
+	}
+
+	ns, err := dht.ReadSnapshotFromFile(cl.config.dhtSnapshotPath)
+	if errorsx.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errorsx.Wrap(err, "unable to read dht snapshot")
+	}
+
+	ipv6 := connIsIpv6(conn)
+	family := make([]dht.SnapshotNode, 0, len(ns))
+	for _, n := range ns {
+		if (n.Addr.IP().To4() == nil) == ipv6 {
+			family = append(family, n)
+		}
+	}
+
+	return s.Restore(family), nil
+}
+
+// remove the restored nodes that no longer respond.
+func (cl *Client) dhtValidateRestored(s *dht.Server, restored []dht.SnapshotNode) {
+	if len(restored) == 0 {
+		return
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), dhtRestoreTimeout)
+	defer done()
+
+	validated := s.ValidateRestored(ctx, restored)
+	cl.config.debug().Printf("%v validated %d / %d restored nodes\n", s, validated, len(restored))
+}
+
+// persist the routing tables of every DHT server. an empty snapshot never replaces a
+// previous one, e.g. when the client is closed before the DHT is bootstrapped.
+func (cl *Client) dhtSnapshot() error {
+	if cl.config.dhtSnapshotPath == "" {
+		return nil
+	}
+
+	cl.rLock()
+	servers := cl.dhtServers
+	cl.rUnlock()
+
+	var (
+		ns   []dht.SnapshotNode
+		seen = make(map[string]struct{})
+	)
+
+	for _, s := range servers {
+		for _, n := range s.Snapshot() {
+			if _, ok := seen[n.Addr.String()]; ok {
+				continue
+			}
+
+			seen[n.Addr.String()] = struct{}{}
+			ns = append(ns, n)
+		}
+	}
+
+	if len(ns) == 0 {
+		return nil
+	}
+
+	if err := dht.WriteSnapshotToFile(ns, cl.config.dhtSnapshotPath); err != nil {
+		return errorsx.Wrap(err, "unable to write dht snapshot")
+	}
+
+	return nil
+}
+
+func (cl *Client) dhtSnapshotter() {
+	ticker := time.NewTicker(cl.config.dhtSnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-cl.closed:
+			return
+		}
+
+		if err := cl.dhtSnapshot(); err != nil {
+			cl.config.errors().Println(err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package torrent
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/dht"
+	"github.com/james-lawrence/torrent/dht/dhttest"
+)
+
+func TestDHTSnapshot(t *testing.T) {
+	bootstrap := dhttest.NewServer(t, dht.DefaultMuxer())
+	path := filepath.Join(t.TempDir(), "dht.nodes")
+
+	var consulted atomic.Int64
+	newClient := func() *Client {
+		starting := ClientConfigBootstrapFn(func(string) dht.StartingNodesGetter {
+			return func() ([]dht.Addr, error) {
+				consulted.Add(1)
+				return []dht.Addr{dht.NewAddr(bootstrap.Addr())}, nil
+			}
+		})
+
+		cl, err := Autosocket(t, BinderOptionDHT).Bind(NewClient(TestingConfig(t, t.TempDir(), starting, ClientConfigDHTSnapshot(path, time.Hour))))
+		require.NoError(t, err)
+		require.Len(t, cl.DhtServers(), 1)
+		return cl
+	}
+
+	cl := newClient()
+	require.Eventually(t, func() bool { return cl.DhtServers()[0].NumNodes() > 0 }, 10*time.Second, 10*time.Millisecond)
+	require.NoError(t, cl.Close())
+	require.FileExists(t, path)
+
+	// the restarted client restores the routing table instead of bootstrapping from scratch.
+	consulted.Store(0)
+	cl = newClient()
+	defer cl.Close()
+
+	s := cl.DhtServers()[0]
+	require.Eventually(t, func() bool {
+		ns := s.Snapshot()
+		return len(ns) == 1 && ns[0].ID == bootstrap.ID() && !ns[0].LastResponse.IsZero()
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Zero(t, consulted.Load())
+}
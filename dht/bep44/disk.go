@@ -0,0 +1,322 @@
+package bep44
+
+import (
+	"container/list"
+	"encoding/hex"
+	"io/fs"
+	"iter"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+)
+
+const defaultDiskMaxBytes = 32 << 20
+
+// DiskOption configures a Disk store.
+type DiskOption func(*Disk)
+
+// DiskOptionMaxBytes caps the bytes used by the items on disk, the least recently
+// used items are evicted to make room. items originated by the local node are never
+// evicted. defaults to 32MiB.
+func DiskOptionMaxBytes(n int64) DiskOption {
+	return func(t *Disk) {
+		t.maxBytes = n
+	}
+}
+
+// NewDisk opens a store persisting each item to a file within the directory, items
+// stored by a previous process are restored. stale sequence numbers are rejected
+// across restarts.
+func NewDisk(dir string, options ...DiskOption) (_ *Disk, err error) {
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, errorsx.Wrapf(err, "unable to ensure item store directory %s", dir)
+	}
+
+	d := &Disk{
+		dir:      dir,
+		maxBytes: defaultDiskMaxBytes,
+		index:    make(map[Target]*list.Element),
+		order:    list.New(),
+	}
+
+	for _, opt := range options {
+		opt(d)
+	}
+
+	if err = d.load(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Disk is a Store that persists items to disk, so they survive restarts. the store
+// is bounded by the total size of the items, evicting the least recently used.
+type Disk struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	index map[Target]*list.Element
+	order *list.List // diskitem ordered by the last use, least recent first.
+	bytes int64      // size of the items on disk.
+}
+
+var _ interface {
+	Store
+	Originator
+} = (*Disk)(nil)
+
+type diskitem struct {
+	*Item
+	size int64
+}
+
+// the encoded form of an item on disk.
+type diskrecord struct {
+	V       bencode.Bytes `bencode:"v"`
+	K       [32]byte      `bencode:"k"`
+	Salt    []byte        `bencode:"salt,omitempty"`
+	Sig     [64]byte      `bencode:"sig"`
+	Cas     int64         `bencode:"cas"`
+	Seq     int64         `bencode:"seq"`
+	Created int64         `bencode:"created"`
+	Origin  bool          `bencode:"origin"`
+}
+
+func (t *Disk) path(target Target) string {
+	return filepath.Join(t.dir, hex.EncodeToString(target[:]))
+}
+
+func (t *Disk) Put(i *Item) error {
+	if err := Check(i); err != nil {
+		return err
+	}
+
+	encoded, err := bencode.Marshal(diskrecord{
+		V:       bencode.MustMarshal(i.V),
+		K:       i.K,
+		Salt:    i.Salt,
+		Sig:     i.Sig,
+		Cas:     i.Cas,
+		Seq:     i.Seq,
+		Created: i.created.UnixNano(),
+		Origin:  i.origin,
+	})
+	if err != nil {
+		return errorsx.Wrap(err, "unable to encode item")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	target := i.Target()
+	needed := int64(len(encoded))
+	if el, ok := t.index[target]; ok {
+		if err = CheckIncoming(el.Value.(diskitem).Item, i); err != nil {
+			return err
+		}
+
+		needed -= el.Value.(diskitem).size
+	}
+
+	t.evict(needed, target)
+
+	// originated items are always stored, they're bounded by the local node.
+	if t.bytes+needed > t.maxBytes && !i.origin {
+		return ErrStoreFull
+	}
+
+	if err = t.write(target, encoded); err != nil {
+		return err
+	}
+
+	t.insert(target, diskitem{Item: i, size: int64(len(encoded))})
+
+	return nil
+}
+
+func (t *Disk) Get(target Target) (*Item, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	el, ok := t.index[target]
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+
+	t.order.MoveToBack(el)
+
+	return el.Value.(diskitem).Item, nil
+}
+
+func (t *Disk) Del(target Target) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	el, ok := t.index[target]
+	if !ok {
+		return nil
+	}
+
+	return t.remove(el)
+}
+
+func (t *Disk) Originated() iter.Seq[*Item] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var originated []*Item
+	for el := t.order.Front(); el != nil; el = el.Next() {
+		if i := el.Value.(diskitem).Item; i.origin {
+			originated = append(originated, i)
+		}
+	}
+
+	return slices.Values(originated)
+}
+
+func (t *Disk) insert(target Target, di diskitem) {
+	if el, ok := t.index[target]; ok {
+		t.bytes -= el.Value.(diskitem).size
+		el.Value = di
+		t.order.MoveToBack(el)
+	} else {
+		t.index[target] = t.order.PushBack(di)
+	}
+
+	t.bytes += di.size
+}
+
+func (t *Disk) remove(el *list.Element) error {
+	di := t.order.Remove(el).(diskitem)
+	target := di.Target()
+	delete(t.index, target)
+	t.bytes -= di.size
+
+	if err := os.Remove(t.path(target)); errorsx.Ignore(err, fs.ErrNotExist) != nil {
+		return errorsx.Wrap(err, "unable to remove item")
+	}
+
+	return nil
+}
+
+// evict the least recently used items until the additional bytes fit, originated
+// items are never evicted.
+func (t *Disk) evict(needed int64, retain Target) {
+	for el := t.order.Front(); el != nil && t.bytes+needed > t.maxBytes; {
+		next := el.Next()
+		if di := el.Value.(diskitem); !di.origin && di.Target() != retain {
+			if err := t.remove(el); err != nil {
+				log.Println("unable to evict item", err)
+			}
+		}
+		el = next
+	}
+}
+
+// atomically replace the item on disk.
+func (t *Disk) write(target Target, encoded []byte) (err error) {
+	dst, err := os.CreateTemp(t.dir, "item.*.tmp")
+	if err != nil {
+		return errorsx.Wrap(err, "unable to create item")
+	}
+	defer os.Remove(dst.Name())
+	defer dst.Close()
+
+	if _, err = dst.Write(encoded); err != nil {
+		return errorsx.Wrap(err, "unable to write item")
+	}
+
+	if err = dst.Sync(); err != nil {
+		return errorsx.Wrap(err, "unable to write item")
+	}
+
+	if err = os.Rename(dst.Name(), t.path(target)); err != nil {
+		return errorsx.Wrap(err, "unable to replace item")
+	}
+
+	return nil
+}
+
+// restore the items stored within the directory, least recently stored first.
+func (t *Disk) load() error {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return errorsx.Wrapf(err, "unable to read item store %s", t.dir)
+	}
+
+	restored := make([]diskitem, 0, len(entries))
+	for _, e := range entries {
+		path := filepath.Join(t.dir, e.Name())
+
+		// temporary files are left behind by writes interrupted by a crash.
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			if err = os.Remove(path); err != nil {
+				log.Println("unable to remove partially written item", path, err)
+			}
+			continue
+		}
+
+		di, err := readdiskitem(path)
+		if err != nil {
+			log.Println("ignoring item", path, err)
+			continue
+		}
+
+		restored = append(restored, di)
+	}
+
+	slices.SortFunc(restored, func(a, b diskitem) int {
+		return a.created.Compare(b.created)
+	})
+
+	for _, di := range restored {
+		t.insert(di.Target(), di)
+	}
+
+	t.evict(0, Target{})
+
+	return nil
+}
+
+func readdiskitem(path string) (di diskitem, err error) {
+	var r diskrecord
+
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return di, err
+	}
+
+	if err = bencode.Unmarshal(encoded, &r); err != nil {
+		return di, err
+	}
+
+	i := &Item{
+		created: time.Unix(0, r.Created),
+		origin:  r.Origin,
+		V:       r.V,
+		K:       r.K,
+		Salt:    r.Salt,
+		Sig:     r.Sig,
+		Cas:     r.Cas,
+		Seq:     r.Seq,
+	}
+
+	if err = Check(i); err != nil {
+		return di, err
+	}
+
+	if target := i.Target(); filepath.Base(path) != hex.EncodeToString(target[:]) {
+		return di, errorsx.Errorf("item does not match its target %x", target)
+	}
+
+	return diskitem{Item: i, size: int64(len(encoded))}, nil
+}
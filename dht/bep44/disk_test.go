@@ -0,0 +1,148 @@
+package bep44
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/bencode"
+)
+
+func openDisk(t *testing.T, dir string, options ...DiskOption) *Disk {
+	d, err := NewDisk(dir, options...)
+	require.NoError(t, err)
+	return d
+}
+
+func immutable(t *testing.T, v string) *Item {
+	i, err := NewItem(v, nil, 0, 0, nil)
+	require.NoError(t, err)
+	return i
+}
+
+// the value of the item as it is sent in replies.
+func encoded(i *Item) string {
+	return string(bencode.MustMarshal(i.V))
+}
+
+func TestDisk(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	mutable := func(t *testing.T, v string, seq int64) *Item {
+		i, err := NewItem(v, []byte("salt"), seq, 0, key)
+		require.NoError(t, err)
+		return i
+	}
+
+	t.Run("items survive restarts", func(t *testing.T) {
+		dir := t.TempDir()
+		w := NewWrapper(openDisk(t, dir), time.Hour)
+
+		i1 := immutable(t, "hello")
+		i2 := mutable(t, "world", 1)
+		require.NoError(t, w.Put(i1))
+		require.NoError(t, w.Put(i2))
+
+		w = NewWrapper(openDisk(t, dir), time.Hour)
+		for _, i := range []*Item{i1, i2} {
+			restored, err := w.Get(i.Target())
+			require.NoError(t, err)
+			require.Equal(t, encoded(i), encoded(restored))
+			require.Equal(t, i.Seq, restored.Seq)
+			require.Equal(t, i.Sig, restored.Sig)
+			require.True(t, i.created.Equal(restored.created))
+		}
+	})
+
+	t.Run("stale sequence numbers are rejected across restarts", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, NewWrapper(openDisk(t, dir), time.Hour).Put(mutable(t, "v2", 2)))
+
+		w := NewWrapper(openDisk(t, dir), time.Hour)
+		require.Equal(t, ErrSequenceNumberLessThanCurrent, w.Put(mutable(t, "v1", 1)))
+		require.NoError(t, w.Put(mutable(t, "v3", 3)))
+	})
+
+	t.Run("value size limits are enforced", func(t *testing.T) {
+		d := openDisk(t, t.TempDir())
+		require.Equal(t, ErrValueFieldTooBig, d.Put(immutable(t, strings.Repeat("x", 1000))))
+
+		i := mutable(t, "v", 1)
+		i.Salt = make([]byte, 65)
+		require.Equal(t, ErrSaltFieldTooBig, d.Put(i))
+	})
+
+	t.Run("least recently used items are evicted", func(t *testing.T) {
+		dir := t.TempDir()
+		items := []*Item{immutable(t, "a"), immutable(t, "b"), immutable(t, "c")}
+
+		d := openDisk(t, dir)
+		require.NoError(t, d.Put(items[0]))
+		size := d.bytes
+
+		d = openDisk(t, dir, DiskOptionMaxBytes(2*size))
+		require.NoError(t, d.Put(items[1]))
+		_, err := d.Get(items[0].Target())
+		require.NoError(t, err)
+		require.NoError(t, d.Put(items[2]))
+
+		_, err = d.Get(items[1].Target())
+		require.Equal(t, ErrItemNotFound, err)
+
+		d = openDisk(t, dir, DiskOptionMaxBytes(2*size))
+		for _, i := range []*Item{items[0], items[2]} {
+			_, err := d.Get(i.Target())
+			require.NoError(t, err)
+		}
+
+		// items that exceed the budget by themselves are rejected.
+		require.Equal(t, ErrStoreFull, openDisk(t, t.TempDir(), DiskOptionMaxBytes(size-1)).Put(immutable(t, "d")))
+	})
+
+	t.Run("originated items are retained and republished", func(t *testing.T) {
+		dir := t.TempDir()
+		i := mutable(t, "local", 1)
+
+		d := openDisk(t, dir)
+		w := NewWrapper(d, 0)
+		require.NoError(t, w.Originate(i))
+		size := d.bytes
+
+		// nodes republishing the item do not change where it originated.
+		republished := mutable(t, "local", 1)
+		require.NoError(t, w.Put(republished))
+
+		// originated items are never evicted to make room.
+		d = openDisk(t, dir, DiskOptionMaxBytes(size))
+		require.Equal(t, ErrStoreFull, d.Put(immutable(t, "a")))
+
+		restored, err := NewWrapper(d, 0).Get(i.Target())
+		require.NoError(t, err)
+		require.Equal(t, encoded(i), encoded(restored))
+		originated := slices.Collect(d.Originated())
+		require.Len(t, originated, 1)
+		require.Equal(t, i.Target(), originated[0].Target())
+	})
+
+	t.Run("partially written and corrupt items are ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		i := immutable(t, "hello")
+		require.NoError(t, openDisk(t, dir).Put(i))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "item.1234.tmp"), []byte("d1:v"), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, strings.Repeat("0", 40)), []byte("garbage"), 0600))
+
+		d := openDisk(t, dir)
+		_, err := d.Get(i.Target())
+		require.NoError(t, err)
+		require.Len(t, d.index, 1)
+		require.NoFileExists(t, filepath.Join(dir, "item.1234.tmp"))
+	})
+}
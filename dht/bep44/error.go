@@ -24,4 +24,8 @@ var (
 		Code: krpc.ErrorCodeSequenceNumberLessThanCurrent,
 		Msg:  "sequence number less than current",
 	}
+	ErrStoreFull = krpc.Error{
+		Code: krpc.ErrorCodeServerError,
+		Msg:  "storage full",
+	}
 )
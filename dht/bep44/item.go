@@ -15,6 +15,9 @@ var Empty32ByteArray [32]byte
 type Item struct {
 	// time when this object was added to storage
 	created time.Time
+	// the item was put by the local node, it never expires from the local store
+	// and is republished, see Originator.
+	origin bool
 
 	// Value to be stored
 	V interface{}
@@ -1,10 +1,15 @@
 package bep44
 
 import (
+	"iter"
+	"slices"
 	"sync"
 )
 
-var _ Store = &Memory{}
+var _ interface {
+	Store
+	Originator
+} = &Memory{}
 
 type Memory struct {
 	// protects m
@@ -47,3 +52,17 @@ func (m *Memory) Del(t Target) error {
 
 	return nil
 }
+
+func (m *Memory) Originated() iter.Seq[*Item] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var originated []*Item
+	for _, i := range m.m {
+		if i.origin {
+			originated = append(originated, i)
+		}
+	}
+
+	return slices.Values(originated)
+}
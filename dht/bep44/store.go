@@ -2,6 +2,7 @@ package bep44
 
 import (
 	"errors"
+	"iter"
 	"time"
 )
 
@@ -13,6 +14,12 @@ type Store interface {
 	Del(Target) error
 }
 
+// Originator is implemented by stores able to enumerate the items put by the local
+// node, allowing them to be republished before they expire from other nodes.
+type Originator interface {
+	Originated() iter.Seq[*Item]
+}
+
 // Wrapper is in charge of validate all new items and
 // decide when to store, or ignore them depending of the BEP 44 definition.
 // It is also in charge of removing expired items.
@@ -43,17 +50,26 @@ func (w *Wrapper) Put(i *Item) error {
 		return err
 	}
 
+	// other nodes republishing an item do not change where it originated.
+	i.origin = i.origin || (is.origin && is.Seq == i.Seq)
 	i.created = time.Now().Local()
 	return w.s.Put(i)
 }
 
+// Originate stores an item put by the local node, originated items never expire
+// from the store so they are able to be republished, see Originator.
+func (w *Wrapper) Originate(i *Item) error {
+	i.origin = true
+	return w.Put(i)
+}
+
 func (w *Wrapper) Get(t Target) (*Item, error) {
 	i, err := w.s.Get(t)
 	if err != nil {
 		return nil, err
 	}
 
-	if i.created.Add(w.exp).After(time.Now().Local()) {
+	if i.origin || i.created.Add(w.exp).After(time.Now().Local()) {
 		return i, nil
 	}
 
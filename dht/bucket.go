@@ -50,7 +50,7 @@ func (b *bucket) EachNode(f func(*node) bool) bool {
 		v, ok := next()
 		b._m.RUnlock()
 		if !ok {
-			return false
+			return true
 		}
 		if !f(v) {
 			return false
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/james-lawrence/torrent/bep0051"
+)
+
+type cmdCrawl struct {
+	query
+	Concurrency int           `arg:"--concurrency" default:"16" help:"number of nodes queried in parallel"`
+	Revisit     time.Duration `arg:"--revisit" help:"query nodes holding more infohashes than they sampled again, when their interval is within this duration"`
+}
+
+func (t cmdCrawl) Run(ctx context.Context) error {
+	ctx, done := t.context(ctx)
+	defer done()
+
+	s, err := t.bootstrapped(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	crawled, err := bep0051.Crawl(ctx, s, bep0051.CrawlOptionConcurrency(t.Concurrency), bep0051.CrawlOptionRevisit(t.Revisit))
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	for id := range crawled {
+		n++
+		fmt.Println(id)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d unique infohashes\n", n)
+	return nil
+}
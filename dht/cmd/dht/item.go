@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/dht/bep44"
+	"github.com/james-lawrence/torrent/dht/exts/getput"
+	"github.com/james-lawrence/torrent/dht/krpc"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+)
+
+type cmdPut struct {
+	query
+	Key   string `arg:"-k,--key" help:"file containing the hex encoded ed25519 seed used to sign a mutable item, generated when missing"`
+	Salt  string `arg:"--salt" help:"salt of the mutable item"`
+	Seq   int64  `arg:"--seq" help:"minimum sequence number of the mutable item, increments the sequence found on the DHT by default"`
+	Value string `arg:"positional,required" help:"value to store, bencoded as a string"`
+}
+
+func (t cmdPut) Run(ctx context.Context) error {
+	ctx, done := t.context(ctx)
+	defer done()
+
+	s, err := t.bootstrapped(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	put := bep44.Put{V: t.Value}
+	sign := func(int64) bep44.Put { return put }
+
+	if t.Key != "" {
+		key, err := loadKey(t.Key)
+		if err != nil {
+			return err
+		}
+
+		put.K = (*[32]byte)(key.Public().(ed25519.PublicKey))
+		put.Salt = []byte(t.Salt)
+		sign = func(seq int64) bep44.Put {
+			signed := put
+			signed.Seq = max(seq+1, t.Seq)
+			signed.Sign(key)
+			return signed
+		}
+	}
+
+	target := put.Target()
+	stats, err := getput.Put(ctx, krpc.ID(target), s, put.Salt, sign)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%x\n", target)
+	fmt.Fprintf(os.Stderr, "%d nodes contacted, %d responses\n", stats.NumAddrsTried, stats.NumResponses)
+	return nil
+}
+
+type cmdGet struct {
+	query
+	Salt   string `arg:"--salt" help:"salt of the mutable item"`
+	Seq    *int64 `arg:"--seq" help:"only return mutable items with a greater sequence number"`
+	Target string `arg:"positional,required" help:"hex encoded target of the item, see put"`
+}
+
+func (t cmdGet) Run(ctx context.Context) error {
+	ctx, done := t.context(ctx)
+	defer done()
+
+	target, err := decodeID(t.Target)
+	if err != nil {
+		return err
+	}
+
+	s, err := t.bootstrapped(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	res, stats, err := getput.Get(ctx, target.AsByteArray(), s, t.Seq, []byte(t.Salt))
+	if err != nil {
+		return err
+	}
+
+	var v any
+	if err = bencode.Unmarshal(res.V, &v); err != nil {
+		return errorsx.Wrap(err, "unable to decode the value")
+	}
+
+	if res.Mutable {
+		fmt.Fprintf(os.Stderr, "seq %d\n", res.Seq)
+	}
+
+	switch v := v.(type) {
+	case string:
+		fmt.Println(v)
+	default:
+		fmt.Printf("%v\n", v)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d nodes contacted, %d responses\n", stats.NumAddrsTried, stats.NumResponses)
+	return nil
+}
+
+// load the signing key of mutable items, generating one when the file does not exist.
+func loadKey(path string) (ed25519.PrivateKey, error) {
+	encoded, err := os.ReadFile(path)
+	if errorsx.Is(err, fs.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = os.WriteFile(path, []byte(hex.EncodeToString(key.Seed())+"\n"), 0600); err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(os.Stderr, "generated key %s public key %x\n", path, key.Public())
+		return key, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	seed, err := hex.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, errorsx.Wrapf(err, "invalid key %s", path)
+	}
+
+	if len(seed) != ed25519.SeedSize {
+		return nil, errorsx.Errorf("invalid key %s, expected a %d byte seed", path, ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
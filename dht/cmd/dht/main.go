@@ -0,0 +1,150 @@
+// Command dht operates on the mainline DHT, and runs standalone DHT nodes.
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/alexflint/go-arg"
+
+	"github.com/james-lawrence/torrent/dht"
+	"github.com/james-lawrence/torrent/dht/int160"
+	peer_store "github.com/james-lawrence/torrent/dht/peer-store"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+)
+
+type node struct {
+	Network   string   `arg:"--network" default:"udp" help:"network to listen on, udp, udp4 or udp6"`
+	Listen    string   `arg:"--listen" default:":0" help:"address to listen on"`
+	Bootstrap []string `arg:"-b,--bootstrap,separate" help:"host:port of the nodes to bootstrap from, defaults to the global bootstrap nodes"`
+	Debug     bool     `arg:"--debug" help:"log the queries of the node"`
+}
+
+func main() {
+	var args struct {
+		Ping     *cmdPing     `arg:"subcommand:ping" help:"ping nodes"`
+		GetPeers *cmdGetPeers `arg:"subcommand:get-peers" help:"retrieve the peers of an info hash, optionally announcing a local peer"`
+		Put      *cmdPut      `arg:"subcommand:put" help:"store an immutable or mutable item (BEP 44)"`
+		Get      *cmdGet      `arg:"subcommand:get" help:"retrieve an item (BEP 44)"`
+		Crawl    *cmdCrawl    `arg:"subcommand:crawl" help:"enumerate the infohashes sampled by the nodes of the DHT (BEP 51)"`
+		Serve    *cmdServe    `arg:"subcommand:serve" help:"run a long lived node"`
+	}
+
+	p := arg.MustParse(&args)
+	if p.Subcommand() == nil {
+		p.Fail("missing subcommand")
+	}
+
+	ctx, done := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer done()
+
+	var err error
+	switch {
+	case args.Ping != nil:
+		err = args.Ping.Run(ctx)
+	case args.GetPeers != nil:
+		err = args.GetPeers.Run(ctx)
+	case args.Put != nil:
+		err = args.Put.Run(ctx)
+	case args.Get != nil:
+		err = args.Get.Run(ctx)
+	case args.Crawl != nil:
+		err = args.Crawl.Run(ctx)
+	case args.Serve != nil:
+		err = args.Serve.Run(ctx)
+	}
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// muxer answering BEP 44 queries along with the standard BEP 5 queries.
+func muxer() dht.Muxer {
+	return dht.DefaultMuxer().
+		Method("put", dht.Bep44Put{}).
+		Method("get", dht.Bep44Get{})
+}
+
+// start a server listening on the configured address.
+func (t node) server(options ...func(*dht.ServerConfig)) (s *dht.Server, err error) {
+	conn, err := net.ListenPacket(t.Network, t.Listen)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := dht.NewDefaultServerConfig()
+	cfg.Conn = conn
+	cfg.PeerStore = &peer_store.InMemory{}
+	cfg.Muxer = muxer()
+	cfg.Logger = log.New(io.Discard, "", 0)
+
+	if t.Debug {
+		cfg.Logger = log.New(os.Stderr, "[dht] ", log.Flags())
+	}
+
+	if len(t.Bootstrap) > 0 {
+		for _, hostport := range t.Bootstrap {
+			if _, _, err = net.SplitHostPort(hostport); err != nil {
+				return nil, errorsx.Wrapf(err, "invalid bootstrap node %q", hostport)
+			}
+		}
+
+		cfg.StartingNodes = func() ([]dht.Addr, error) {
+			return dht.ResolveHostPorts(t.Bootstrap)
+		}
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	if s, err = dht.NewServer(cfg); err != nil {
+		return nil, errorsx.Compact(err, conn.Close())
+	}
+
+	return s, nil
+}
+
+// operations traversing the DHT.
+type query struct {
+	node
+	Timeout time.Duration `arg:"--timeout" default:"1m" help:"maximum time to spend on the operation"`
+}
+
+func (t query) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, t.Timeout)
+}
+
+// start a server and populate its routing table.
+func (t node) bootstrapped(ctx context.Context) (*dht.Server, error) {
+	s, err := t.server()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.Bootstrap(ctx); err != nil {
+		s.Close()
+		return nil, errorsx.Wrap(err, "unable to bootstrap")
+	}
+
+	return s, nil
+}
+
+func decodeID(encoded string) (id int160.T, err error) {
+	if len(encoded) != 40 {
+		return id, errorsx.Errorf("invalid id %q, expected 40 hex characters", encoded)
+	}
+
+	if id, err = int160.FromHexEncodedString(encoded); err != nil {
+		return id, errorsx.Wrapf(err, "invalid id %q", encoded)
+	}
+
+	return id, nil
+}
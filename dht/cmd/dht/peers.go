@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+
+	"github.com/james-lawrence/torrent/dht"
+)
+
+type cmdGetPeers struct {
+	query
+	Announce int    `arg:"--announce" help:"announce a peer listening on this port once the traversal completes"`
+	Implied  bool   `arg:"--implied-port" help:"announce the source port of the queries instead"`
+	Scrape   bool   `arg:"--scrape" help:"request BEP 33 scrape bloom filters"`
+	InfoHash string `arg:"positional,required" help:"hex encoded info hash"`
+}
+
+func (t cmdGetPeers) Run(ctx context.Context) error {
+	ctx, done := t.context(ctx)
+	defer done()
+
+	id, err := decodeID(t.InfoHash)
+	if err != nil {
+		return err
+	}
+
+	s, err := t.bootstrapped(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	options := []dht.AnnounceOpt{dht.AnnouncePeer(t.Implied, t.Announce)}
+	if t.Scrape {
+		options = append(options, dht.Scrape())
+	}
+
+	a, err := s.AnnounceTraversal(ctx, id, options...)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	seen := make(map[netip.AddrPort]struct{})
+	for v := range a.Peers {
+		for _, p := range v.Peers {
+			if _, ok := seen[p.AddrPort]; ok {
+				continue
+			}
+
+			seen[p.AddrPort] = struct{}{}
+			fmt.Println(p.AddrPort)
+		}
+
+		if v.BFpe != nil && v.BFsd != nil {
+			fmt.Fprintf(os.Stderr, "%s: scrape seeders ~%.0f peers ~%.0f\n", v.NodeInfo.Addr.AddrPort, v.BFsd.EstimateCount(), v.BFpe.EstimateCount())
+		}
+	}
+
+	stats := a.TraversalStats()
+	fmt.Fprintf(os.Stderr, "%d unique peers, %d nodes contacted, %d responses\n", len(seen), stats.NumAddrsTried, stats.NumResponses)
+
+	return nil
+}
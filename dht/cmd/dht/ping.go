@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/james-lawrence/torrent/dht"
+	"github.com/james-lawrence/torrent/dht/krpc"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+)
+
+type cmdPing struct {
+	node
+	Timeout time.Duration `arg:"--timeout" default:"3s" help:"maximum time to wait for each response"`
+	Nodes   []string      `arg:"positional,required" help:"host:port of the nodes to ping"`
+}
+
+func (t cmdPing) Run(ctx context.Context) error {
+	s, err := t.server()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+
+	for _, hostport := range t.Nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			started := time.Now()
+			id, err := t.ping(ctx, s, hostport)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				fmt.Printf("%s: %v\n", hostport, err)
+				return
+			}
+
+			fmt.Printf("%s: %x %v\n", hostport, id, time.Since(started).Round(time.Millisecond))
+		}()
+	}
+
+	wg.Wait()
+
+	if failed > 0 {
+		return errorsx.Errorf("%d/%d nodes did not respond", failed, len(t.Nodes))
+	}
+
+	return nil
+}
+
+func (t cmdPing) ping(ctx context.Context, s *dht.Server, hostport string) (id krpc.ID, err error) {
+	addr, err := net.ResolveUDPAddr(t.Network, hostport)
+	if err != nil {
+		return id, err
+	}
+
+	res := dht.PingDuration(ctx, t.Timeout, s, dht.NewAddr(addr), s.ID())
+	if err = res.ToError(); err != nil {
+		return id, err
+	}
+
+	if res.Reply.R == nil {
+		return id, errorsx.Errorf("missing response")
+	}
+
+	return res.Reply.R.ID, nil
+}
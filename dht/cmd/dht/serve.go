@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/james-lawrence/torrent/bep0051"
+	"github.com/james-lawrence/torrent/dht"
+	"github.com/james-lawrence/torrent/dht/bep44"
+	"github.com/james-lawrence/torrent/dht/exts/getput"
+	peer_store "github.com/james-lawrence/torrent/dht/peer-store"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/userx"
+)
+
+type cmdServe struct {
+	node
+	Nodes     string        `arg:"--nodes" help:"file the routing table is persisted to, defaults to the user cache directory"`
+	Peers     string        `arg:"--peers" help:"directory announced peers are persisted to, defaults to the user cache directory"`
+	PeerTTL   time.Duration `arg:"--peer-ttl" default:"1h" help:"duration announced peers are retained"`
+	Items     string        `arg:"--items" help:"directory BEP 44 items are persisted to, defaults to the user cache directory"`
+	ItemMax   int64         `arg:"--item-max" default:"33554432" help:"maximum bytes of BEP 44 items stored, the least recently used are evicted"`
+	Republish time.Duration `arg:"--republish" default:"1h" help:"interval items put by the node are republished"`
+	Sample    time.Duration `arg:"--sample" default:"1h" help:"interval the BEP 51 sample of the announced infohashes is rotated"`
+	Persist   time.Duration `arg:"--persist" default:"5m" help:"interval between writes of the routing table"`
+	Unlimited bool          `arg:"--unlimited" help:"disable the per source limits of incoming queries, see dht.DefaultQueryLimits"`
+	HTTP      string        `arg:"--http" default:"127.0.0.1:8080" help:"address to serve the status of the node on, disabled when empty"`
+}
+
+func (t cmdServe) Run(ctx context.Context) error {
+	nodes := t.Nodes
+	if nodes == "" {
+		nodes = filepath.Join(userx.DefaultCacheDirectory(), "dht", "nodes")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(nodes), 0700); err != nil {
+		return err
+	}
+
+	peers := t.Peers
+	if peers == "" {
+		peers = filepath.Join(userx.DefaultCacheDirectory(), "dht", "peers")
+	}
+
+	ps, err := peer_store.NewDisk(peers, peer_store.DiskOptionTTL(t.PeerTTL))
+	if err != nil {
+		return err
+	}
+	defer ps.Close()
+
+	items := t.Items
+	if items == "" {
+		items = filepath.Join(userx.DefaultCacheDirectory(), "dht", "items")
+	}
+
+	store, err := bep44.NewDisk(items, bep44.DiskOptionMaxBytes(t.ItemMax))
+	if err != nil {
+		return err
+	}
+
+	s, err := t.server(func(c *dht.ServerConfig) {
+		c.PeerStore = ps
+		c.Store = store
+		c.Muxer = c.Muxer.Method(bep0051.Query, bep0051.NewEndpoint(bep0051.NewSampler(t.Sample, bep0051.SourcePeerStore(ps))))
+		if !t.Unlimited {
+			c.QueryLimits = dht.DefaultQueryLimits()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if ns, err := dht.ReadNodesFromFile(nodes); errorsx.Ignore(err, fs.ErrNotExist) != nil {
+		log.Println("unable to read nodes", nodes, err)
+	} else if err = s.AddNode(ns...); err != nil {
+		log.Println("unable to add nodes", nodes, err)
+	} else if len(ns) > 0 {
+		log.Println("loaded", len(ns), "nodes from", nodes)
+	}
+
+	log.Printf("listening on %s node id %x\n", s.Addr(), s.ID())
+
+	go s.TableMaintainer()
+	go getput.Republish(ctx, s, store, t.Republish)
+
+	if t.HTTP != "" {
+		l, err := net.Listen("tcp", t.HTTP)
+		if err != nil {
+			return err
+		}
+
+		srv := &http.Server{Handler: status(s)}
+		defer srv.Close()
+
+		go func() {
+			if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+				log.Println("status server failed", err)
+			}
+		}()
+
+		log.Println("status available at", "http://"+l.Addr().String())
+	}
+
+	ticker := time.NewTicker(t.Persist)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			persist(s, nodes)
+		case <-ctx.Done():
+			persist(s, nodes)
+			return nil
+		}
+	}
+}
+
+// write the routing table so the node is able to rejoin without the bootstrap nodes.
+func persist(s *dht.Server, path string) {
+	ns := s.Nodes()
+	if len(ns) == 0 {
+		return
+	}
+
+	if err := dht.WriteNodesToFile(ns, path); err != nil {
+		log.Println("unable to persist nodes", path, err)
+	}
+}
+
+// status of the node, / as text via WriteStatus and /stats as json.
+func status(s *dht.Server) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		s.WriteStatus(w)
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(s.Stats()); err != nil {
+			log.Println("unable to write stats", err)
+		}
+	})
+
+	return mux
+}
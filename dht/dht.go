@@ -56,6 +56,9 @@ type ServerConfig struct {
 
 	// Hook received queries. Return false if you don't want to propagate to the default handlers.
 	OnQuery func(query *krpc.Msg, source net.Addr) (propagate bool)
+	// Handles the received queries, defaults to DefaultMuxer. see Server.ServeMux
+	// for replacing it once the server is running.
+	Muxer Muxer
 	// Called when a peer successfully announces to us.
 	OnAnnouncePeer func(infoHash int160.T, ip net.IP, port int, portOk bool)
 	// How long to wait before resending queries that haven't received a response. Defaults to 2s.
@@ -77,6 +80,8 @@ type ServerConfig struct {
 	DefaultWant []krpc.Want
 
 	SendLimiter *rate.Limiter
+	// Limits incoming queries per source, see DefaultQueryLimits. Disabled when nil.
+	QueryLimits *QueryLimits
 }
 
 // ServerStats instance is returned by Server.Stats() and stores Server metrics
@@ -93,6 +98,10 @@ type ServerStats struct {
 	// Nodes that have been blocked.
 	BadNodes                 uint
 	OutboundQueriesAttempted int64
+	// Packets ignored from sources dropped for repeatedly sending malformed messages.
+	DroppedPackets int64
+	// Queries ignored for exceeding the query limits.
+	LimitedQueries int64
 }
 
 type Peer = krpc.NodeAddr
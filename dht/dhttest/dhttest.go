@@ -0,0 +1,38 @@
+// Package dhttest contains functions for testing dht-related behaviour.
+package dhttest
+
+import (
+	"io"
+	"log"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/dht"
+	"github.com/james-lawrence/torrent/dht/krpc"
+)
+
+// NewServer starts a dht server on the loopback interface that answers queries
+// with the muxer. the server has no starting nodes and is closed with the test.
+func NewServer(t testing.TB, m dht.Muxer) *dht.Server {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	cfg := dht.NewDefaultServerConfig()
+	cfg.Conn = conn
+	cfg.StartingNodes = func() ([]dht.Addr, error) { return nil, nil }
+	cfg.Logger = log.New(io.Discard, "", 0)
+	cfg.Muxer = m
+
+	s, err := dht.NewServer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// NodeInfo of the server, used to add it to the routing table of other servers.
+func NodeInfo(s *dht.Server) krpc.NodeInfo {
+	return krpc.NodeInfo{ID: s.ID(), Addr: krpc.NewNodeAddrFromAddrPort(s.Addr().(*net.UDPAddr).AddrPort())}
+}
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"math"
 	"sync"
+	"time"
 
 	"github.com/james-lawrence/torrent/bencode"
 
@@ -170,3 +171,22 @@ notDone:
 	stats = op.Stats()
 	return
 }
+
+// Republish puts the items originated by the local node again at the interval, nodes
+// discard items that are not refreshed. blocks until the context is done.
+func Republish(ctx context.Context, s *dht.Server, items bep44.Originator, interval time.Duration) {
+	for {
+		for i := range items.Originated() {
+			put := i.ToPut()
+			if _, err := Put(ctx, put.Target(), s, put.Salt, func(int64) bep44.Put { return put }); err != nil {
+				slog.Warn(fmt.Sprintf("error republishing %x: %v", put.Target(), err))
+			}
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
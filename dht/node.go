@@ -15,6 +15,7 @@ type nodeKey struct {
 type node struct {
 	nodeKey
 
+	added           time.Time // When the node was added to the routing table
 	lastGotQuery    time.Time // From the remote node
 	lastGotResponse time.Time // From the remote node
 
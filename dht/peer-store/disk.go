@@ -0,0 +1,319 @@
+package peer_store
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"iter"
+	"log"
+	"maps"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/james-lawrence/torrent/dht/krpc"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+)
+
+const (
+	defaultDiskTTL            = time.Hour
+	defaultDiskMaxPerInfoHash = 200
+	defaultDiskMaxPeers       = 1 << 18
+	// infohash, ipv6 (ipv4 addresses are mapped), port and the unix time in nanoseconds.
+	diskRecordSize = 20 + 16 + 2 + 8
+	// minimum number of records in the journal before it is compacted.
+	diskCompactMinimum = 4096
+)
+
+// DiskOption configures a Disk peer store.
+type DiskOption func(*Disk)
+
+// DiskOptionTTL sets how long a peer is retained after its last announce, defaults to 1 hour.
+func DiskOptionTTL(d time.Duration) DiskOption {
+	return func(t *Disk) {
+		t.ttl = d
+	}
+}
+
+// DiskOptionMaxPerInfoHash caps the peers retained for a single infohash, the least
+// recently announced peer is evicted to make room. defaults to 200.
+func DiskOptionMaxPerInfoHash(n int) DiskOption {
+	return func(t *Disk) {
+		t.maxPerInfoHash = max(n, 1)
+	}
+}
+
+// DiskOptionMaxPeers caps the peers retained across all infohashes, the least recently
+// announced peer is evicted to make room. this bounds both memory and disk usage, each
+// peer occupies 46 bytes within the journal which is compacted once it holds twice the
+// retained peers. defaults to 262144.
+func DiskOptionMaxPeers(n int) DiskOption {
+	return func(t *Disk) {
+		t.maxPeers = max(n, 1)
+	}
+}
+
+// NewDisk opens a peer store persisted to a journal within the directory, announces
+// recorded by a previous process are restored as long as they have not expired.
+func NewDisk(dir string, options ...DiskOption) (_ *Disk, err error) {
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, errorsx.Wrapf(err, "unable to ensure peer store directory %s", dir)
+	}
+
+	d := &Disk{
+		dir:            dir,
+		ttl:            defaultDiskTTL,
+		maxPerInfoHash: defaultDiskMaxPerInfoHash,
+		maxPeers:       defaultDiskMaxPeers,
+		now:            time.Now,
+		index:          make(map[InfoHash]map[netip.Addr]*list.Element),
+		order:          list.New(),
+	}
+
+	for _, opt := range options {
+		opt(d)
+	}
+
+	if err = d.load(); err != nil {
+		return nil, err
+	}
+
+	// rewrite the journal to drop expired peers and any partially written record.
+	if err = d.compact(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Disk is a peer store that persists announces to disk, so they survive restarts.
+// peers expire after the ttl and are evicted when the store is at capacity.
+type Disk struct {
+	dir            string
+	ttl            time.Duration
+	maxPerInfoHash int
+	maxPeers       int
+	now            func() time.Time
+
+	mu      sync.Mutex
+	index   map[InfoHash]map[netip.Addr]*list.Element
+	order   *list.List // diskentry ordered by the time of the announce, oldest first.
+	journal *os.File
+	records int // number of records within the journal.
+}
+
+var _ interface {
+	Interface
+	Enumerable
+} = (*Disk)(nil)
+
+type diskentry struct {
+	InfoHash
+	NodeAndTime
+}
+
+func (t *Disk) path() string {
+	return filepath.Join(t.dir, "peers")
+}
+
+func (t *Disk) GetPeers(ih InfoHash) (ret []krpc.NodeAddr) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire(t.now())
+	for _, el := range t.index[ih] {
+		ret = append(ret, el.Value.(diskentry).NodeAddr)
+	}
+
+	return ret
+}
+
+func (t *Disk) InfoHashes() iter.Seq[InfoHash] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire(t.now())
+	return slices.Values(slices.Collect(maps.Keys(t.index)))
+}
+
+func (t *Disk) AddPeer(ih InfoHash, na krpc.NodeAddr) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.expire(now)
+	e := diskentry{InfoHash: ih, NodeAndTime: NodeAndTime{NodeAddr: na, Time: now}}
+	t.insert(e)
+
+	if t.journal == nil {
+		return
+	}
+
+	if _, err := t.journal.Write(e.encode(make([]byte, 0, diskRecordSize))); err != nil {
+		log.Println("unable to persist peer", t.path(), err)
+		return
+	}
+	t.records++
+
+	if t.records < max(diskCompactMinimum, 2*t.order.Len()) {
+		return
+	}
+
+	if err := t.compact(); err != nil {
+		log.Println("unable to compact peer store", t.path(), err)
+	}
+}
+
+// Close flushes the journal to disk, announces received afterwards are only retained in memory.
+func (t *Disk) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.journal == nil {
+		return nil
+	}
+
+	err := errorsx.Compact(t.journal.Sync(), t.journal.Close())
+	t.journal = nil
+	return err
+}
+
+func (t *Disk) insert(e diskentry) {
+	addr := e.Addr().Unmap()
+	peers := t.index[e.InfoHash]
+	if peers == nil {
+		peers = make(map[netip.Addr]*list.Element)
+	}
+
+	if el, ok := peers[addr]; ok {
+		el.Value = e
+		t.order.MoveToBack(el)
+		return
+	}
+
+	if len(peers) >= t.maxPerInfoHash {
+		var oldest *list.Element
+		for _, el := range peers {
+			if oldest == nil || el.Value.(diskentry).Time.Before(oldest.Value.(diskentry).Time) {
+				oldest = el
+			}
+		}
+		t.remove(oldest)
+	}
+
+	peers[addr] = t.order.PushBack(e)
+	t.index[e.InfoHash] = peers
+
+	for t.order.Len() > t.maxPeers {
+		t.remove(t.order.Front())
+	}
+}
+
+func (t *Disk) remove(el *list.Element) {
+	e := t.order.Remove(el).(diskentry)
+	peers := t.index[e.InfoHash]
+	delete(peers, e.Addr().Unmap())
+	if len(peers) == 0 {
+		delete(t.index, e.InfoHash)
+	}
+}
+
+func (t *Disk) expire(now time.Time) {
+	for el := t.order.Front(); el != nil && now.Sub(el.Value.(diskentry).Time) > t.ttl; el = t.order.Front() {
+		t.remove(el)
+	}
+}
+
+// replay the journal, records are appended in the order the announces were received.
+func (t *Disk) load() error {
+	src, err := os.Open(t.path())
+	if errorsx.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return errorsx.Wrapf(err, "unable to read peer store %s", t.path())
+	}
+	defer src.Close()
+
+	var (
+		now    = t.now()
+		r      = bufio.NewReader(src)
+		record = make([]byte, diskRecordSize)
+	)
+
+	for {
+		if _, err = io.ReadFull(r, record); errorsx.Is(err, io.EOF, io.ErrUnexpectedEOF) {
+			// an unexpected eof is a record truncated by a crash, it is safe to ignore.
+			return nil
+		} else if err != nil {
+			return errorsx.Wrapf(err, "unable to read peer store %s", t.path())
+		}
+
+		e := decodediskentry(record)
+		if now.Sub(e.Time) > t.ttl {
+			continue
+		}
+
+		t.insert(e)
+	}
+}
+
+// rewrite the journal with only the retained peers.
+func (t *Disk) compact() (err error) {
+	dst, err := os.CreateTemp(t.dir, "peers.*.tmp")
+	if err != nil {
+		return errorsx.Wrap(err, "unable to create peer store journal")
+	}
+	defer os.Remove(dst.Name())
+	defer dst.Close()
+
+	w := bufio.NewWriter(dst)
+	buf := make([]byte, 0, diskRecordSize)
+	for el := t.order.Front(); el != nil; el = el.Next() {
+		if _, err = w.Write(el.Value.(diskentry).encode(buf)); err != nil {
+			return errorsx.Wrap(err, "unable to write peer store journal")
+		}
+	}
+
+	if err = errorsx.Compact(w.Flush(), dst.Sync()); err != nil {
+		return errorsx.Wrap(err, "unable to write peer store journal")
+	}
+
+	if err = os.Rename(dst.Name(), t.path()); err != nil {
+		return errorsx.Wrap(err, "unable to replace peer store journal")
+	}
+
+	journal, err := os.OpenFile(t.path(), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errorsx.Wrap(err, "unable to open peer store journal")
+	}
+
+	if t.journal != nil {
+		t.journal.Close()
+	}
+
+	t.journal = journal
+	t.records = t.order.Len()
+
+	return nil
+}
+
+func (t diskentry) encode(buf []byte) []byte {
+	ip := t.Addr().As16()
+	buf = append(buf[:0], t.InfoHash[:]...)
+	buf = append(buf, ip[:]...)
+	buf = binary.BigEndian.AppendUint16(buf, t.Port())
+	return binary.BigEndian.AppendUint64(buf, uint64(t.Time.UnixNano()))
+}
+
+func decodediskentry(record []byte) (e diskentry) {
+	copy(e.InfoHash[:], record[:20])
+	addr := netip.AddrFrom16([16]byte(record[20:36])).Unmap()
+	e.NodeAddr = krpc.NewNodeAddrFromAddrPort(netip.AddrPortFrom(addr, binary.BigEndian.Uint16(record[36:38])))
+	e.Time = time.Unix(0, int64(binary.BigEndian.Uint64(record[38:46])))
+	return e
+}
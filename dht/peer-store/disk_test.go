@@ -0,0 +1,141 @@
+package peer_store
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/dht/krpc"
+)
+
+func peer(s string) krpc.NodeAddr {
+	return krpc.NewNodeAddrFromAddrPort(netip.MustParseAddrPort(s))
+}
+
+// clock that only advances when told to.
+type clock struct {
+	time.Time
+}
+
+func (t *clock) now() time.Time {
+	return t.Time
+}
+
+func openDisk(t *testing.T, dir string, c *clock, options ...DiskOption) *Disk {
+	d, err := NewDisk(dir, append(options, func(d *Disk) { d.now = c.now })...)
+	require.NoError(t, err)
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func TestDisk(t *testing.T) {
+	ih1 := InfoHash{1}
+	ih2 := InfoHash{2}
+
+	t.Run("announces survive restarts", func(t *testing.T) {
+		dir := t.TempDir()
+		c := &clock{Time: time.Now()}
+
+		d := openDisk(t, dir, c)
+		require.Empty(t, d.GetPeers(ih1))
+		d.AddPeer(ih1, peer("127.0.0.1:7000"))
+		d.AddPeer(ih1, peer("[fd00::1]:7000"))
+		d.AddPeer(ih2, peer("127.0.0.2:7000"))
+		// peers are unique by ip, announcing again replaces the port.
+		d.AddPeer(ih1, peer("127.0.0.1:7001"))
+		require.NoError(t, d.Close())
+
+		d = openDisk(t, dir, c)
+		require.ElementsMatch(t, []krpc.NodeAddr{peer("127.0.0.1:7001"), peer("[fd00::1]:7000")}, d.GetPeers(ih1))
+		require.ElementsMatch(t, []krpc.NodeAddr{peer("127.0.0.2:7000")}, d.GetPeers(ih2))
+		require.ElementsMatch(t, []InfoHash{ih1, ih2}, slices.Collect(d.InfoHashes()))
+	})
+
+	t.Run("peers expire", func(t *testing.T) {
+		dir := t.TempDir()
+		c := &clock{Time: time.Now()}
+
+		d := openDisk(t, dir, c, DiskOptionTTL(time.Minute))
+		d.AddPeer(ih1, peer("127.0.0.1:7000"))
+		c.Time = c.Add(30 * time.Second)
+		d.AddPeer(ih1, peer("127.0.0.2:7000"))
+		c.Time = c.Add(45 * time.Second)
+		require.ElementsMatch(t, []krpc.NodeAddr{peer("127.0.0.2:7000")}, d.GetPeers(ih1))
+		require.NoError(t, d.Close())
+
+		c.Time = c.Add(time.Minute)
+		d = openDisk(t, dir, c, DiskOptionTTL(time.Minute))
+		require.Empty(t, d.GetPeers(ih1))
+	})
+
+	t.Run("least recently announced peers are evicted", func(t *testing.T) {
+		dir := t.TempDir()
+		c := &clock{Time: time.Now()}
+
+		d := openDisk(t, dir, c, DiskOptionMaxPerInfoHash(2), DiskOptionMaxPeers(3))
+		announce := func(ih InfoHash, s string) {
+			c.Time = c.Add(time.Second)
+			d.AddPeer(ih, peer(s))
+		}
+
+		announce(ih1, "127.0.0.1:7000")
+		announce(ih1, "127.0.0.2:7000")
+		announce(ih1, "127.0.0.1:7000")
+		announce(ih1, "127.0.0.3:7000")
+		require.ElementsMatch(t, []krpc.NodeAddr{peer("127.0.0.1:7000"), peer("127.0.0.3:7000")}, d.GetPeers(ih1))
+
+		announce(ih2, "127.0.0.4:7000")
+		announce(ih2, "127.0.0.5:7000")
+		require.ElementsMatch(t, []krpc.NodeAddr{peer("127.0.0.3:7000")}, d.GetPeers(ih1))
+		require.NoError(t, d.Close())
+
+		d = openDisk(t, dir, c, DiskOptionMaxPerInfoHash(2), DiskOptionMaxPeers(3))
+		require.ElementsMatch(t, []krpc.NodeAddr{peer("127.0.0.3:7000")}, d.GetPeers(ih1))
+		require.ElementsMatch(t, []krpc.NodeAddr{peer("127.0.0.4:7000"), peer("127.0.0.5:7000")}, d.GetPeers(ih2))
+	})
+
+	t.Run("journal is compacted", func(t *testing.T) {
+		dir := t.TempDir()
+		c := &clock{Time: time.Now()}
+
+		d := openDisk(t, dir, c, DiskOptionMaxPeers(10))
+		for i := range 2 * diskCompactMinimum {
+			d.AddPeer(ih1, peer(fmt.Sprintf("127.0.0.%d:7000", i%10)))
+		}
+		require.NoError(t, d.Close())
+
+		info, err := os.Stat(filepath.Join(dir, "peers"))
+		require.NoError(t, err)
+		require.Less(t, info.Size(), int64(diskCompactMinimum*diskRecordSize))
+		require.Len(t, openDisk(t, dir, c, DiskOptionMaxPeers(10)).GetPeers(ih1), 10)
+	})
+
+	t.Run("truncated records are ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		c := &clock{Time: time.Now()}
+
+		d := openDisk(t, dir, c)
+		d.AddPeer(ih1, peer("127.0.0.1:7000"))
+		require.NoError(t, d.Close())
+
+		journal, err := os.OpenFile(filepath.Join(dir, "peers"), os.O_WRONLY|os.O_APPEND, 0600)
+		require.NoError(t, err)
+		_, err = journal.Write(make([]byte, diskRecordSize/2))
+		require.NoError(t, err)
+		require.NoError(t, journal.Close())
+
+		d = openDisk(t, dir, c)
+		require.ElementsMatch(t, []krpc.NodeAddr{peer("127.0.0.1:7000")}, d.GetPeers(ih1))
+		d.AddPeer(ih1, peer("127.0.0.2:7000"))
+		require.NoError(t, d.Close())
+
+		d = openDisk(t, dir, c)
+		require.ElementsMatch(t, []krpc.NodeAddr{peer("127.0.0.1:7000"), peer("127.0.0.2:7000")}, d.GetPeers(ih1))
+	})
+}
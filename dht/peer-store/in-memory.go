@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"iter"
+	"maps"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -30,22 +33,24 @@ type debugWriterInterface interface {
 
 var _ interface {
 	debugWriterInterface
+	Enumerable
 } = (*InMemory)(nil)
 
 func (me *InMemory) GetPeers(ih InfoHash) (ret []krpc.NodeAddr) {
 	me.mu.RLock()
 	defer me.mu.RUnlock()
-	for b := range me.index[ih] {
-		var r krpc.NodeAddr
-		err := r.UnmarshalBinary([]byte(b))
-		if err != nil {
-			panic(err)
-		}
-		ret = append(ret, r)
+	for _, v := range me.index[ih] {
+		ret = append(ret, v.NodeAddr)
 	}
 	return
 }
 
+func (me *InMemory) InfoHashes() iter.Seq[InfoHash] {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	return slices.Values(slices.Collect(maps.Keys(me.index)))
+}
+
 func (me *InMemory) AddPeer(ih InfoHash, na krpc.NodeAddr) {
 	key := string(na.IP())
 	me.mu.Lock()
@@ -0,0 +1,30 @@
+package peer_store
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/dht/krpc"
+)
+
+func TestInMemory(t *testing.T) {
+	var (
+		store InMemory
+		ih    InfoHash
+	)
+
+	require.Empty(t, store.GetPeers(ih))
+
+	v4 := krpc.NewNodeAddrFromAddrPort(netip.MustParseAddrPort("127.0.0.1:7000"))
+	v6 := krpc.NewNodeAddrFromAddrPort(netip.MustParseAddrPort("[fd00::1]:7000"))
+	store.AddPeer(ih, v4)
+	store.AddPeer(ih, v6)
+	require.ElementsMatch(t, []krpc.NodeAddr{v4, v6}, store.GetPeers(ih))
+
+	// peers are unique by ip, announcing again replaces the port.
+	replaced := krpc.NewNodeAddrFromAddrPort(netip.MustParseAddrPort("127.0.0.1:7001"))
+	store.AddPeer(ih, replaced)
+	require.ElementsMatch(t, []krpc.NodeAddr{replaced, v6}, store.GetPeers(ih))
+}
@@ -1,6 +1,8 @@
 package peer_store
 
 import (
+	"iter"
+
 	"github.com/james-lawrence/torrent/dht/krpc"
 	"github.com/james-lawrence/torrent/metainfo"
 )
@@ -11,3 +13,8 @@ type Interface interface {
 	AddPeer(InfoHash, krpc.NodeAddr)
 	GetPeers(InfoHash) []krpc.NodeAddr
 }
+
+// Enumerable peer stores are able to list the infohashes they hold peers for.
+type Enumerable interface {
+	InfoHashes() iter.Seq[InfoHash]
+}
@@ -0,0 +1,245 @@
+package dht
+
+import (
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// QueryLimit is a token bucket limiting the rate of incoming queries, the zero value
+// is unlimited.
+type QueryLimit struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+func (t QueryLimit) limiter() *rate.Limiter {
+	if t == (QueryLimit{}) {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+
+	return rate.NewLimiter(t.Rate, t.Burst)
+}
+
+// QueryLimits protects the server from abusive sources, e.g. crawlers, by limiting
+// the incoming queries of every source ip and of every network, a /24 for IPv4 and
+// a /48 for IPv6. queries exceeding the limits are silently ignored.
+type QueryLimits struct {
+	// limit of the queries from a single ip.
+	Source QueryLimit
+	// limit of the queries from a network.
+	Network QueryLimit
+	// limits of specific methods from a single ip, e.g. get_peers, in addition to the source limit.
+	Methods map[string]QueryLimit
+	// sources exceeding the limit of malformed messages are dropped for DropDuration,
+	// every packet they send is ignored.
+	Malformed    QueryLimit
+	DropDuration time.Duration
+	// maximum number of sources and networks tracked, the state of idle sources is
+	// discarded to make room.
+	MaxSources int
+}
+
+// DefaultQueryLimits are suitable for public facing nodes.
+func DefaultQueryLimits() *QueryLimits {
+	return &QueryLimits{
+		Source:  QueryLimit{Rate: 10, Burst: 50},
+		Network: QueryLimit{Rate: 50, Burst: 200},
+		Methods: map[string]QueryLimit{
+			"get_peers":         {Rate: 5, Burst: 25},
+			"announce_peer":     {Rate: 1, Burst: 10},
+			"get":               {Rate: 5, Burst: 25},
+			"put":               {Rate: 1, Burst: 5},
+			"sample_infohashes": {Rate: 1, Burst: 5},
+		},
+		Malformed:    QueryLimit{Rate: 0.1, Burst: 10},
+		DropDuration: 10 * time.Minute,
+		MaxSources:   1 << 16,
+	}
+}
+
+type querysource struct {
+	queries   *rate.Limiter
+	methods   map[string]*rate.Limiter
+	malformed *rate.Limiter
+	dropped   time.Time // packets are ignored until this time.
+}
+
+// idle sources have recovered every token and are indistinguishable from a new source.
+func (t *querysource) idle(now time.Time) bool {
+	if now.Before(t.dropped) || !full(t.queries, now) || !full(t.malformed, now) {
+		return false
+	}
+
+	for _, l := range t.methods {
+		if !full(l, now) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func full(l *rate.Limiter, now time.Time) bool {
+	return l.TokensAt(now) >= float64(l.Burst())
+}
+
+func newQueryLimiter(limits *QueryLimits) *queryLimiter {
+	return &queryLimiter{
+		QueryLimits: *limits,
+		sources:     make(map[netip.Addr]*querysource),
+		networks:    make(map[netip.Prefix]*rate.Limiter),
+	}
+}
+
+type queryLimiter struct {
+	QueryLimits
+
+	mu       sync.Mutex
+	sources  map[netip.Addr]*querysource
+	networks map[netip.Prefix]*rate.Limiter
+
+	dropped atomic.Int64 // packets ignored from dropped sources.
+	limited atomic.Int64 // queries ignored for exceeding the limits.
+}
+
+// the network of the ip, a /24 for IPv4 and a /48 for IPv6.
+func network(ip netip.Addr) netip.Prefix {
+	if ip.Is4() {
+		return netip.PrefixFrom(ip, 24).Masked()
+	}
+
+	return netip.PrefixFrom(ip, 48).Masked()
+}
+
+func (t *queryLimiter) source(ip netip.Addr, now time.Time) *querysource {
+	if qs, ok := t.sources[ip]; ok {
+		return qs
+	}
+
+	t.makeroom(now)
+
+	qs := &querysource{
+		queries:   t.Source.limiter(),
+		methods:   make(map[string]*rate.Limiter, len(t.Methods)),
+		malformed: t.Malformed.limiter(),
+	}
+
+	for method, limit := range t.Methods {
+		qs.methods[method] = limit.limiter()
+	}
+
+	t.sources[ip] = qs
+
+	return qs
+}
+
+// discard the state of idle sources and networks once the maximum is reached, when the
+// sources are active discard arbitrary sources until a quarter of the space is free.
+// dropped sources are never discarded, otherwise they'd be forgiven.
+func (t *queryLimiter) makeroom(now time.Time) {
+	if t.MaxSources <= 0 || len(t.sources)+len(t.networks) < t.MaxSources {
+		return
+	}
+
+	for ip, qs := range t.sources {
+		if qs.idle(now) {
+			delete(t.sources, ip)
+		}
+	}
+
+	for n, l := range t.networks {
+		if full(l, now) {
+			delete(t.networks, n)
+		}
+	}
+
+	for ip, qs := range t.sources {
+		if len(t.sources)+len(t.networks) < t.MaxSources*3/4 {
+			return
+		}
+
+		if now.Before(qs.dropped) {
+			continue
+		}
+
+		delete(t.sources, ip)
+	}
+
+	for n := range t.networks {
+		if len(t.sources)+len(t.networks) < t.MaxSources*3/4 {
+			return
+		}
+
+		delete(t.networks, n)
+	}
+}
+
+// reports if packets from the ip are to be ignored.
+func (t *queryLimiter) blocked(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	qs, ok := t.sources[ip]
+	if !ok || !now.Before(qs.dropped) {
+		return false
+	}
+
+	t.dropped.Add(1)
+	return true
+}
+
+// record a malformed message from the ip, dropping the source once it exceeds the limit.
+func (t *queryLimiter) malformed(ip netip.Addr) {
+	ip = ip.Unmap()
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if qs := t.source(ip, now); !qs.malformed.AllowN(now, 1) {
+		qs.dropped = now.Add(t.DropDuration)
+	}
+}
+
+// reports if the query from the ip is within the limits.
+func (t *queryLimiter) allow(ip netip.Addr, method string) bool {
+	ip = ip.Unmap()
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	qs := t.source(ip, now)
+
+	// the source and method limits are both consumed so abusive sources are not
+	// rewarded by spreading their queries across methods.
+	allowed := qs.queries.AllowN(now, 1)
+	if l, ok := qs.methods[method]; ok {
+		allowed = l.AllowN(now, 1) && allowed
+	}
+
+	// the network is only charged for queries within the limits of the source,
+	// otherwise an abusive source exhausts the limit of its neighbours.
+	if allowed {
+		n, ok := t.networks[network(ip)]
+		if !ok {
+			n = t.Network.limiter()
+			t.networks[network(ip)] = n
+		}
+
+		allowed = n.AllowN(now, 1)
+	}
+
+	if !allowed {
+		t.limited.Add(1)
+	}
+
+	return allowed
+}
@@ -0,0 +1,161 @@
+package dht
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/dht/krpc"
+)
+
+// limit that never recovers during a test.
+func burst(n int) QueryLimit {
+	return QueryLimit{Rate: rate.Every(time.Hour), Burst: n}
+}
+
+func allowed(l *queryLimiter, ip netip.Addr, method string, n int) (count int) {
+	for range n {
+		if l.allow(ip, method) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestQueryLimiter(t *testing.T) {
+	a := netip.MustParseAddr("10.0.0.1")
+	b := netip.MustParseAddr("10.0.0.2")
+	c := netip.MustParseAddr("10.0.1.1")
+
+	t.Run("sources are limited individually", func(t *testing.T) {
+		l := newQueryLimiter(&QueryLimits{Source: burst(3)})
+		require.Equal(t, 3, allowed(l, a, "ping", 5))
+		require.Equal(t, 3, allowed(l, b, "ping", 5))
+		require.EqualValues(t, 4, l.limited.Load())
+
+		// mapped addresses are the same source.
+		require.False(t, l.allow(netip.AddrFrom16(a.As16()), "ping"))
+	})
+
+	t.Run("networks are limited", func(t *testing.T) {
+		l := newQueryLimiter(&QueryLimits{Network: burst(3)})
+		require.Equal(t, 2, allowed(l, a, "ping", 2))
+		require.Equal(t, 1, allowed(l, b, "ping", 2))
+		require.Equal(t, 2, allowed(l, c, "ping", 2))
+
+		// sources exceeding their own limit do not exhaust the limit of their network.
+		l = newQueryLimiter(&QueryLimits{Source: burst(2), Network: burst(3)})
+		require.Equal(t, 2, allowed(l, a, "ping", 10))
+		require.Equal(t, 1, allowed(l, b, "ping", 2))
+
+		v6 := newQueryLimiter(&QueryLimits{Network: burst(1)})
+		require.True(t, v6.allow(netip.MustParseAddr("2001:db8:1::1"), "ping"))
+		require.False(t, v6.allow(netip.MustParseAddr("2001:db8:1:ffff::1"), "ping"))
+		require.True(t, v6.allow(netip.MustParseAddr("2001:db8:2::1"), "ping"))
+	})
+
+	t.Run("methods are limited individually", func(t *testing.T) {
+		l := newQueryLimiter(&QueryLimits{Methods: map[string]QueryLimit{"get_peers": burst(2), "put": burst(1)}})
+		require.Equal(t, 2, allowed(l, a, "get_peers", 3))
+		require.Equal(t, 1, allowed(l, a, "put", 3))
+		require.Equal(t, 3, allowed(l, a, "ping", 3))
+		require.Equal(t, 2, allowed(l, b, "get_peers", 3))
+	})
+
+	t.Run("sources sending malformed messages are dropped", func(t *testing.T) {
+		l := newQueryLimiter(&QueryLimits{Malformed: burst(2), DropDuration: time.Hour})
+		for range 2 {
+			l.malformed(a)
+			require.False(t, l.blocked(a))
+		}
+
+		l.malformed(a)
+		require.True(t, l.blocked(a))
+		require.False(t, l.blocked(b))
+		require.EqualValues(t, 1, l.dropped.Load())
+
+		l.sources[a].dropped = time.Now()
+		require.False(t, l.blocked(a))
+	})
+
+	t.Run("idle sources are discarded", func(t *testing.T) {
+		l := newQueryLimiter(&QueryLimits{Source: QueryLimit{Rate: rate.Inf, Burst: 1}, MaxSources: 4})
+		for i := range 16 {
+			require.True(t, l.allow(netip.AddrFrom4([4]byte{10, 0, byte(i), 1}), "ping"))
+			require.LessOrEqual(t, len(l.sources)+len(l.networks), 4)
+		}
+
+		// active sources are discarded when the sources are exhausted.
+		l = newQueryLimiter(&QueryLimits{Source: burst(1), MaxSources: 4})
+		for i := range 16 {
+			require.True(t, l.allow(netip.AddrFrom4([4]byte{10, 0, byte(i), 1}), "ping"))
+			require.LessOrEqual(t, len(l.sources)+len(l.networks), 5)
+		}
+
+		// dropped sources are never discarded.
+		l = newQueryLimiter(&QueryLimits{Source: burst(1), Malformed: burst(1), DropDuration: time.Hour, MaxSources: 4})
+		for range 2 {
+			l.malformed(a)
+		}
+		require.True(t, l.blocked(a))
+		for i := range 16 {
+			require.True(t, l.allow(netip.AddrFrom4([4]byte{10, 1, byte(i), 1}), "ping"))
+		}
+		require.True(t, l.blocked(a))
+	})
+}
+
+func TestServerQueryLimits(t *testing.T) {
+	cfg := NewDefaultServerConfig()
+	cfg.Conn = mustListen("127.0.0.1:0")
+	cfg.StartingNodes = func() ([]Addr, error) { return nil, nil }
+	cfg.QueryLimits = &QueryLimits{
+		Methods:      map[string]QueryLimit{"ping": burst(2)},
+		Malformed:    burst(1),
+		DropDuration: time.Hour,
+	}
+	s, err := NewServer(cfg)
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn := mustListen("127.0.0.1:0")
+	defer conn.Close()
+
+	// reports if the server replied to the query.
+	query := func(method string) bool {
+		query := bencode.MustMarshal(krpc.Msg{T: "t", Y: "q", Q: method, A: &krpc.MsgArgs{ID: krpc.RandomNodeInfo(4).ID}})
+		_, err := conn.WriteTo(query, s.Addr())
+		require.NoError(t, err)
+
+		buf := make([]byte, 1024)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(250*time.Millisecond)))
+		_, _, err = conn.ReadFrom(buf)
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+
+	require.True(t, query("ping"))
+	require.True(t, query("ping"))
+	require.False(t, query("ping"))
+	require.True(t, query("find_node"))
+	require.EqualValues(t, 1, s.Stats().LimitedQueries)
+	require.Zero(t, s.Stats().DroppedPackets)
+
+	for range 2 {
+		_, err = conn.WriteTo([]byte("garbage"), s.Addr())
+		require.NoError(t, err)
+	}
+
+	require.False(t, query("find_node"))
+	require.EqualValues(t, 1, s.Stats().LimitedQueries)
+	require.EqualValues(t, 1, s.Stats().DroppedPackets)
+}
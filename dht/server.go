@@ -57,6 +57,7 @@ type Server struct {
 	bootstrappingNow bool
 	mux              Muxer
 	store            *bep44.Wrapper
+	limiter          *queryLimiter // nil when incoming queries are not limited.
 }
 
 func (s *Server) numGoodNodes() (num int) {
@@ -144,6 +145,10 @@ func (s *Server) Stats() ServerStats {
 	ss.GoodNodes = s.numGoodNodes()
 	ss.Nodes = s.numNodes()
 	ss.OutstandingTransactions = s.transactions.NumActive()
+	if s.limiter != nil {
+		ss.DroppedPackets = s.limiter.dropped.Load()
+		ss.LimitedQueries = s.limiter.limited.Load()
+	}
 	return ss
 }
 
@@ -234,6 +239,12 @@ func NewServer(c *ServerConfig) (s *Server, err error) {
 	}
 	rand.Read(s.tokenServer.secret)
 	s.socket = c.Conn
+	if c.Muxer != nil {
+		s.mux = c.Muxer
+	}
+	if c.QueryLimits != nil {
+		s.limiter = newQueryLimiter(c.QueryLimits)
+	}
 	s.id = int160.FromByteArray(c.NodeId)
 	s.table.rootID = s.id
 	s.resendDelay = s.config.QueryResendDelay
@@ -280,11 +291,25 @@ func (s *Server) IPBlocklist() iplist.Ranger {
 	return s.ipBlockList
 }
 
+// record a malformed message from the source, see QueryLimits.
+func (s *Server) malformed(addr Addr) {
+	if s.limiter == nil {
+		return
+	}
+
+	s.limiter.malformed(addr.KRPC().Addr())
+}
+
 func (s *Server) processPacket(ctx context.Context, b []byte, addr Addr) {
 	// log.Printf("got packet %q", b)
+	if s.limiter != nil && s.limiter.blocked(addr.KRPC().Addr()) {
+		return
+	}
+
 	if len(b) < 2 || b[0] != 'd' {
 		// KRPC messages are bencoded dicts.
 		readNotKRPCDict.Add(1)
+		s.malformed(addr)
 		return
 	}
 	var d krpc.Msg
@@ -294,6 +319,7 @@ func (s *Server) processPacket(ctx context.Context, b []byte, addr Addr) {
 		expvars.Add("processed packets with trailing bytes", 1)
 	} else if err != nil {
 		readUnmarshalError.Add(1)
+		s.malformed(addr)
 		// log.Printf("%s: received bad krpc message from %s: %s: %+q", s, addr, err, b)
 		func() {
 			if se, ok := err.(*bencode.SyntaxError); ok {
@@ -320,6 +346,10 @@ func (s *Server) processPacket(ctx context.Context, b []byte, addr Addr) {
 	}
 
 	if d.Y == "q" {
+		if s.limiter != nil && !s.limiter.allow(addr.KRPC().Addr(), d.Q) {
+			return
+		}
+
 		s.logger().Printf("received query %q from %v", d.Q, addr)
 		s.handleQuery(ctx, addr, b, d)
 		return
@@ -514,7 +544,11 @@ func (s *Server) handleQuery(ctx context.Context, source Addr, raw []byte, m krp
 		}
 	}
 
-	if pattern, fn = s.mux.Handler(raw, &m); fn == nil {
+	s.mu.RLock()
+	mux := s.mux
+	s.mu.RUnlock()
+
+	if pattern, fn = mux.Handler(raw, &m); fn == nil {
 		log.Println("unable to locate a handler for", pattern)
 		return
 	}
@@ -625,7 +659,7 @@ func (s *Server) updateNode(addr Addr, id *krpc.ID, tryAdd bool, update func(*no
 		n = &node{nodeKey: nodeKey{
 			Id:   _id,
 			Addr: addr,
-		}}
+		}, added: time.Now()}
 	}
 
 	update(n)
@@ -853,7 +887,7 @@ func (s *Server) Ping(node net.Addr) QueryResult {
 
 // Put adds a new item to node. You need to call Get first for a write token.
 func (s *Server) Put(ctx context.Context, node Addr, i bep44.Put, token string, rl QueryRateLimiting) QueryResult {
-	if err := s.store.Put(i.ToItem()); err != nil {
+	if err := s.store.Originate(i.ToItem()); err != nil {
 		return QueryResult{
 			Err: err,
 		}
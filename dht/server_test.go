@@ -2,7 +2,11 @@ package dht
 
 import (
 	"log"
+	"net"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
 )
 
 // func TestPutGet(t *testing.T) {
@@ -102,3 +106,55 @@ func newServer(t *testing.T, l *log.Logger) *Server {
 
 	return s
 }
+
+func newBucketServer(t *testing.T, k int) *Server {
+	s, err := NewServer(&ServerConfig{
+		Conn:          mustListen("127.0.0.1:0"),
+		NoSecurity:    true,
+		BucketLimit:   k,
+		StartingNodes: func() ([]Addr, error) { return nil, nil },
+	})
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func bucketNode(s *Server, bucket int, port int) *node {
+	return &node{
+		nodeKey:         nodeKey{Id: s.table.randomIdForBucket(bucket), Addr: NewAddr(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})},
+		lastGotResponse: time.Now(),
+	}
+}
+
+func TestServerAddNode(t *testing.T) {
+	s := newBucketServer(t, 2)
+	n0, n1, n2 := bucketNode(s, 10, 1), bucketNode(s, 10, 2), bucketNode(s, 10, 3)
+	require.NoError(t, s.addNode(n0))
+	require.NoError(t, s.addNode(n1))
+
+	// full buckets of good nodes have no room.
+	require.EqualError(t, s.addNode(n2), "no room in bucket")
+	require.Equal(t, 2, s.table.buckets[10].Len())
+
+	// bad nodes are replaced.
+	n0.failedLastQuestionablePing = true
+	require.NoError(t, s.addNode(n2))
+	require.Equal(t, 2, s.table.buckets[10].Len())
+	require.Nil(t, s.table.getNode(n0.Addr, n0.Id))
+	require.NotNil(t, s.table.getNode(n2.Addr, n2.Id))
+}
+
+func TestServerShouldStopRefreshingBucket(t *testing.T) {
+	s := newBucketServer(t, 2)
+	n0, n1 := bucketNode(s, 10, 1), bucketNode(s, 10, 2)
+
+	require.NoError(t, s.addNode(n0))
+	require.False(t, s.shouldStopRefreshingBucket(10))
+
+	// full buckets of nodes that are not bad are not refreshed.
+	require.NoError(t, s.addNode(n1))
+	require.True(t, s.shouldStopRefreshingBucket(10))
+
+	n1.failedLastQuestionablePing = true
+	require.False(t, s.shouldStopRefreshingBucket(10))
+}
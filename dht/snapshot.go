@@ -0,0 +1,219 @@
+package dht
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/dht/krpc"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+)
+
+// number of restored nodes validated concurrently.
+const restoreConcurrency = 16
+
+// SnapshotNode is a node of the routing table persisted across restarts, see Server.Snapshot.
+type SnapshotNode struct {
+	krpc.NodeInfo
+	Added        time.Time // when the node was added to the routing table.
+	LastResponse time.Time // last response received from the node, zero when it never responded.
+}
+
+// Snapshot returns the nodes of the routing table that are not bad, see Server.Restore.
+func (s *Server) Snapshot() (ns []SnapshotNode) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	s.table.forNodes(func(n *node) bool {
+		if s.nodeIsBad(n) {
+			return true
+		}
+
+		ns = append(ns, SnapshotNode{
+			NodeInfo:     n.NodeInfo(),
+			Added:        n.added,
+			LastResponse: n.lastGotResponse,
+		})
+		return true
+	})
+
+	return ns
+}
+
+// Restore adds the nodes of a snapshot to the routing table, since the routing table is
+// traversed before the starting nodes are consulted this should be called before the
+// server is bootstrapped. returns the nodes added, most recently responsive first and
+// then oldest first, which are expected to be validated, see ValidateRestored.
+func (s *Server) Restore(ns []SnapshotNode) []SnapshotNode {
+	ns = slices.Clone(ns)
+	slices.SortStableFunc(ns, func(a, b SnapshotNode) int {
+		return cmp.Or(b.LastResponse.Compare(a.LastResponse), a.Added.Compare(b.Added))
+	})
+
+	added := make([]SnapshotNode, 0, len(ns))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sn := range ns {
+		err := s.updateNode(NewAddr(sn.Addr.UDP()), &sn.ID, true, func(n *node) {
+			n.added = sn.Added
+		})
+		if err != nil {
+			continue
+		}
+
+		added = append(added, sn)
+	}
+
+	return added
+}
+
+// ValidateRestored pings the restored nodes in order, removing the nodes that do not
+// respond before the context is done. it's safe to bootstrap the server concurrently.
+// returns the number of nodes that responded.
+func (s *Server) ValidateRestored(ctx context.Context, ns []SnapshotNode) int {
+	var (
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, restoreConcurrency)
+		responded = make([]bool, len(ns))
+	)
+
+validate:
+	for i, sn := range ns {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break validate
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			res := s.questionableNodePing(ctx, NewAddr(sn.Addr.UDP()), sn.ID)
+			responded[i] = res.Err == nil && res.Reply.R != nil && res.Reply.R.ID == sn.ID
+		}()
+	}
+	wg.Wait()
+
+	restored := 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sn := range ns {
+		if responded[i] {
+			restored++
+			continue
+		}
+
+		if n := s.table.getNode(NewAddr(sn.Addr.UDP()), int160.FromByteArray(sn.ID)); n != nil {
+			s.table.dropNode(n)
+		}
+	}
+
+	return restored
+}
+
+// the encoded form of a snapshot node.
+type snapshotrecord struct {
+	Node         []byte `bencode:"node"` // binary encoded krpc.NodeInfo
+	Added        int64  `bencode:"added"`
+	LastResponse int64  `bencode:"resp"`
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+func timeOrZero(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(ts, 0)
+}
+
+// WriteSnapshotToFile atomically replaces the file with the snapshot, see ReadSnapshotFromFile.
+func WriteSnapshotToFile(ns []SnapshotNode, path string) (err error) {
+	records := make([]snapshotrecord, 0, len(ns))
+	for _, n := range ns {
+		encoded, err := n.NodeInfo.MarshalBinary()
+		if err != nil {
+			return errorsx.Wrap(err, "unable to encode node")
+		}
+
+		records = append(records, snapshotrecord{
+			Node:         encoded,
+			Added:        unixOrZero(n.Added),
+			LastResponse: unixOrZero(n.LastResponse),
+		})
+	}
+
+	encoded, err := bencode.Marshal(records)
+	if err != nil {
+		return errorsx.Wrap(err, "unable to encode snapshot")
+	}
+
+	dst, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errorsx.Wrap(err, "unable to create snapshot")
+	}
+	defer os.Remove(dst.Name())
+	defer dst.Close()
+
+	if _, err = dst.Write(encoded); err != nil {
+		return errorsx.Wrap(err, "unable to write snapshot")
+	}
+
+	if err = dst.Sync(); err != nil {
+		return errorsx.Wrap(err, "unable to write snapshot")
+	}
+
+	if err = os.Rename(dst.Name(), path); err != nil {
+		return errorsx.Wrap(err, "unable to replace snapshot")
+	}
+
+	return nil
+}
+
+// ReadSnapshotFromFile reads a snapshot written by WriteSnapshotToFile.
+func ReadSnapshotFromFile(path string) (ns []SnapshotNode, err error) {
+	var records []snapshotrecord
+
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = bencode.Unmarshal(encoded, &records); err != nil {
+		return nil, errorsx.Wrapf(err, "unable to decode snapshot %s", path)
+	}
+
+	ns = make([]SnapshotNode, 0, len(records))
+	for _, r := range records {
+		var ni krpc.NodeInfo
+		if len(r.Node) <= len(ni.ID) {
+			return nil, errorsx.Errorf("unable to decode snapshot %s, truncated node", path)
+		}
+
+		if err = ni.UnmarshalBinary(r.Node); err != nil {
+			return nil, errorsx.Wrapf(err, "unable to decode snapshot %s", path)
+		}
+
+		ns = append(ns, SnapshotNode{
+			NodeInfo:     ni,
+			Added:        timeOrZero(r.Added),
+			LastResponse: timeOrZero(r.LastResponse),
+		})
+	}
+
+	return ns, nil
+}
@@ -0,0 +1,98 @@
+package dht
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/dht/krpc"
+)
+
+func TestSaveLoadSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes")
+	now := time.Unix(time.Now().Unix(), 0)
+	ns := []SnapshotNode{
+		{NodeInfo: krpc.RandomNodeInfo(4), Added: now.Add(-time.Hour), LastResponse: now},
+		{NodeInfo: krpc.RandomNodeInfo(16), Added: now},
+	}
+
+	require.NoError(t, WriteSnapshotToFile(ns, path))
+	restored, err := ReadSnapshotFromFile(path)
+	require.NoError(t, err)
+	require.Len(t, restored, len(ns))
+	for i, n := range ns {
+		require.Equal(t, n.ID, restored[i].ID)
+		require.Equal(t, n.Addr.String(), restored[i].Addr.String())
+		require.True(t, n.Added.Equal(restored[i].Added))
+		require.True(t, n.LastResponse.Equal(restored[i].LastResponse))
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	newSnapshotServer := func(starting StartingNodesGetter) *Server {
+		s, err := NewServer(&ServerConfig{
+			Conn:          mustListen("127.0.0.1:0"),
+			NoSecurity:    true,
+			BucketLimit:   8,
+			StartingNodes: starting,
+		})
+		require.NoError(t, err)
+		t.Cleanup(s.Close)
+		return s
+	}
+
+	info := func(s *Server) krpc.NodeInfo {
+		return krpc.NodeInfo{ID: s.ID(), Addr: krpc.NewNodeAddrFromAddrPort(s.Addr().(*net.UDPAddr).AddrPort())}
+	}
+
+	live := []*Server{newSnapshotServer(nil), newSnapshotServer(nil)}
+	origin := newSnapshotServer(nil)
+	for _, s := range live {
+		require.NoError(t, origin.AddNode(info(s)))
+		require.NoError(t, origin.Ping(s.Addr()).Err)
+	}
+
+	snapshot := origin.Snapshot()
+	require.Len(t, snapshot, len(live))
+	for _, n := range snapshot {
+		require.False(t, n.Added.IsZero())
+		require.False(t, n.LastResponse.IsZero())
+	}
+
+	// nodes that went away since the snapshot are removed.
+	dead := mustListen("127.0.0.1:0")
+	require.NoError(t, dead.Close())
+	snapshot = append(snapshot, SnapshotNode{
+		NodeInfo: krpc.NodeInfo{ID: krpc.RandomNodeInfo(4).ID, Addr: krpc.NewNodeAddrFromAddrPort(dead.LocalAddr().(*net.UDPAddr).AddrPort())},
+		Added:    time.Now().Add(-time.Hour),
+	})
+
+	consulted := false
+	restarted := newSnapshotServer(func() ([]Addr, error) {
+		consulted = true
+		return nil, nil
+	})
+
+	// the restored nodes are used instead of the starting nodes, without waiting for
+	// them to be validated.
+	restored := restarted.Restore(snapshot)
+	require.Len(t, restored, len(snapshot))
+	require.Equal(t, snapshot[len(snapshot)-1].ID, restored[len(restored)-1].ID, "unresponsive nodes are validated last")
+	nodes, err := restarted.TraversalStartingNodes()
+	require.NoError(t, err)
+	require.Len(t, nodes, len(snapshot))
+	require.False(t, consulted)
+
+	ctx, done := context.WithTimeout(t.Context(), time.Second)
+	defer done()
+	require.Equal(t, len(live), restarted.ValidateRestored(ctx, restored))
+	require.Equal(t, len(live), restarted.NumNodes())
+
+	for _, n := range restarted.Snapshot() {
+		require.False(t, n.LastResponse.IsZero())
+	}
+}
@@ -91,19 +91,29 @@ func (tbl *table) closestNodes(k int, target int160.T, filter func(*node) bool)
 	tbl.m.Lock()
 	defer tbl.m.Unlock()
 
-	for bi := func() int {
-		if target == tbl.rootID {
-			return len(tbl.buckets) - 1
-		} else {
-			return tbl.bucketIndex(target)
-		}
-	}(); bi >= 0 && len(ret) < k; bi-- {
-		for n := range tbl.buckets[bi].NodeIter() {
+	bi := len(tbl.buckets) - 1
+	if target != tbl.rootID {
+		bi = tbl.bucketIndex(target)
+	}
+
+	visit := func(i int) {
+		for n := range tbl.buckets[i].NodeIter() {
 			if filter(n) {
 				ret = append(ret, n)
 			}
 		}
 	}
+
+	// the bucket of the target holds the closest nodes, followed by the buckets nearer
+	// to the root which share the prefix of the target, then the buckets further away.
+	visit(bi)
+	for i := bi + 1; i < len(tbl.buckets) && len(ret) < k; i++ {
+		visit(i)
+	}
+	for i := bi - 1; i >= 0 && len(ret) < k; i-- {
+		visit(i)
+	}
+
 	// TODO: Keep only the closest.
 	if len(ret) > k {
 		ret = ret[:k]
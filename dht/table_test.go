@@ -51,6 +51,51 @@ func TestTable(t *testing.T) {
 	assert.Equal(t, 0, tbl.numNodes())
 }
 
+func TestTableClosestNodes(t *testing.T) {
+	tbl := newTable(8)
+	tbl.rootID = int160.Random()
+
+	// one node in each of the nearest and furthest buckets.
+	near := &node{nodeKey: nodeKey{Id: tbl.randomIdForBucket(100), Addr: NewAddr(&net.UDPAddr{})}}
+	far := &node{nodeKey: nodeKey{Id: tbl.randomIdForBucket(0), Addr: NewAddr(&net.UDPAddr{})}}
+	assert.NoError(t, tbl.addNode(near))
+	assert.NoError(t, tbl.addNode(far))
+
+	all := func(*node) bool { return true }
+	assert.Equal(t, []*node{near, far}, tbl.closestNodes(8, tbl.randomIdForBucket(50), all))
+	assert.Equal(t, []*node{near}, tbl.closestNodes(1, tbl.randomIdForBucket(50), all))
+	assert.Equal(t, []*node{far}, tbl.closestNodes(1, far.Id, all))
+}
+
+func TestTableForNodes(t *testing.T) {
+	tbl := newTable(8)
+	tbl.rootID = int160.Random()
+
+	// nodes in multiple buckets, including buckets after empty ones.
+	nodes := []*node{
+		{nodeKey: nodeKey{Id: tbl.randomIdForBucket(0), Addr: NewAddr(&net.UDPAddr{})}},
+		{nodeKey: nodeKey{Id: tbl.randomIdForBucket(50), Addr: NewAddr(&net.UDPAddr{})}},
+		{nodeKey: nodeKey{Id: tbl.randomIdForBucket(100), Addr: NewAddr(&net.UDPAddr{})}},
+	}
+	for _, n := range nodes {
+		assert.NoError(t, tbl.addNode(n))
+	}
+
+	var visited []*node
+	assert.True(t, tbl.forNodes(func(n *node) bool {
+		visited = append(visited, n)
+		return true
+	}))
+	assert.ElementsMatch(t, nodes, visited)
+
+	visited = nil
+	assert.False(t, tbl.forNodes(func(n *node) bool {
+		visited = append(visited, n)
+		return false
+	}))
+	assert.Len(t, visited, 1)
+}
+
 func TestRandomIdInBucket(t *testing.T) {
 	tbl := table{
 		rootID: int160.Random(),
@@ -1,22 +1,27 @@
 package torrent
 
 import (
-	"crypto/sha1"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/RoaringBitmap/roaring/v2"
-	"github.com/james-lawrence/torrent/internal/bytesx"
 	"github.com/james-lawrence/torrent/internal/errorsx"
 	"github.com/james-lawrence/torrent/metainfo"
 )
 
 func newDigestsFromTorrent(t *torrent) digests {
+	pool := newDefaultHashPool()
+	if t.cln != nil && t.cln.hashing != nil {
+		pool = t.cln.hashing
+	}
+
 	return newDigests(
+		pool,
 		t.storage,
 		t.piece,
 		func(idx int, cause error) func() {
@@ -26,6 +31,11 @@ func newDigestsFromTorrent(t *torrent) digests {
 
 			t.pieceStateChanges.Publish(idx)
 
+			// peers remain choked until there is data to offer them.
+			if cause == nil {
+				t.rechoke()
+			}
+
 			return func() {
 				if t.cln.torrents == nil {
 					return
@@ -36,18 +46,21 @@ func newDigestsFromTorrent(t *torrent) digests {
 				}
 			}
 		},
+		t.layers,
 	)
 }
 
-func newDigests(iora io.ReaderAt, retrieve func(int) *metainfo.Piece, complete func(int, error) func()) digests {
+func newDigests(pool *hashpool, iora io.ReaderAt, retrieve func(int) *metainfo.Piece, complete func(int, error) func(), layers *pieceLayers) digests {
 	if iora == nil {
 		panic("digests require a storage implementation")
 	}
 
 	return digests{
+		pool:     pool,
 		ReaderAt: iora,
 		retrieve: retrieve,
 		complete: complete,
+		layers:   layers,
 		pending:  newBitQueue(),
 		c:        sync.NewCond(&sync.Mutex{}),
 	}
@@ -55,15 +68,32 @@ func newDigests(iora io.ReaderAt, retrieve func(int) *metainfo.Piece, complete f
 
 // digests is responsible correctness of received data.
 type digests struct {
+	// workers shared by the torrents of the client.
+	pool     *hashpool
 	ReaderAt io.ReaderAt
 	retrieve func(int) *metainfo.Piece
 	complete func(int, error) func()
+	// piece layers used to verify v2 only torrents.
+	layers *pieceLayers
 	// marks whether digest is actively processing.
 	reaping int64
 	// cache of the pieces that need to be verified.
 	pending   *bitQueue
 	c         *sync.Cond
 	completed atomic.Uint64
+	// hashing metrics.
+	hashedPieces atomic.Int64
+	hashedBytes  atomic.Int64
+	elapsed      atomic.Int64
+}
+
+// Stats about the hashing performed.
+func (t *digests) Stats() HashingStats {
+	return HashingStats{
+		Pieces:  t.hashedPieces.Load(),
+		Bytes:   t.hashedBytes.Load(),
+		Elapsed: time.Duration(t.elapsed.Load()),
+	}
 }
 
 // Enqueue a piece to check its completed digest.
@@ -88,14 +118,16 @@ func (t *digests) Wait() {
 }
 
 func (t *digests) verify() {
-	if atomic.AddInt64(&t.reaping, 1) > int64(runtime.NumCPU()) {
+	if atomic.AddInt64(&t.reaping, 1) > int64(t.pool.workers()) {
 		atomic.AddInt64(&t.reaping, -1)
 		return
 	}
 
 	go func() {
 		for idx, ok := t.pending.Pop(); ok; idx, ok = t.pending.Pop() {
-			t.check(idx)
+			t.pool.run(func(buf []byte) {
+				t.check(idx, buf)
+			})
 		}
 
 		if remaining := atomic.AddInt64(&t.reaping, -1); remaining == 0 {
@@ -104,11 +136,10 @@ func (t *digests) verify() {
 	}()
 }
 
-func (t *digests) check(idx int) {
+func (t *digests) check(idx int, buf []byte) {
 	var (
-		err    error
-		digest metainfo.Hash
-		p      *metainfo.Piece
+		err error
+		p   *metainfo.Piece
 	)
 
 	if p = t.retrieve(idx); p == nil {
@@ -116,13 +147,17 @@ func (t *digests) check(idx int) {
 		return
 	}
 
-	if digest, err = t.compute(p); err != nil {
-		t.complete(idx, err)
-		return
-	}
+	started := time.Now()
+	err = t.validate(p, buf)
+	t.hashedPieces.Add(1)
+	t.hashedBytes.Add(p.Length())
+	t.elapsed.Add(int64(time.Since(started)))
 
-	if digest != p.Hash() {
-		t.complete(idx, fmt.Errorf("piece %d digest mismatch %s != %s", idx, hex.EncodeToString(digest[:]), p.Hash().String()))
+	if errors.Is(err, errPieceLayerUnavailable) {
+		// the piece is verified once its hashes are received.
+		return
+	} else if err != nil {
+		t.complete(idx, err)
 		return
 	}
 
@@ -134,20 +169,32 @@ func (t *digests) check(idx int) {
 	}
 }
 
-func (t *digests) compute(p *metainfo.Piece) (ret metainfo.Hash, err error) {
-	var (
-		buf [32 * bytesx.KiB]byte
-	)
-	c := sha1.New()
-	plen := p.Length()
+// validate the piece against the v1 piece hash, v2 only torrents use the merkle trees of their files.
+func (t *digests) validate(p *metainfo.Piece, buf []byte) error {
+	if !p.Info.HasV1() {
+		if t.layers == nil {
+			return fmt.Errorf("piece %d unable to verify v2 torrent without piece layers", p.Index())
+		}
+
+		return t.layers.Verify(t.ReaderAt, p, buf)
+	}
 
-	n, err := io.CopyBuffer(c, io.NewSectionReader(t.ReaderAt, p.Offset(), plen), buf[:])
+	digest, err := t.compute(p, buf)
 	if err != nil {
-		return ret, errorsx.Wrapf(err, "piece %d digest failed", p.Offset())
+		return err
 	}
 
-	if n != plen {
-		return ret, fmt.Errorf("piece digest failed short copy %d: %d != %d", p.Offset(), n, plen)
+	if digest != p.Hash() {
+		return fmt.Errorf("piece %d digest mismatch %s != %s", p.Index(), hex.EncodeToString(digest[:]), p.Hash().String())
+	}
+
+	return nil
+}
+
+func (t *digests) compute(p *metainfo.Piece, buf []byte) (ret metainfo.Hash, err error) {
+	c := t.pool.sha1()
+	if err = digestRegion(c, t.ReaderAt, p.Offset(), p.Length(), buf); err != nil {
+		return ret, errorsx.Wrapf(err, "piece %d", p.Index())
 	}
 
 	copy(ret[:], c.Sum(nil))
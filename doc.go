@@ -21,6 +21,7 @@ BitTorrent features implemented include:
   - 10: Extension protocol
   - 11: PEX
   - 12: Multitracker metadata extension
+  - 14: Local Service Discovery
   - 15: UDP Tracker Protocol
   - 20: Peer ID convention ("-GTnnnn-")
   - 23: Tracker Returns Compact Peer Lists
@@ -29,5 +30,7 @@ BitTorrent features implemented include:
   - 41: UDP Tracker Protocol Extensions
   - 42: DHT Security extension
   - 43: Read-only DHT Nodes
+  - 46: Updating Torrents Via DHT Mutable Items
+  - 51: DHT Infohash Indexing
 */
 package torrent
@@ -3,16 +3,45 @@ package torrent
 import (
 	"strings"
 
+	"github.com/RoaringBitmap/roaring/v2"
 	"github.com/james-lawrence/torrent/metainfo"
 )
 
+// Priority determines the order data is downloaded in.
+type Priority int
+
+const (
+	// PrioritySkip the data is never requested from peers.
+	PrioritySkip Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+	// PriorityUrgent the data is requested before anything else, in order.
+	PriorityUrgent
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PrioritySkip:
+		return "skip"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityUrgent:
+		return "urgent"
+	default:
+		return "unknown"
+	}
+}
+
 // File provides access to regions of torrent data that correspond to its files.
 type File struct {
-	t      *torrent
-	path   string
-	offset int64
-	length int64
-	fi     metainfo.FileInfo
+	t        *torrent
+	path     string
+	offset   int64
+	length   int64
+	fi       metainfo.FileInfo
+	priority Priority
 }
 
 // Torrent returns the associated torrent
@@ -40,8 +69,15 @@ func (f *File) Length() int64 {
 	return f.length
 }
 
-// BytesCompleted number of bytes of the entire file we have completed. This is the sum of
-// completed pieces, and dirtied chunks of incomplete pieces.
+// Priority the download priority of the file.
+func (f *File) Priority() Priority {
+	f.t.rLock()
+	defer f.t.rUnlock()
+	return f.priority
+}
+
+// BytesCompleted number of bytes of the file that belong to completed pieces.
+// skipped files only report the data that was completed on behalf of neighbouring files.
 func (f *File) BytesCompleted() int64 {
 	f.t.rLock()
 	defer f.t.rUnlock()
@@ -54,29 +90,32 @@ func (f *File) bytesCompleted() int64 {
 
 func (f *File) bytesLeft() (left int64) {
 	pieceSize := int64(f.t.usualPieceSize())
-	firstPieceIndex := f.firstPieceIndex()
-	endPieceIndex := f.endPieceIndex() - 1
-
-	dup := f.t.chunks.Clone(f.t.chunks.completed)
-	dup.Flip(firstPieceIndex+1, endPieceIndex)
-	dup.Iterate(func(piece uint32) bool {
-		if uint64(piece) >= endPieceIndex {
-			return false
-		}
-		if uint64(piece) > firstPieceIndex {
-			left += pieceSize
+	completed := f.t.chunks.Clone(f.t.chunks.completed)
+
+	for pid := f.firstPieceIndex(); pid < f.endPieceIndex(); pid++ {
+		if completed.Contains(uint32(pid)) {
+			continue
 		}
-		return true
-	})
 
-	if !f.t.chunks.ChunksComplete(uint64(firstPieceIndex)) {
-		left += pieceSize - (f.offset % pieceSize)
+		begin := max(int64(pid)*pieceSize, f.offset)
+		end := min(int64(pid+1)*pieceSize, f.offset+f.length)
+		left += end - begin
 	}
 
-	if !f.t.chunks.ChunksComplete(uint64(endPieceIndex)) {
-		left += (f.offset + f.length) % pieceSize
+	return left
+}
+
+// the chunks of every piece containing data for the file.
+func (f *File) chunks() *roaring.Bitmap {
+	b := roaring.New()
+	if f.length == 0 {
+		return b
 	}
-	return
+
+	cmin, _ := f.t.chunks.Range(f.firstPieceIndex())
+	_, cmax := f.t.chunks.Range(f.endPieceIndex() - 1)
+	b.AddRange(cmin, cmax)
+	return b
 }
 
 // DisplayPath the relative file path for a multi-file torrent, and the torrent name for a
@@ -98,13 +137,7 @@ func byteRegionExclusivePieces(off, size, pieceSize int64) (begin, end int) {
 
 // NewReader returns a reader for the file.
 func (f *File) NewReader() Reader {
-	tr := reader{
-		TorrentImpl: f.t.storage,
-		offset:      f.Offset(),
-		length:      f.Length(),
-	}
-
-	return &tr
+	return newReader(f.t, f.Offset(), f.Length())
 }
 
 // Returns the index of the first piece containing data for the file.
@@ -4,6 +4,11 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/internal/bitmapx"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/metainfo"
 )
 
 func TestFileExclusivePieces(t *testing.T) {
@@ -20,3 +25,74 @@ func TestFileExclusivePieces(t *testing.T) {
 		assert.EqualValues(t, _case.end, end)
 	}
 }
+
+func TestFilePriority(t *testing.T) {
+	cl := &Client{config: TestingConfig(t, t.TempDir())}
+	md, err := New(metainfo.Hash{})
+	require.NoError(t, err)
+	tt := newTorrent(cl, md)
+	require.NoError(t, tt.setInfo(&metainfo.Info{
+		Name:        "example",
+		PieceLength: 4 * bytesx.KiB,
+		Pieces:      make([]byte, metainfo.HashSize*5),
+		Files: []metainfo.FileInfo{
+			{Path: []string{"a"}, Length: 6 * bytesx.KiB},
+			{Path: []string{"b"}, Length: 1 * bytesx.KiB},
+			{Path: []string{"c"}, Length: 9 * bytesx.KiB},
+			{Path: []string{"d"}, Length: 4 * bytesx.KiB},
+		},
+	}))
+
+	files := tt.Files()
+	require.Len(t, files, 4)
+	require.NoError(t, tt.Tune(TuneAutoDownload, TuneFilePriority(files[0], PrioritySkip), TuneFilePriority(files[2], PriorityHigh)))
+	require.Equal(t, PrioritySkip, files[0].Priority())
+
+	// the first piece only belongs to the skipped file, the high priority
+	// file's pieces are requested before the normal priority file.
+	reqs, err := tt.chunks.Pop(int(tt.chunks.cmaximum), bitmapx.Fill(tt.chunks.cmaximum))
+	require.NoError(t, err)
+	for _, r := range reqs {
+		require.NotEqual(t, 0, int(r.Index))
+	}
+	pids := requestPieces(reqs...)
+	require.ElementsMatch(t, []int{1, 2, 3}, pids[:3])
+	require.Equal(t, []int{4}, pids[3:])
+	tt.chunks.Retry(reqs...)
+
+	for _, f := range files {
+		require.Zero(t, f.BytesCompleted())
+	}
+
+	// complete the piece shared by all three files, the skipped file still
+	// reports the bytes it shares with the completed piece.
+	tt.chunks.Complete(1)
+	require.EqualValues(t, 2*bytesx.KiB, files[0].BytesCompleted())
+	require.EqualValues(t, 1*bytesx.KiB, files[1].BytesCompleted())
+	require.EqualValues(t, 1*bytesx.KiB, files[2].BytesCompleted())
+
+	// the torrent completes without the piece belonging to the skipped file.
+	for pid := uint64(1); pid < 5; pid++ {
+		tt.chunks.Complete(pid)
+	}
+	require.False(t, tt.needData())
+	require.EqualValues(t, 2*bytesx.KiB, files[0].BytesCompleted())
+	for _, f := range files[1:] {
+		require.Equal(t, f.Length(), f.BytesCompleted())
+	}
+
+	// raising the priority of the skipped file wants its data again.
+	require.NoError(t, tt.Tune(TuneFilePriority(files[0], PriorityNormal)))
+	require.True(t, tt.needData())
+	require.EqualValues(t, 2*bytesx.KiB, files[0].BytesCompleted())
+	reqs, err = tt.chunks.Pop(int(tt.chunks.cmaximum), bitmapx.Fill(tt.chunks.cmaximum))
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, requestPieces(reqs...))
+	tt.chunks.Retry(reqs...)
+
+	tt.chunks.Complete(0)
+	require.False(t, tt.needData())
+	for _, f := range files {
+		require.Equal(t, f.Length(), f.BytesCompleted())
+	}
+}
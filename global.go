@@ -11,10 +11,11 @@ const (
 	defaultMaxEstablishedConns = 200
 	defaultChunkSize           = 16 * bytesx.KiB
 	maxRequestsGrace           = 5 // grace requests above the limit before we start rejecting
+	defaultReadahead           = 5 * bytesx.MiB
 )
 
 func defaultPeerExtensionBytes() pp.ExtensionBits {
-	return pp.NewExtensionBits(pp.ExtensionBitDHT, pp.ExtensionBitExtended, pp.ExtensionBitFast)
+	return pp.NewExtensionBits(pp.ExtensionBitDHT, pp.ExtensionBitExtended, pp.ExtensionBitFast, pp.ExtensionBitV2)
 }
 
 // I could move a lot of these counters to their own file, but I suspect they
@@ -10,6 +10,7 @@ func TestDefaultExtensionBytes(t *testing.T) {
 	pex := defaultPeerExtensionBytes()
 	assert.True(t, pex.SupportsDHT())
 	assert.True(t, pex.SupportsExtended())
+	assert.True(t, pex.SupportsV2())
 	assert.False(t, pex.GetBit(63))
 	assert.Panics(t, func() { pex.GetBit(64) })
 }
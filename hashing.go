@@ -0,0 +1,108 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/storage"
+)
+
+// size of the buffer used by each hashing worker when reading pieces.
+const defaultHashingBuffer = 128 * bytesx.KiB
+
+func newHashPool(workers, buffer int, digest func() hash.Hash) *hashpool {
+	if digest == nil {
+		digest = sha1.New
+	}
+
+	slots := make(chan []byte, max(workers, 1))
+	// buffers are allocated once a worker is first used.
+	for range cap(slots) {
+		slots <- nil
+	}
+
+	return &hashpool{
+		slots:  slots,
+		buffer: max(buffer, bytesx.KiB),
+		sha1:   digest,
+	}
+}
+
+func newDefaultHashPool() *hashpool {
+	return newHashPool(runtime.NumCPU(), defaultHashingBuffer, sha1.New)
+}
+
+// hashpool bounds the number of pieces hashed concurrently across the torrents
+// of a client. each worker is given a buffer for reading the piece, bounding
+// the memory used for hashing to workers * buffer.
+type hashpool struct {
+	slots  chan []byte
+	buffer int
+	sha1   func() hash.Hash
+}
+
+// number of pieces that can be hashed concurrently.
+func (t *hashpool) workers() int {
+	return cap(t.slots)
+}
+
+// run fn once a worker is available.
+func (t *hashpool) run(fn func(buf []byte)) {
+	buf := <-t.slots
+	if buf == nil {
+		buf = make([]byte, t.buffer)
+	}
+	defer func() { t.slots <- buf }()
+
+	fn(buf)
+}
+
+// digest the n bytes at the offset of the source into dst. mapped storage is
+// hashed directly from memory, otherwise the data is read using the buffer.
+func digestRegion(dst io.Writer, src io.ReaderAt, off, n int64, buf []byte) (err error) {
+	var (
+		copied int64
+	)
+
+	if m, ok := src.(storage.Mapped); ok {
+		err = m.Range(off, n, func(b []byte) error {
+			w, err := dst.Write(b)
+			copied += int64(w)
+			return err
+		})
+	} else {
+		copied, err = io.CopyBuffer(dst, io.NewSectionReader(src, off, n), buf)
+	}
+
+	if err != nil && !(errorsx.Is(err, io.EOF) && copied == n) {
+		return errorsx.Wrapf(err, "digest failed at offset %d", off)
+	}
+
+	if copied != n {
+		return fmt.Errorf("digest failed short copy at offset %d: %d != %d", off, copied, n)
+	}
+
+	return nil
+}
+
+// HashingStats metrics about the verification of a torrent's pieces.
+type HashingStats struct {
+	Pieces  int64         // number of pieces hashed.
+	Bytes   int64         // number of bytes hashed.
+	Elapsed time.Duration // cumulative time spent hashing across all the workers.
+}
+
+// Throughput in bytes per second of a single hashing worker.
+func (t HashingStats) Throughput() float64 {
+	if t.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(t.Bytes) / t.Elapsed.Seconds()
+}
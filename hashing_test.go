@@ -0,0 +1,78 @@
+package torrent
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/internal/cryptox"
+	"github.com/james-lawrence/torrent/metainfo"
+	"github.com/james-lawrence/torrent/storage"
+)
+
+func TestHashPool(t *testing.T) {
+	var (
+		wg      sync.WaitGroup
+		active  atomic.Int64
+		maximum atomic.Int64
+	)
+
+	pool := newHashPool(3, bytesx.KiB, nil)
+	for range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.run(func(buf []byte) {
+				n := active.Add(1)
+				defer active.Add(-1)
+
+				for m := maximum.Load(); n > m && !maximum.CompareAndSwap(m, n); m = maximum.Load() {
+				}
+
+				require.Len(t, buf, bytesx.KiB)
+			})
+		}()
+	}
+
+	wg.Wait()
+	require.LessOrEqual(t, maximum.Load(), int64(3))
+	require.Len(t, pool.slots, 3)
+}
+
+func TestDigestRegion(t *testing.T) {
+	data := make([]byte, 3*bytesx.KiB+7)
+	_, err := io.ReadFull(cryptox.NewChaCha8(t.Name()), data)
+	require.NoError(t, err)
+
+	info := &metainfo.Info{
+		Name:        "root",
+		PieceLength: bytesx.KiB,
+		Files: []metainfo.FileInfo{
+			{Path: []string{"a"}, Length: bytesx.KiB + 3},
+			{Path: []string{"b"}, Length: 2*bytesx.KiB + 4},
+		},
+	}
+
+	mapped, err := storage.NewMMap(t.TempDir()).OpenTorrent(info, int160.Zero())
+	require.NoError(t, err)
+	defer mapped.Close()
+	_, err = mapped.WriteAt(data, 0)
+	require.NoError(t, err)
+	require.Implements(t, (*storage.Mapped)(nil), mapped)
+
+	for _, src := range []io.ReaderAt{bytes.NewReader(data), mapped} {
+		// spans both files.
+		digest := sha1.New()
+		require.NoError(t, digestRegion(digest, src, bytesx.KiB, 2*bytesx.KiB, make([]byte, 512)))
+		require.Equal(t, sha1.Sum(data[bytesx.KiB:3*bytesx.KiB]), [sha1.Size]byte(digest.Sum(nil)))
+
+		require.Error(t, digestRegion(sha1.New(), src, 3*bytesx.KiB, bytesx.KiB, nil))
+	}
+}
@@ -0,0 +1,176 @@
+// Package merkle implements the SHA-256 merkle trees used by BitTorrent v2 (BEP 52).
+package merkle
+
+import (
+	"crypto/sha256"
+	"math/bits"
+
+	"github.com/james-lawrence/torrent/internal/bytesx"
+)
+
+// BlockSize of the leaves of the tree.
+const BlockSize = 16 * bytesx.KiB
+
+// Size of the hashes within the tree.
+const Size = sha256.Size
+
+// RoundUp n to the next power of two.
+func RoundUp(n int) int {
+	if n <= 1 {
+		return 1
+	}
+
+	return 1 << bits.Len(uint(n-1))
+}
+
+// Pad returns the root of a tree containing n zero leaves, n must be a power of two.
+func Pad(n int) (h [Size]byte) {
+	for ; n > 1; n /= 2 {
+		h = pair(h, h)
+	}
+
+	return h
+}
+
+// Root of the tree built from the given hashes, the layer is padded out to
+// width (rounded to a power of two) using the pad hash.
+func Root(hashes [][Size]byte, width int, pad [Size]byte) [Size]byte {
+	layer := make([][Size]byte, RoundUp(max(width, len(hashes))))
+	n := copy(layer, hashes)
+	for i := n; i < len(layer); i++ {
+		layer[i] = pad
+	}
+
+	for len(layer) > 1 {
+		next := layer[:len(layer)/2]
+		for i := range next {
+			next[i] = pair(layer[2*i], layer[2*i+1])
+		}
+		layer = next
+	}
+
+	return layer[0]
+}
+
+// Tree returns every layer of the tree built from the hashes, from the base
+// layer up to the root. The base layer is padded in the same manner as Root.
+func Tree(hashes [][Size]byte, width int, pad [Size]byte) (layers [][][Size]byte) {
+	layer := make([][Size]byte, RoundUp(max(width, len(hashes))))
+	n := copy(layer, hashes)
+	for i := n; i < len(layer); i++ {
+		layer[i] = pad
+	}
+
+	layers = append(layers, layer)
+	for len(layer) > 1 {
+		next := make([][Size]byte, len(layer)/2)
+		for i := range next {
+			next[i] = pair(layer[2*i], layer[2*i+1])
+		}
+		layers = append(layers, next)
+		layer = next
+	}
+
+	return layers
+}
+
+// Proof returns up to n uncle hashes, lowest layer first, required to verify the
+// subtree of length (a power of two) hashes starting at index of the base layer.
+func Proof(layers [][][Size]byte, index, length, n int) (uncles [][Size]byte) {
+	pos := index / length
+	for l := bits.Len(uint(length)) - 1; l < len(layers)-1 && len(uncles) < n; l++ {
+		uncles = append(uncles, layers[l][pos^1])
+		pos /= 2
+	}
+
+	return uncles
+}
+
+// VerifyProof reports if the subtree formed by the hashes, which start at index
+// of the base layer, combined with the uncle hashes produces the root.
+func VerifyProof(root [Size]byte, hashes [][Size]byte, index int, uncles [][Size]byte) bool {
+	if len(hashes) == 0 || len(hashes) != RoundUp(len(hashes)) || index%len(hashes) != 0 {
+		return false
+	}
+
+	computed := Root(hashes, 0, [Size]byte{})
+	pos := index / len(hashes)
+	for _, u := range uncles {
+		if pos%2 == 0 {
+			computed = pair(computed, u)
+		} else {
+			computed = pair(u, computed)
+		}
+		pos /= 2
+	}
+
+	return pos == 0 && computed == root
+}
+
+func pair(l, r [Size]byte) [Size]byte {
+	var buf [2 * Size]byte
+	copy(buf[:Size], l[:])
+	copy(buf[Size:], r[:])
+	return sha256.Sum256(buf[:])
+}
+
+// New hash that splits the written data into BlockSize leaves.
+func New() *Hash {
+	return &Hash{
+		buf: make([]byte, 0, BlockSize),
+	}
+}
+
+// Hash implements hash.Hash, the sum is the root of the tree formed by the written data.
+type Hash struct {
+	leaves [][Size]byte
+	buf    []byte
+}
+
+func (t *Hash) Write(b []byte) (n int, err error) {
+	n = len(b)
+	for len(b) > 0 {
+		c := min(BlockSize-len(t.buf), len(b))
+		t.buf = append(t.buf, b[:c]...)
+		b = b[c:]
+
+		if len(t.buf) == BlockSize {
+			t.leaves = append(t.leaves, sha256.Sum256(t.buf))
+			t.buf = t.buf[:0]
+		}
+	}
+
+	return n, nil
+}
+
+// Leaves returns the hashes of the blocks written so far, including the trailing partial block.
+func (t *Hash) Leaves() [][Size]byte {
+	if len(t.buf) == 0 {
+		return t.leaves
+	}
+
+	return append(t.leaves[:len(t.leaves):len(t.leaves)], sha256.Sum256(t.buf))
+}
+
+// Root of the written data padded with zero leaves to at least width leaves.
+func (t *Hash) Root(width int) [Size]byte {
+	return Root(t.Leaves(), width, [Size]byte{})
+}
+
+func (t *Hash) Sum(b []byte) []byte {
+	r := t.Root(0)
+	return append(b, r[:]...)
+}
+
+func (t *Hash) Reset() {
+	t.leaves = t.leaves[:0]
+	t.buf = t.buf[:0]
+}
+
+func (t *Hash) Size() int {
+	return Size
+}
+
+func (t *Hash) BlockSize() int {
+	return BlockSize
+}
@@ -0,0 +1,57 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashRoot(t *testing.T) {
+	data := make([]byte, 2*BlockSize+10)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	h := New()
+	_, err := h.Write(data)
+	require.NoError(t, err)
+
+	l0 := sha256.Sum256(data[:BlockSize])
+	l1 := sha256.Sum256(data[BlockSize : 2*BlockSize])
+	l2 := sha256.Sum256(data[2*BlockSize:])
+	expected := pair(pair(l0, l1), pair(l2, [Size]byte{}))
+
+	require.Equal(t, expected, h.Root(0))
+	require.Equal(t, expected[:], h.Sum(nil))
+	require.Equal(t, pair(expected, Pad(4)), h.Root(8))
+}
+
+func TestProof(t *testing.T) {
+	hashes := make([][Size]byte, 13)
+	for i := range hashes {
+		hashes[i] = sha256.Sum256([]byte{byte(i)})
+	}
+	pad := Pad(4)
+	root := Root(hashes, 0, pad)
+	tree := Tree(hashes, 0, pad)
+	require.Equal(t, root, tree[len(tree)-1][0])
+
+	for _, length := range []int{2, 4, 8, 16} {
+		for index := 0; index < len(tree[0]); index += length {
+			t.Run(fmt.Sprintf("%d-%d", index, length), func(t *testing.T) {
+				uncles := Proof(tree, index, length, len(tree))
+				require.True(t, VerifyProof(root, tree[0][index:index+length], index, uncles))
+
+				tampered := append([][Size]byte(nil), tree[0][index:index+length]...)
+				tampered[0][0] ^= 0xff
+				require.False(t, VerifyProof(root, tampered, index, uncles))
+
+				if len(uncles) > 0 {
+					require.False(t, VerifyProof(root, tree[0][index:index+length], index, uncles[:len(uncles)-1]))
+				}
+			})
+		}
+	}
+}
@@ -90,6 +90,11 @@ func (t RacingDialer) Dial(ctx context.Context, timeout time.Duration, address s
 		dup.network = n
 
 		if err := queue(__ctx, dup); err != nil {
+			// a previously queued network already established the connection.
+			if _ctx.Err() == nil && __ctx.Err() != nil {
+				break
+			}
+
 			cancel(errorsx.Wrapf(err, "timeout: %d", timeout))
 			return nil, err
 		}
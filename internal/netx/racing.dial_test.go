@@ -3,6 +3,7 @@ package netx
 import (
 	"context"
 	"net"
+	"runtime"
 	"testing"
 	"time"
 
@@ -45,6 +46,31 @@ func TestRacingDialer_Dial(t *testing.T) {
 		require.NoError(t, conn.Close())
 	})
 
+	t.Run("success before the remaining networks are queued wins", func(t *testing.T) {
+		dialer := NewRacing(1)
+		timeout := time.Second
+
+		winnerNetwork := dialerfn(func(ctx context.Context, addr string) (net.Conn, error) {
+			c, _ := net.Pipe()
+			return c, nil
+		})
+
+		stallingNetwork := dialerfn(func(ctx context.Context, addr string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, context.Cause(ctx)
+		})
+
+		networks := []DialableNetwork{winnerNetwork}
+		for range runtime.NumCPU() + 8 {
+			networks = append(networks, stallingNetwork)
+		}
+
+		conn, err := dialer.Dial(context.Background(), timeout, "127.0.0.1:8080", networks...)
+		require.NoError(t, err)
+		require.NotNil(t, conn)
+		require.NoError(t, conn.Close())
+	})
+
 	t.Run("all networks fail to dial", func(t *testing.T) {
 		dialer := NewRacing(10)
 		address := "127.0.0.1:8081"
@@ -1,6 +1,9 @@
 package slicesx
 
-import "iter"
+import (
+	"iter"
+	"slices"
+)
 
 // Remove elements from the slice where the predicate returns true.
 func Remove[T any](remove func(T) bool, items ...T) []T {
@@ -163,3 +166,14 @@ func IterSlice[T any](v iter.Seq[T]) []T {
 	}
 	return o
 }
+
+// AppendMissing appends the items not already present in the slice, preserving their order.
+func AppendMissing[T comparable](dst []T, items ...T) []T {
+	for _, item := range items {
+		if !slices.Contains(dst, item) {
+			dst = append(dst, item)
+		}
+	}
+
+	return dst
+}
@@ -2,8 +2,13 @@ package testutil
 
 import (
 	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/james-lawrence/torrent/bencode"
 	"github.com/james-lawrence/torrent/internal/errorsx"
 	"github.com/james-lawrence/torrent/metainfo"
@@ -59,3 +64,50 @@ func (t *Torrent) Metainfo(pieceLength int64) *metainfo.MetaInfo {
 	errorsx.Panic(err)
 	return &mi
 }
+
+// InfoV2 generates a v2 only (BEP 52) info for the files under the root directory.
+func InfoV2(t tt, root string, pieceLength int64) *metainfo.Info {
+	info := &metainfo.Info{
+		Name:        filepath.Base(root),
+		PieceLength: pieceLength,
+		MetaVersion: 2,
+		PieceLayers: metainfo.PieceLayers{},
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		// the root is a file.
+		if rel == "." {
+			rel = filepath.Base(root)
+		}
+
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		f, layer, err := metainfo.ComputeFileV2(src, pieceLength)
+		if err != nil {
+			return err
+		}
+
+		if len(layer) > 0 {
+			info.PieceLayers.Set(f.Root(), layer)
+		}
+
+		info.FileTree.Insert(strings.Split(rel, string(filepath.Separator)), metainfo.FileTree{File: &f})
+		return nil
+	})
+	require.NoError(t, err)
+
+	return info
+}
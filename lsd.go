@@ -0,0 +1,197 @@
+package torrent
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/james-lawrence/torrent/bep0014"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/backoffx"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/netx"
+)
+
+// how frequently active torrents are announced to the local network.
+const lsdInterval = 5 * time.Minute
+
+type lsdconn struct {
+	net.PacketConn
+	group netip.AddrPort
+}
+
+// BindLSD enables local service discovery (BEP 14) on the packet connection, the
+// active torrents are announced to the multicast group and peers announced by others
+// on the local network are added to the torrents. see bep0014.Listen.
+func (cl *Client) BindLSD(pc net.PacketConn, group netip.AddrPort) error {
+	cl.lock()
+	defer cl.unlock()
+
+	if len(cl.lsdconns) == 0 {
+		go cl.lsdAnnouncer()
+	}
+
+	c := lsdconn{PacketConn: pc, group: group}
+	cl.lsdconns = append(cl.lsdconns, c)
+	cl.onClose = append(cl.onClose, func() { pc.Close() })
+
+	go cl.lsdReceive(c)
+	cl.lsdAnnounceSoon()
+
+	return nil
+}
+
+// request the active torrents are announced to the local network.
+func (cl *Client) lsdAnnounceSoon() {
+	select {
+	case cl.lsdEvent <- struct{}{}:
+	default:
+	}
+}
+
+func (cl *Client) lsdAnnouncer() {
+	var (
+		last time.Time
+	)
+
+	ticker := time.NewTicker(lsdInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cl.closed:
+			return
+		case <-ticker.C:
+		case <-cl.lsdEvent:
+			// announcements are limited to once a minute.
+			select {
+			case <-cl.closed:
+				return
+			case <-time.After(time.Until(last.Add(time.Minute))):
+			}
+		}
+
+		last = time.Now()
+		cl.lsdAnnounce()
+	}
+}
+
+func (cl *Client) lsdAnnounce() {
+	var (
+		ids []int160.T
+	)
+
+	for _, t := range cl.torrents.active() {
+		if !t.lsdEnabled() {
+			continue
+		}
+
+		ids = append(ids, t.md.Swarms()...)
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	cl.rLock()
+	conns := cl.lsdconns
+	cl.rUnlock()
+
+	// nothing to announce until we're accepting connections.
+	port := cl.LocalPort16()
+	if port == 0 {
+		return
+	}
+
+	for _, c := range conns {
+		for _, announce := range bep0014.Announcements(c.group, port, cl.lsdcookie, ids...) {
+			encoded, err := announce.MarshalBinary()
+			if err != nil {
+				cl.config.errors().Println(errorsx.Wrap(err, "unable to encode local service discovery announcement"))
+				continue
+			}
+
+			if _, err := c.WriteTo(encoded, net.UDPAddrFromAddrPort(c.group)); err != nil {
+				cl.config.debug().Println(errorsx.Wrapf(err, "unable to announce to local service discovery group %s", c.group))
+			}
+		}
+	}
+}
+
+func (cl *Client) lsdReceive(c lsdconn) {
+	var (
+		buf      = make([]byte, 2*bep0014.MaximumMessageSize)
+		attempts = 0
+		backoff  = backoffx.New(
+			backoffx.Exponential(100*time.Millisecond),
+			backoffx.Maximum(time.Minute),
+		)
+	)
+
+	for {
+		n, from, err := c.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			delay := backoff.Backoff(attempts)
+			attempts++
+			cl.config.debug().Println(errorsx.Wrapf(err, "local service discovery read failed, retrying in %s", delay))
+
+			select {
+			case <-cl.closed:
+				return
+			case <-time.After(delay):
+			}
+			continue
+		}
+		attempts = 0
+
+		var announce bep0014.Announce
+		if err = announce.UnmarshalBinary(buf[:n]); err != nil {
+			cl.config.debug().Println(errorsx.Wrapf(err, "invalid local service discovery announcement from %s", from))
+			continue
+		}
+
+		if announce.Cookie == cl.lsdcookie {
+			continue
+		}
+
+		src, err := netx.AddrPort(from)
+		if err != nil {
+			cl.config.debug().Println(errorsx.Wrap(err, "invalid local service discovery address"))
+			continue
+		}
+
+		cl.onLSDAnnounce(netip.AddrPortFrom(src.Addr().Unmap(), announce.Port), announce.Infohashes...)
+	}
+}
+
+func (cl *Client) onLSDAnnounce(addr netip.AddrPort, ids ...int160.T) {
+	for _, id := range ids {
+		t, _, err := cl.torrents.Load(id, cl.newTorrent)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			cl.config.debug().Println(errorsx.Wrapf(err, "unable to load torrent for local service discovery: %s", id))
+			continue
+		}
+
+		if !t.lsdEnabled() {
+			continue
+		}
+
+		t.addPeers(NewPeer(
+			int160.Zero(),
+			addr,
+			PeerOptionSource(peerSourceLSD),
+			PeerOptionSwarm(id),
+		))
+	}
+}
+
+// local service discovery is enabled unless disabled for the torrent or it is private.
+func (t *torrent) lsdEnabled() bool {
+	return !t.lsddisabled.Load() && !t.private()
+}
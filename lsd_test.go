@@ -0,0 +1,115 @@
+package torrent
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/bep0014"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/metainfo"
+	"github.com/james-lawrence/torrent/torrenttest"
+)
+
+func TestLSD(t *testing.T) {
+	start := func(t *testing.T, cl *Client, options ...metainfo.Option) *torrent {
+		info, _, err := torrenttest.Random(t.TempDir(), 32, options...)
+		require.NoError(t, err)
+		md, err := NewFromInfo(info)
+		require.NoError(t, err)
+		tt, _, err := cl.start(md, TuneMaxConnections(0))
+		require.NoError(t, err)
+		return tt
+	}
+
+	t.Run("announce active torrents", func(t *testing.T) {
+		cl, err := Autosocket(t).Bind(NewClient(TestingConfig(t, t.TempDir())))
+		require.NoError(t, err)
+		defer cl.Close()
+
+		group, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		require.NoError(t, err)
+		defer group.Close()
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		require.NoError(t, err)
+		require.NoError(t, cl.BindLSD(conn, group.LocalAddr().(*net.UDPAddr).AddrPort()))
+
+		tt := start(t, cl)
+		private := start(t, cl, func(i *metainfo.Info) { i.Private = langx.Autoptr(true) })
+		disabled := start(t, cl)
+		require.NoError(t, disabled.Tune(TuneLSD(false)))
+		cl.lsdAnnounce()
+
+		buf := make([]byte, bep0014.MaximumMessageSize)
+		require.NoError(t, group.SetReadDeadline(time.Now().Add(5*time.Second)))
+		for {
+			n, err := group.Read(buf)
+			require.NoError(t, err)
+
+			var announce bep0014.Announce
+			require.NoError(t, announce.UnmarshalBinary(buf[:n]))
+			require.Equal(t, cl.lsdcookie, announce.Cookie)
+			require.NotContains(t, announce.Infohashes, private.md.ID)
+			require.NotContains(t, announce.Infohashes, disabled.md.ID)
+			if len(announce.Infohashes) == 1 {
+				require.Equal(t, []int160.T{tt.md.ID}, announce.Infohashes)
+				return
+			}
+		}
+	})
+
+	t.Run("add announced peers", func(t *testing.T) {
+		cl, err := NewClient(TestingConfig(t, t.TempDir()))
+		require.NoError(t, err)
+		defer cl.Close()
+
+		tt := start(t, cl)
+		private := start(t, cl, func(i *metainfo.Info) { i.Private = langx.Autoptr(true) })
+		disabled := start(t, cl)
+		require.NoError(t, disabled.Tune(TuneLSD(false)))
+
+		peer := netip.MustParseAddrPort("192.168.1.2:6881")
+		cl.onLSDAnnounce(peer, tt.md.ID, private.md.ID, disabled.md.ID, int160.Random())
+
+		require.Equal(t, 1, tt.peers.Len())
+		popped, ok := tt.peers.PopMax()
+		require.True(t, ok)
+		require.Equal(t, peer, popped.p.AddrPort)
+		require.Equal(t, peerSource(peerSourceLSD), popped.p.Source)
+		require.Equal(t, 0, private.peers.Len())
+		require.Equal(t, 0, disabled.peers.Len())
+	})
+	t.Run("back off on read failures", func(t *testing.T) {
+		cl, err := NewClient(TestingConfig(t, t.TempDir()))
+		require.NoError(t, err)
+
+		conn := &lsdfailingconn{}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			cl.lsdReceive(lsdconn{PacketConn: conn})
+		}()
+
+		time.Sleep(500 * time.Millisecond)
+		require.NoError(t, cl.Close())
+		<-done
+		require.Less(t, conn.reads.Load(), int64(10))
+	})
+}
+
+// packet connection that always fails to read.
+type lsdfailingconn struct {
+	net.PacketConn
+	reads atomic.Int64
+}
+
+func (t *lsdfailingconn) ReadFrom([]byte) (int, net.Addr, error) {
+	t.reads.Add(1)
+	return 0, nil, errors.New("read failed")
+}
@@ -0,0 +1,173 @@
+package torrent
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+// maximum size of a .torrent file downloaded from a magnet source.
+const maxSourceSize = 64 * bytesx.MiB
+
+// the initial priority of the file at the given index of the info, files absent
+// from the selection are skipped (BEP 53).
+func (t *torrent) selected(idx int) Priority {
+	if len(t.md.SelectOnly) == 0 || slices.ContainsFunc(t.md.SelectOnly, func(r metainfo.FileRange) bool { return r.Contains(idx) }) {
+		return PriorityNormal
+	}
+
+	return PrioritySkip
+}
+
+// replace the file selection of the metadata, e.g. from the so parameter of a magnet
+// link for an already known torrent.
+func (t *torrent) selectOnly(selection ...metainfo.FileRange) {
+	if len(selection) == 0 || slices.Equal(t.md.SelectOnly, selection) {
+		return
+	}
+
+	t.md.SelectOnly = selection
+
+	if !t.haveInfo() {
+		return
+	}
+
+	t.lock()
+	defer t.unlock()
+
+	// t.files mirrors the upverted files without the padding, see initFiles.
+	files := t.files
+	for idx, fi := range t.info.UpvertedFiles() {
+		if fi.IsPadding() || len(files) == 0 {
+			continue
+		}
+
+		files[0].priority = t.selected(idx)
+		files = files[1:]
+	}
+
+	t.priorities = nil
+	t.prioritize()
+}
+
+// resolve and add the peers provided by the metadata, e.g. magnet x.pe values.
+func (t *torrent) addHintedPeers(ctx context.Context, addrs ...string) {
+	peers := make([]Peer, 0, len(addrs))
+	for _, addr := range addrs {
+		resolved, err := resolvePeerAddr(ctx, addr)
+		if err != nil {
+			t.cln.config.info().Printf("ignoring peer %q: %v\n", addr, err)
+			continue
+		}
+
+		peers = append(peers, NewPeer(int160.Zero(), resolved, PeerOptionSource(peerSourceMagnet)))
+	}
+
+	t.lock()
+	t.addPeers(peers...)
+	t.unlock()
+	t.maybeNewConns()
+}
+
+func resolvePeerAddr(ctx context.Context, addr string) (netip.AddrPort, error) {
+	if resolved, err := netip.ParseAddrPort(addr); err == nil {
+		return resolved, nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return netip.AddrPort{}, errorsx.Wrapf(err, "invalid port %q", port)
+	}
+
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+
+	if len(ips) == 0 {
+		return netip.AddrPort{}, errorsx.Errorf("no addresses found for %s", host)
+	}
+
+	return netip.AddrPortFrom(ips[0].Unmap(), uint16(p)), nil
+}
+
+// download the .torrent from the sources in order until one provides the info,
+// falling back to requesting the info from peers via ut_metadata.
+func (t *torrent) fetchSources(ctx context.Context, c *http.Client, uris ...string) {
+	for _, uri := range uris {
+		t.rLock()
+		done := t.haveInfo()
+		t.rUnlock()
+
+		if done {
+			return
+		}
+
+		if err := t.fetchSource(ctx, c, uri); err != nil {
+			t.cln.config.info().Printf("unable to retrieve torrent from %s: %v\n", uri, err)
+			continue
+		}
+
+		t.cln.config.debug().Printf("%s: received metadata from %s\n", t, uri)
+		return
+	}
+}
+
+func (t *torrent) fetchSource(ctx context.Context, c *http.Client, uri string) (err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return errorsx.WithStack(err)
+	}
+	req.Header.Set("User-Agent", t.cln.config.HTTPUserAgent)
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return errorsx.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errorsx.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	mi, err := metainfo.Load(io.LimitReader(resp.Body, maxSourceSize))
+	if err != nil {
+		return errorsx.Wrap(err, "invalid torrent file")
+	}
+
+	t.lock()
+	defer t.unlock()
+
+	if t.haveInfo() {
+		return nil
+	}
+
+	layers, pending := t.md.PieceLayers, t.metadataBytes
+	if len(layers) == 0 {
+		t.md.PieceLayers = mi.PieceLayers
+	}
+
+	t.metadataBytes = mi.InfoBytes
+	if err = t.setInfoBytes(t.metadataBytes); err != nil {
+		t.md.PieceLayers, t.metadataBytes = layers, pending
+		return err
+	}
+
+	t.chunks.fill(t.chunks.missing, uint64(t.chunks.cmaximum))
+
+	return nil
+}
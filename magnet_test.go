@@ -0,0 +1,154 @@
+package torrent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/internal/testx"
+	"github.com/james-lawrence/torrent/metainfo"
+	"github.com/james-lawrence/torrent/torrenttest"
+)
+
+func TestMagnetExtensions(t *testing.T) {
+	cl, err := NewClient(TestingConfig(t, t.TempDir()))
+	require.NoError(t, err)
+	defer cl.Close()
+
+	t.Run("info is downloaded from the sources", func(t *testing.T) {
+		ctx, done := testx.Context(t)
+		defer done()
+
+		info, _, err := torrenttest.Random(t.TempDir(), 32*bytesx.KiB)
+		require.NoError(t, err)
+		md, err := NewFromInfo(info)
+		require.NoError(t, err)
+		encoded, err := metainfo.Encode(md.Metainfo())
+		require.NoError(t, err)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/example.torrent" {
+				http.NotFound(w, r)
+				return
+			}
+
+			w.Write(encoded)
+		}))
+		defer srv.Close()
+
+		m := NewMagnet(md)
+		m.Sources = []string{srv.URL + "/missing.torrent", srv.URL + "/example.torrent"}
+		magnet, err := NewFromMagnet(m.String())
+		require.NoError(t, err)
+		require.Nil(t, magnet.InfoBytes)
+		require.Equal(t, m.Sources, magnet.Sources)
+
+		tt, _, err := cl.start(magnet, TuneMaxConnections(0))
+		require.NoError(t, err)
+
+		select {
+		case <-tt.GotInfo():
+		case <-ctx.Done():
+			require.FailNow(t, "info was not downloaded")
+		}
+		require.Equal(t, md.InfoBytes, tt.Metadata().InfoBytes)
+	})
+
+	t.Run("unselected files are skipped", func(t *testing.T) {
+		md, err := New(metainfo.Hash{}, OptionSelectOnly(0, 2))
+		require.NoError(t, err)
+		tt := newTorrent(cl, md)
+		require.NoError(t, tt.setInfo(&metainfo.Info{
+			Name:        "example",
+			PieceLength: 4 * bytesx.KiB,
+			Pieces:      make([]byte, metainfo.HashSize*3),
+			Files: []metainfo.FileInfo{
+				{Path: []string{"a"}, Length: 4 * bytesx.KiB},
+				{Path: []string{"b"}, Length: 4 * bytesx.KiB},
+				{Path: []string{"c"}, Length: 4 * bytesx.KiB},
+			},
+		}))
+
+		files := tt.Files()
+		require.Len(t, files, 3)
+		require.Equal(t, PriorityNormal, files[0].Priority())
+		require.Equal(t, PrioritySkip, files[1].Priority())
+		require.Equal(t, PriorityNormal, files[2].Priority())
+
+		require.NoError(t, tt.Tune(TuneAutoDownload))
+		reqs, err := tt.chunks.Pop(int(tt.chunks.cmaximum), tt.chunks.Clone(tt.chunks.missing))
+		require.NoError(t, err)
+		require.ElementsMatch(t, []int{0, 2}, requestPieces(reqs...))
+	})
+
+	t.Run("ranges past the files select the remaining files", func(t *testing.T) {
+		md, err := New(metainfo.Hash{}, OptionSelectOnlyRanges(metainfo.FileRange{First: 1, Last: 2000000000}))
+		require.NoError(t, err)
+		tt := newTorrent(cl, md)
+		require.NoError(t, tt.setInfo(&metainfo.Info{
+			Name:        "example",
+			PieceLength: 4 * bytesx.KiB,
+			Pieces:      make([]byte, metainfo.HashSize*3),
+			Files: []metainfo.FileInfo{
+				{Path: []string{"a"}, Length: 4 * bytesx.KiB},
+				{Path: []string{"b"}, Length: 4 * bytesx.KiB},
+				{Path: []string{"c"}, Length: 4 * bytesx.KiB},
+			},
+		}))
+
+		files := tt.Files()
+		require.Len(t, files, 3)
+		require.Equal(t, PrioritySkip, files[0].Priority())
+		require.Equal(t, PriorityNormal, files[1].Priority())
+		require.Equal(t, PriorityNormal, files[2].Priority())
+	})
+
+	t.Run("hints are merged into known torrents", func(t *testing.T) {
+		info, _, err := torrenttest.Random(t.TempDir(), 32)
+		require.NoError(t, err)
+		md, err := NewFromInfo(info)
+		require.NoError(t, err)
+		tt, _, err := cl.start(md, TuneMaxConnections(0))
+		require.NoError(t, err)
+
+		m := NewMagnet(md)
+		m.Peers = []string{"10.0.0.1:6881"}
+		m.Sources = []string{"http://example.com/example.torrent"}
+		m.SelectOnly = []metainfo.FileRange{metainfo.FileIndex(1)}
+		magnet, err := NewFromMagnet(m.String())
+		require.NoError(t, err)
+
+		for range 2 {
+			tt.Tune(tuneMerge(magnet))
+		}
+
+		require.Equal(t, m.Peers, tt.Metadata().Peers)
+		require.Equal(t, m.Sources, tt.Metadata().Sources)
+		require.Equal(t, PrioritySkip, tt.Files()[0].Priority())
+	})
+
+	t.Run("peers are added", func(t *testing.T) {
+		ctx, done := testx.Context(t)
+		defer done()
+
+		info, _, err := torrenttest.Random(t.TempDir(), 32)
+		require.NoError(t, err)
+		md, err := NewFromInfo(info)
+		require.NoError(t, err)
+		tt, _, err := cl.start(md, TuneMaxConnections(0))
+		require.NoError(t, err)
+
+		for _, addr := range []string{"10.0.0.1:6881", "[fd00::1]:6881", "localhost:6882"} {
+			tt.addHintedPeers(ctx, addr)
+			p, ok := tt.peers.PopMax()
+			require.True(t, ok, addr)
+			require.Equal(t, peerSource(peerSourceMagnet), p.p.Source)
+		}
+
+		tt.addHintedPeers(ctx, "invalid", "localhost:port")
+		require.Zero(t, tt.peers.Len())
+	})
+}
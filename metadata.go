@@ -3,7 +3,9 @@ package torrent
 import (
 	"io"
 	"math/rand/v2"
+	"net/url"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/james-lawrence/torrent/bencode"
@@ -35,6 +37,16 @@ func OptionPublicTrackers(private bool, trackers ...string) Option {
 	}
 }
 
+// OptionTiers add the tiers of trackers to the torrent, see BEP 12.
+func OptionTiers(tiers ...[]string) Option {
+	return func(t *Metadata) {
+		for _, tier := range tiers {
+			t.Tiers = append(t.Tiers, tier)
+			t.Trackers = append(t.Trackers, tier...)
+		}
+	}
+}
+
 // OptionTrackers set the trackers for the torrent.
 func OptionResetTrackers(trackers ...string) Option {
 	return func(t *Metadata) {
@@ -49,6 +61,37 @@ func OptionNodes(nodes ...string) Option {
 	}
 }
 
+// OptionPeers peer addresses, in host:port form, to connect to when the torrent starts.
+func OptionPeers(peers ...string) Option {
+	return func(t *Metadata) {
+		t.Peers = append(t.Peers, peers...)
+	}
+}
+
+// OptionSources urls the .torrent file can be downloaded from, they're tried in order
+// before requesting the info from peers.
+func OptionSources(sources ...string) Option {
+	return func(t *Metadata) {
+		t.Sources = append(t.Sources, sources...)
+	}
+}
+
+// OptionSelectOnly only download the files with the given indices, see BEP 53.
+func OptionSelectOnly(indices ...int) Option {
+	return func(t *Metadata) {
+		for _, idx := range indices {
+			t.SelectOnly = append(t.SelectOnly, metainfo.FileIndex(idx))
+		}
+	}
+}
+
+// OptionSelectOnlyRanges only download the files within the given ranges, see BEP 53.
+func OptionSelectOnlyRanges(ranges ...metainfo.FileRange) Option {
+	return func(t *Metadata) {
+		t.SelectOnly = append(t.SelectOnly, ranges...)
+	}
+}
+
 // OptionDisplayName set the display name for the torrent.
 func OptionDisplayName(dn string) Option {
 	return func(t *Metadata) {
@@ -84,6 +127,20 @@ func OptionWebseeds(seeds []string) Option {
 	}
 }
 
+// OptionIDv2 set the v2 (BEP 52) info hash of the torrent.
+func OptionIDv2(id metainfo.HashV2) Option {
+	return func(t *Metadata) {
+		t.IDv2 = id
+	}
+}
+
+// OptionPieceLayers set the v2 (BEP 52) piece layers of the torrent.
+func OptionPieceLayers(layers metainfo.PieceLayers) Option {
+	return func(t *Metadata) {
+		t.PieceLayers = layers
+	}
+}
+
 // OptionNoop does nothing, stand in during configurations.
 func OptionNoop(t *Metadata) {}
 
@@ -91,13 +148,27 @@ func OptionNoop(t *Metadata) {}
 // There are helpers for magnet URIs and torrent metainfo files.
 type Metadata struct {
 	// The tiered tracker URIs.
-	Trackers  []string
-	ID        int160.T
+	Trackers []string
+	// The BEP 12 tiers of the trackers, trackers absent from the tiers
+	// form a final tier. see AnnounceList.
+	Tiers [][]string
+	ID    int160.T
+	// The full v2 info hash, the ID of v2 only torrents is the truncated form
+	// of this hash.
+	IDv2      metainfo.HashV2
 	InfoBytes []byte
+	// The v2 piece layers, missing layers are requested from peers.
+	PieceLayers metainfo.PieceLayers
 	// The name to use if the Name field from the Info isn't available.
 	DisplayName string
 	Webseeds    []string
 	DHTNodes    []string
+	// Peers to connect to when the torrent starts, in host:port form.
+	Peers []string
+	// urls the .torrent file can be downloaded from.
+	Sources []string
+	// ranges of the files to download, every file is downloaded when empty.
+	SelectOnly []metainfo.FileRange
 	// The chunk size to use for outbound requests. Defaults to 16KiB if not
 	// set.
 	ChunkSize uint64
@@ -114,6 +185,51 @@ func (t Metadata) Announce() string {
 	return t.Trackers[rand.IntN(max)]
 }
 
+// AnnounceList returns the tiers of the trackers, trackers absent from Tiers
+// are placed into a final tier. duplicate trackers are removed.
+func (t Metadata) AnnounceList() (tiers metainfo.AnnounceList) {
+	remaining := make(map[string]struct{}, len(t.Trackers))
+	for _, uri := range t.Trackers {
+		remaining[uri] = struct{}{}
+	}
+
+	take := func(uris ...string) (tier []string) {
+		for _, uri := range uris {
+			if _, ok := remaining[uri]; !ok {
+				continue
+			}
+
+			delete(remaining, uri)
+			tier = append(tier, uri)
+		}
+
+		return tier
+	}
+
+	for _, tier := range append(slices.Clone(t.Tiers), t.Trackers) {
+		if tier = take(tier...); len(tier) > 0 {
+			tiers = append(tiers, tier)
+		}
+	}
+
+	return tiers
+}
+
+// Swarms returns the infohashes of the swarms the torrent participates in.
+// Hybrid torrents are members of both the v1 swarm and the v2 swarm, which is
+// identified by the truncated v2 infohash.
+func (t Metadata) Swarms() []int160.T {
+	if t.IDv2.IsZero() {
+		return []int160.T{t.ID}
+	}
+
+	if v2 := int160.FromByteArray(t.IDv2.Truncate()); v2 != t.ID {
+		return []int160.T{t.ID, v2}
+	}
+
+	return []int160.T{t.ID}
+}
+
 // Merge Metadata options into the current metadata.
 func (t Metadata) Merge(options ...Option) Metadata {
 	for _, opt := range options {
@@ -133,6 +249,25 @@ func New(info metainfo.Hash, options ...Option) (t Metadata, err error) {
 	return t, nil
 }
 
+// NewV2 create a torrent from just a v2 infohash and any additional options.
+func NewV2(info metainfo.HashV2, options ...Option) (t Metadata, err error) {
+	return New(info.Truncate(), append([]Option{OptionIDv2(info)}, options...)...)
+}
+
+// computes the identity of the torrent from its info.
+func newFromEncodedInfo(info *metainfo.Info, encoded []byte, options ...Option) (t Metadata, err error) {
+	if !info.IsV2() {
+		return New(metainfo.NewHashFromBytes(encoded), options...)
+	}
+
+	options = append([]Option{OptionPieceLayers(info.PieceLayers)}, options...)
+	if !info.HasV1() {
+		return NewV2(metainfo.NewHashV2FromBytes(encoded), options...)
+	}
+
+	return New(metainfo.NewHashFromBytes(encoded), append(options, OptionIDv2(metainfo.NewHashV2FromBytes(encoded)))...)
+}
+
 // NewFromMetaInfoFile loads torrent info stored in a file.
 func NewFromInfoFile(path string, options ...Option) (t Metadata, err error) {
 	src, err := os.Open(path)
@@ -193,7 +328,7 @@ func NewFromFile(path string, options ...Option) (t Metadata, err error) {
 		return t, errorsx.WithStack(err)
 	}
 
-	if t, err = New(metainfo.NewHashFromBytes(encoded), OptionInfo(encoded), OptionDisplayName(info.Name)); err != nil {
+	if t, err = newFromEncodedInfo(info, encoded, OptionInfo(encoded), OptionDisplayName(info.Name)); err != nil {
 		return t, errorsx.WithStack(err)
 	}
 
@@ -210,8 +345,9 @@ func NewFromInfo(i *metainfo.Info, options ...Option) (t Metadata, err error) {
 		return t, err
 	}
 
-	return New(
-		metainfo.NewHashFromBytes(encoded),
+	return newFromEncodedInfo(
+		i,
+		encoded,
 		append(options, OptionInfo(encoded), OptionDisplayName(i.Name))...,
 	)
 }
@@ -230,10 +366,21 @@ func NewFromMagnet(uri string, options ...Option) (t Metadata, err error) {
 		OptionDisplayName(m.DisplayName),
 		OptionTrackers(m.Trackers...),
 		OptionWebseeds(m.Params["ws"]),
+		OptionPeers(m.Peers...),
+		OptionSources(append(slices.Clone(m.Sources), m.Fallbacks...)...),
+		OptionSelectOnlyRanges(m.SelectOnly...),
 	},
 		options...,
 	)
 
+	if m.InfoHash == (metainfo.Hash{}) {
+		return NewV2(m.InfoHashV2, options...)
+	}
+
+	if !m.InfoHashV2.IsZero() {
+		options = append(options, OptionIDv2(m.InfoHashV2))
+	}
+
 	return New(
 		m.InfoHash,
 		options...,
@@ -250,22 +397,20 @@ func NewFromMetaInfo(mi *metainfo.MetaInfo, options ...Option) (t Metadata, err
 		return t, err
 	}
 
-	trackers := make([]string, 0, 128)
-	for _, add := range mi.UpvertedAnnounceList() {
-		trackers = append(trackers, add...)
-	}
+	info.PieceLayers = mi.PieceLayers
 	options = append([]Option{
 		OptionInfo(mi.InfoBytes),
 		OptionDisplayName(info.Name),
-		OptionTrackers(trackers...),
+		OptionTiers(mi.UpvertedAnnounceList()...),
 		OptionWebseeds(mi.UrlList),
 		OptionNodes(mi.NodeList()...),
 	},
 		options...,
 	)
 
-	return New(
-		mi.HashInfoBytes(),
+	return newFromEncodedInfo(
+		&info,
+		mi.InfoBytes,
 		options...,
 	)
 }
@@ -274,15 +419,31 @@ func NewFromMetaInfo(mi *metainfo.MetaInfo, options ...Option) (t Metadata, err
 func (t Metadata) Metainfo() metainfo.MetaInfo {
 	return metainfo.MetaInfo{
 		InfoBytes:    t.InfoBytes,
+		PieceLayers:  t.PieceLayers,
 		CreationDate: time.Now().Unix(),
-		AnnounceList: metainfo.AnnounceList([][]string{t.Trackers}),
+		AnnounceList: t.AnnounceList(),
 	}
 }
 
 func NewMagnet(md Metadata) metainfo.Magnet {
-	return metainfo.Magnet{
+	m := metainfo.Magnet{
 		DisplayName: md.DisplayName,
 		InfoHash:    md.ID.AsByteArray(),
+		InfoHashV2:  md.IDv2,
 		Trackers:    md.Trackers,
+		Peers:       md.Peers,
+		Sources:     md.Sources,
+		SelectOnly:  md.SelectOnly,
+	}
+
+	if len(md.Webseeds) > 0 {
+		m.Params = url.Values{"ws": md.Webseeds}
+	}
+
+	// v2 only torrents have no v1 info hash.
+	if !md.IDv2.IsZero() && m.InfoHash == md.IDv2.Truncate() {
+		m.InfoHash = metainfo.Hash{}
 	}
+
+	return m
 }
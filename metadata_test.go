@@ -1,12 +1,16 @@
 package torrent_test
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/james-lawrence/torrent"
 	"github.com/james-lawrence/torrent/dht/int160"
 	"github.com/james-lawrence/torrent/internal/bytesx"
 	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/testutil"
 	"github.com/james-lawrence/torrent/metainfo"
 	"github.com/stretchr/testify/require"
 )
@@ -49,4 +53,66 @@ func TestMetadata(t *testing.T) {
 		require.Len(t, md.DHTNodes, 0)
 		require.EqualValues(t, 16*bytesx.KiB, md.ChunkSize)
 	})
+
+	t.Run("NewFromInfo v2", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(root, "example.bin"), make([]byte, 100*bytesx.KiB), 0600))
+		info := testutil.InfoV2(t, root, 32*bytesx.KiB)
+
+		md, err := torrent.NewFromInfo(info)
+		require.NoError(t, err)
+
+		require.Equal(t, metainfo.NewHashV2FromBytes(md.InfoBytes), md.IDv2)
+		require.Equal(t, int160.FromByteArray(md.IDv2.Truncate()), md.ID)
+		require.Equal(t, info.PieceLayers, md.PieceLayers)
+
+		encoded, err := metainfo.Encode(md.Metainfo())
+		require.NoError(t, err)
+		mi, err := metainfo.Load(bytes.NewReader(encoded))
+		require.NoError(t, err)
+		persisted, err := torrent.NewFromMetaInfo(mi)
+		require.NoError(t, err)
+		require.Equal(t, md.ID, persisted.ID)
+		require.Equal(t, md.IDv2, persisted.IDv2)
+		require.Equal(t, md.PieceLayers, persisted.PieceLayers)
+
+		magnet, err := torrent.NewFromMagnet(torrent.NewMagnet(md).String())
+		require.NoError(t, err)
+		require.Equal(t, md.ID, magnet.ID)
+		require.Equal(t, md.IDv2, magnet.IDv2)
+	})
+
+	t.Run("tracker tiers are persisted", func(t *testing.T) {
+		info, err := testutil.GreetingMetaInfo().UnmarshalInfo()
+		require.NoError(t, err)
+
+		md, err := torrent.NewFromInfo(
+			&info,
+			torrent.OptionTiers([]string{"udp://a", "udp://b"}, []string{"udp://c"}),
+			torrent.OptionTrackers("udp://d", "udp://a"),
+		)
+		require.NoError(t, err)
+		require.Equal(t, metainfo.AnnounceList{{"udp://a", "udp://b"}, {"udp://c"}, {"udp://d"}}, md.AnnounceList())
+
+		encoded, err := metainfo.Encode(md.Metainfo())
+		require.NoError(t, err)
+		mi, err := metainfo.Load(bytes.NewReader(encoded))
+		require.NoError(t, err)
+		persisted, err := torrent.NewFromMetaInfo(mi)
+		require.NoError(t, err)
+		require.Equal(t, md.AnnounceList(), persisted.AnnounceList())
+	})
+	t.Run("NewFromMagnet extensions", func(t *testing.T) {
+		md, err := torrent.NewFromMagnet("magnet:?xt=urn:btih:631a31dd0a46257d5078c0dee4e66e26f73e42ac" +
+			"&x.pe=10.0.0.1:6881&so=1,3-4&xs=http://a.example.com/x.torrent&as=http://b.example.com/x.torrent&ws=http://c.example.com/")
+		require.NoError(t, err)
+		require.Equal(t, []string{"10.0.0.1:6881"}, md.Peers)
+		require.Equal(t, []metainfo.FileRange{{First: 1, Last: 1}, {First: 3, Last: 4}}, md.SelectOnly)
+		require.Equal(t, []string{"http://a.example.com/x.torrent", "http://b.example.com/x.torrent"}, md.Sources)
+		require.Equal(t, []string{"http://c.example.com/"}, md.Webseeds)
+
+		rt, err := torrent.NewFromMagnet(torrent.NewMagnet(md).String())
+		require.NoError(t, err)
+		require.Equal(t, md, rt)
+	})
 }
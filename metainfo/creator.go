@@ -0,0 +1,506 @@
+package metainfo
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/internal/langx"
+)
+
+const (
+	minPieceLength = 16 * bytesx.KiB
+	maxPieceLength = 16 * bytesx.MiB
+	// number of pieces PieceLengthFor aims for.
+	targetPieces = 1500
+)
+
+// PieceLengthFor picks the piece length for a torrent of the given total length. the
+// length is the smallest power of two between 16KiB and 16MiB that keeps the number
+// of pieces near 1500.
+func PieceLengthFor(total int64) int64 {
+	length := int64(minPieceLength)
+	for length < maxPieceLength && total/length > targetPieces {
+		length <<= 1
+	}
+
+	return length
+}
+
+// CreatorOption configures the creation of torrents.
+type CreatorOption func(*Creator)
+
+// CreatorOptionPieceLength set the piece length, by default it is chosen from the
+// total length of the torrent. see PieceLengthFor.
+func CreatorOptionPieceLength(n int64) CreatorOption {
+	return func(c *Creator) {
+		c.pieceLength = n
+	}
+}
+
+// CreatorOptionConcurrency set the number of pieces hashed in parallel, defaults to the number of cpus.
+func CreatorOptionConcurrency(n int) CreatorOption {
+	return func(c *Creator) {
+		c.concurrency = n
+	}
+}
+
+// CreatorOptionProgress receive the number of bytes hashed so far and the total number
+// of bytes to hash. hybrid torrents hash their data twice. the function is called
+// concurrently.
+func CreatorOptionProgress(fn func(hashed, total int64)) CreatorOption {
+	return func(c *Creator) {
+		c.progress = fn
+	}
+}
+
+// CreatorOptionFilter only include the files and directories the filter returns
+// true for. the path is relative to the root.
+func CreatorOptionFilter(fn func(path string, d fs.DirEntry) bool) CreatorOption {
+	return func(c *Creator) {
+		c.filter = fn
+	}
+}
+
+// CreatorOptionHidden include hidden files and directories, they're skipped by default.
+func CreatorOptionHidden(include bool) CreatorOption {
+	return func(c *Creator) {
+		c.hidden = include
+	}
+}
+
+// CreatorOptionInfo apply the options to the info, e.g. OptionDisplayName or OptionHybrid.
+func CreatorOptionInfo(options ...Option) CreatorOption {
+	return func(c *Creator) {
+		c.info = append(c.info, options...)
+	}
+}
+
+// CreatorOptionAnnounceList add the tiers of trackers to the torrent, see BEP 12.
+func CreatorOptionAnnounceList(tiers ...[]string) CreatorOption {
+	return func(c *Creator) {
+		c.announce = append(c.announce, tiers...)
+	}
+}
+
+// CreatorOptionUrlList add webseeds to the torrent, see BEP 19.
+func CreatorOptionUrlList(urls ...string) CreatorOption {
+	return func(c *Creator) {
+		c.urls = append(c.urls, urls...)
+	}
+}
+
+// CreatorOptionNodes add DHT nodes, in host:port form, to the torrent.
+func CreatorOptionNodes(nodes ...string) CreatorOption {
+	return func(c *Creator) {
+		for _, n := range nodes {
+			c.nodes = append(c.nodes, Node(n))
+		}
+	}
+}
+
+// CreatorOptionComment set the comment of the torrent.
+func CreatorOptionComment(s string) CreatorOption {
+	return func(c *Creator) {
+		c.comment = s
+	}
+}
+
+// CreatorOptionCreatedBy set the program that created the torrent.
+func CreatorOptionCreatedBy(s string) CreatorOption {
+	return func(c *Creator) {
+		c.createdBy = s
+	}
+}
+
+// CreatorOptionSource set the source of the info, private trackers use it to
+// give torrents a unique info hash.
+func CreatorOptionSource(s string) CreatorOption {
+	return func(c *Creator) {
+		c.source = s
+	}
+}
+
+// Creator generates torrents from files on disk, hashing pieces in parallel.
+type Creator struct {
+	pieceLength int64
+	concurrency int
+	progress    func(hashed, total int64)
+	filter      func(path string, d fs.DirEntry) bool
+	hidden      bool
+	info        []Option
+	announce    AnnounceList
+	urls        UrlList
+	nodes       []Node
+	comment     string
+	createdBy   string
+	source      string
+}
+
+// NewCreator for generating torrents.
+func NewCreator(options ...CreatorOption) Creator {
+	return langx.Clone(Creator{
+		concurrency: runtime.NumCPU(),
+		progress:    func(int64, int64) {},
+		filter:      func(string, fs.DirEntry) bool { return true },
+		createdBy:   "github.com/james-lawrence/torrent",
+	}, options...)
+}
+
+// Create the torrent for the file or directory at root. only regular files are
+// included, symlinks are ignored.
+func (c Creator) Create(ctx context.Context, root string) (mi *MetaInfo, err error) {
+	info := langx.Autoptr(langx.Clone(Info{
+		Name:   filepath.Base(root),
+		Source: c.source,
+	}, c.info...))
+
+	if err = c.walk(root, info); err != nil {
+		return nil, err
+	}
+
+	total := info.Length
+	for _, fi := range info.Files {
+		total += fi.Length
+	}
+	info.PieceLength = langx.DefaultIfZero(PieceLengthFor(total), c.pieceLength)
+
+	passes := int64(1)
+	if info.IsV2() {
+		passes = 2
+	}
+
+	hashed := int64(0)
+	progress := func(n int64) {
+		c.progress(atomic.AddInt64(&hashed, n), passes*total)
+	}
+
+	if info.IsV2() {
+		if err = c.fileTree(ctx, root, info, progress); err != nil {
+			return nil, err
+		}
+	}
+
+	if info.Pieces, err = c.pieces(ctx, root, info, progress); err != nil {
+		return nil, err
+	}
+
+	encoded, err := bencode.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	mi = &MetaInfo{
+		InfoBytes:    encoded,
+		AnnounceList: c.announce,
+		Nodes:        c.nodes,
+		CreationDate: time.Now().Unix(),
+		Comment:      c.comment,
+		CreatedBy:    c.createdBy,
+		UrlList:      c.urls,
+		PieceLayers:  info.PieceLayers,
+	}
+
+	if len(c.announce) > 0 && len(c.announce[0]) > 0 {
+		mi.Announce = c.announce[0][0]
+	}
+
+	return mi, nil
+}
+
+// populate the files of the info.
+func (c Creator) walk(root string, info *Info) error {
+	st, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	if !st.IsDir() {
+		info.Length = st.Size()
+		return nil
+	}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("error getting relative path: %w", err)
+		}
+
+		if (!c.hidden && strings.HasPrefix(d.Name(), ".")) || !c.filter(rel, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		info.Files = append(info.Files, FileInfo{
+			Path:   strings.Split(rel, string(filepath.Separator)),
+			Length: fi.Size(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(info.Files) == 0 {
+		return fmt.Errorf("no files found in %s", root)
+	}
+
+	sort.Slice(info.Files, func(i, j int) bool {
+		return strings.Join(info.Files[i].Path, "/") < strings.Join(info.Files[j].Path, "/")
+	})
+
+	return nil
+}
+
+// run the workers in parallel, each worker consumes indices until they're exhausted.
+// stops at the first error.
+func (c Creator) parallel(ctx context.Context, n int, worker func(indices <-chan int) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	indices := make(chan int)
+	wg := sync.WaitGroup{}
+	for range max(c.concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := worker(indices); err != nil {
+				cancel(err)
+			}
+		}()
+	}
+
+feed:
+	for idx := range n {
+		select {
+		case indices <- idx:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	return nil
+}
+
+// hash the v1 pieces.
+func (c Creator) pieces(ctx context.Context, root string, info *Info, progress func(int64)) ([]byte, error) {
+	layout := newFileLayout(root, info.UpvertedFiles())
+	n := (layout.length + info.PieceLength - 1) / info.PieceLength
+	pieces := make([]byte, n*sha1.Size)
+
+	err := c.parallel(ctx, int(n), func(indices <-chan int) error {
+		r := layout.reader()
+		defer r.Close()
+
+		buf := make([]byte, info.PieceLength)
+		for idx := range indices {
+			offset := int64(idx) * info.PieceLength
+			piece := buf[:min(info.PieceLength, layout.length-offset)]
+			read, err := r.ReadAt(piece, offset)
+			if err != nil {
+				return err
+			}
+
+			digest := sha1.Sum(piece)
+			copy(pieces[idx*sha1.Size:], digest[:])
+			progress(read)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pieces, nil
+}
+
+// hash the v2 file tree and piece layers, each file is hashed in parallel.
+func (c Creator) fileTree(ctx context.Context, root string, info *Info, progress func(int64)) error {
+	files := info.Files
+	if len(files) == 0 {
+		files = []FileInfo{{Length: info.Length}}
+	}
+
+	computed := make([]FileTreeFile, len(files))
+	layers := make([][]HashV2, len(files))
+
+	err := c.parallel(ctx, len(files), func(indices <-chan int) error {
+		for idx := range indices {
+			if err := c.hashFile(filepath.Join(append([]string{root}, files[idx].Path...)...), files[idx].Length, info.PieceLength, &computed[idx], &layers[idx]); err != nil {
+				return err
+			}
+
+			progress(files[idx].Length)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	info.PieceLayers = make(PieceLayers)
+	for idx, f := range computed {
+		if len(layers[idx]) > 0 {
+			info.PieceLayers.Set(f.Root(), layers[idx])
+		}
+
+		path := files[idx].Path
+		if len(info.Files) == 0 {
+			path = []string{info.Name}
+		}
+
+		info.FileTree.Insert(path, FileTree{File: &f})
+	}
+
+	if len(info.Files) > 0 {
+		info.Files = info.upvertedFilesV2()
+	}
+
+	return nil
+}
+
+func (c Creator) hashFile(path string, length int64, pieceLength int64, f *FileTreeFile, layer *[]HashV2) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	*f, *layer, err = ComputeFileV2(io.LimitReader(src, length), pieceLength)
+	return err
+}
+
+// the files of the torrent laid out contiguously, padding files read as zeros.
+type fileLayout struct {
+	paths   []string
+	offsets []int64
+	files   []FileInfo
+	length  int64
+}
+
+func newFileLayout(root string, files []FileInfo) fileLayout {
+	l := fileLayout{
+		paths:   make([]string, len(files)),
+		offsets: make([]int64, len(files)),
+		files:   files,
+	}
+
+	for i, fi := range files {
+		l.paths[i] = filepath.Join(append([]string{root}, fi.Path...)...)
+		l.offsets[i] = l.length
+		l.length += fi.Length
+	}
+
+	return l
+}
+
+func (l fileLayout) reader() *layoutReader {
+	return &layoutReader{fileLayout: l, current: -1}
+}
+
+// reads regions of the layout, retaining the most recently opened file.
+type layoutReader struct {
+	fileLayout
+	current int
+	f       *os.File
+}
+
+func (r *layoutReader) open(idx int) (*os.File, error) {
+	if r.current == idx {
+		return r.f, nil
+	}
+
+	r.Close()
+
+	f, err := os.Open(r.paths[idx])
+	if err != nil {
+		return nil, err
+	}
+
+	r.current, r.f = idx, f
+	return f, nil
+}
+
+// fill dst with the data at the offset, returns the number of bytes read from
+// files, i.e. excluding padding.
+func (r *layoutReader) ReadAt(dst []byte, offset int64) (read int64, err error) {
+	idx := sort.Search(len(r.offsets), func(i int) bool { return r.offsets[i]+r.files[i].Length > offset })
+	for ; len(dst) > 0 && idx < len(r.files); idx++ {
+		fi := r.files[idx]
+		rel := offset - r.offsets[idx]
+		n := min(int64(len(dst)), fi.Length-rel)
+
+		if fi.IsPadding() {
+			clear(dst[:n])
+		} else {
+			f, err := r.open(idx)
+			if err != nil {
+				return read, err
+			}
+
+			if _, err = f.ReadAt(dst[:n], rel); err != nil {
+				return read, fmt.Errorf("error reading %s: %w", r.paths[idx], err)
+			}
+			read += n
+		}
+
+		dst, offset = dst[n:], offset+n
+	}
+
+	if len(dst) > 0 {
+		return read, io.ErrUnexpectedEOF
+	}
+
+	return read, nil
+}
+
+func (r *layoutReader) Close() error {
+	if r.f == nil {
+		return nil
+	}
+
+	err := r.f.Close()
+	r.current, r.f = -1, nil
+	return err
+}
@@ -0,0 +1,159 @@
+package metainfo
+
+import (
+	"context"
+	"crypto/rand"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+)
+
+func TestCreator(t *testing.T) {
+	write := func(t *testing.T, path string, n int) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		data := make([]byte, n)
+		_, err := rand.Read(data)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0600))
+	}
+
+	tree := func(t *testing.T) string {
+		root := filepath.Join(t.TempDir(), "example")
+		write(t, filepath.Join(root, "a.bin"), 40*bytesx.KiB+7)
+		write(t, filepath.Join(root, "b", "c.bin"), 3*bytesx.KiB)
+		write(t, filepath.Join(root, "b", "d.bin"), 100*bytesx.KiB)
+		write(t, filepath.Join(root, "e.bin"), 0)
+		return root
+	}
+
+	decode := func(t *testing.T, mi *MetaInfo) Info {
+		info, err := mi.UnmarshalInfo()
+		require.NoError(t, err)
+		return info
+	}
+
+	t.Run("matches NewFromPath", func(t *testing.T) {
+		root := tree(t)
+		for _, options := range [][]Option{nil, {OptionHybrid}} {
+			expected, err := NewFromPath(root, append(options, OptionPieceLength(16*bytesx.KiB))...)
+			require.NoError(t, err)
+			encoded, err := bencode.Marshal(expected)
+			require.NoError(t, err)
+
+			mi, err := NewCreator(CreatorOptionConcurrency(3), CreatorOptionInfo(options...)).Create(context.Background(), root)
+			require.NoError(t, err)
+			require.Equal(t, string(encoded), string(mi.InfoBytes))
+			require.Equal(t, expected.PieceLayers, mi.PieceLayers)
+		}
+	})
+
+	t.Run("single file", func(t *testing.T) {
+		root := tree(t)
+		path := filepath.Join(root, "b", "d.bin")
+		for _, options := range [][]Option{nil, {OptionHybrid}} {
+			expected, err := NewFromPath(path, append(options, OptionPieceLength(16*bytesx.KiB))...)
+			require.NoError(t, err)
+			encoded, err := bencode.Marshal(expected)
+			require.NoError(t, err)
+
+			mi, err := NewCreator(CreatorOptionInfo(options...)).Create(context.Background(), path)
+			require.NoError(t, err)
+			require.Equal(t, string(encoded), string(mi.InfoBytes))
+		}
+	})
+
+	t.Run("reports progress", func(t *testing.T) {
+		var (
+			mu     sync.Mutex
+			hashed int64
+			total  int64
+		)
+
+		root := tree(t)
+		_, err := NewCreator(CreatorOptionInfo(OptionHybrid), CreatorOptionProgress(func(n, t int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			hashed, total = max(hashed, n), t
+		})).Create(context.Background(), root)
+		require.NoError(t, err)
+		require.EqualValues(t, 2*(143*bytesx.KiB+7), total)
+		require.Equal(t, total, hashed)
+	})
+
+	t.Run("metadata is attached", func(t *testing.T) {
+		mi, err := NewCreator(
+			CreatorOptionAnnounceList([]string{"udp://a", "udp://b"}, []string{"udp://c"}),
+			CreatorOptionUrlList("http://example.com/"),
+			CreatorOptionNodes("10.0.0.1:6881"),
+			CreatorOptionComment("comment"),
+			CreatorOptionCreatedBy("tests"),
+			CreatorOptionSource("source"),
+			CreatorOptionPieceLength(32*bytesx.KiB),
+		).Create(context.Background(), tree(t))
+		require.NoError(t, err)
+
+		require.Equal(t, "udp://a", mi.Announce)
+		require.Equal(t, AnnounceList{{"udp://a", "udp://b"}, {"udp://c"}}, mi.AnnounceList)
+		require.Equal(t, UrlList{"http://example.com/"}, mi.UrlList)
+		require.Equal(t, []string{"10.0.0.1:6881"}, mi.NodeList())
+		require.Equal(t, "comment", mi.Comment)
+		require.Equal(t, "tests", mi.CreatedBy)
+		require.NotZero(t, mi.CreationDate)
+
+		info := decode(t, mi)
+		require.Equal(t, "source", info.Source)
+		require.Equal(t, "example", info.Name)
+		require.EqualValues(t, 32*bytesx.KiB, info.PieceLength)
+	})
+
+	t.Run("hidden and filtered files are skipped", func(t *testing.T) {
+		root := tree(t)
+		write(t, filepath.Join(root, ".hidden"), 10)
+		write(t, filepath.Join(root, ".git", "config"), 10)
+		write(t, filepath.Join(root, "b", "skipped.tmp"), 10)
+
+		filter := CreatorOptionFilter(func(path string, d fs.DirEntry) bool {
+			return filepath.Ext(path) != ".tmp"
+		})
+
+		paths := func(info Info) (paths []string) {
+			for _, fi := range info.Files {
+				paths = append(paths, filepath.Join(fi.Path...))
+			}
+			return paths
+		}
+
+		mi, err := NewCreator(filter).Create(context.Background(), root)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a.bin", "b/c.bin", "b/d.bin", "e.bin"}, paths(decode(t, mi)))
+
+		mi, err = NewCreator(filter, CreatorOptionHidden(true)).Create(context.Background(), root)
+		require.NoError(t, err)
+		require.Equal(t, []string{".git/config", ".hidden", "a.bin", "b/c.bin", "b/d.bin", "e.bin"}, paths(decode(t, mi)))
+
+		_, err = NewCreator(CreatorOptionFilter(func(string, fs.DirEntry) bool { return false })).Create(context.Background(), root)
+		require.Error(t, err)
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		ctx, done := context.WithCancel(context.Background())
+		done()
+		_, err := NewCreator().Create(ctx, tree(t))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("piece length", func(t *testing.T) {
+		require.EqualValues(t, 16*bytesx.KiB, PieceLengthFor(0))
+		require.EqualValues(t, 16*bytesx.KiB, PieceLengthFor(1500*16*bytesx.KiB))
+		require.EqualValues(t, 32*bytesx.KiB, PieceLengthFor(1500*16*bytesx.KiB+16*bytesx.KiB))
+		require.EqualValues(t, 1*bytesx.MiB, PieceLengthFor(1*bytesx.GiB))
+		require.EqualValues(t, 16*bytesx.MiB, PieceLengthFor(4*1024*bytesx.GiB))
+	})
+}
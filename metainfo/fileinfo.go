@@ -1,6 +1,7 @@
 package metainfo
 
 import (
+	"strconv"
 	"strings"
 )
 
@@ -15,6 +16,7 @@ type FileInfo struct {
 	Length   int64    `bencode:"length"`
 	Path     []string `bencode:"path"`
 	PathUTF8 []string `bencode:"path.utf-8,omitempty"`
+	Attr     string   `bencode:"attr,omitempty"` // BEP 47 file attributes.
 }
 
 // DisplayPath ...
@@ -37,3 +39,33 @@ func (fi FileInfo) Offset(info *Info) (ret int64) {
 	}
 	panic("not found")
 }
+
+// NewPaddingFile creates a BEP 47 padding file of the given length.
+func NewPaddingFile(n int64) FileInfo {
+	return FileInfo{
+		Length: n,
+		Path:   []string{".pad", strconv.FormatInt(n, 10)},
+		Attr:   "p",
+	}
+}
+
+// IsPadding reports if the file is a BEP 47 padding file.
+func (fi FileInfo) IsPadding() bool {
+	return strings.ContainsRune(fi.Attr, 'p')
+}
+
+// FileV2 is a file from the BEP 52 file tree.
+type FileV2 struct {
+	FileTreeFile
+	Path   []string
+	Offset int64 // offset of the file within the torrent, always piece aligned.
+}
+
+// the amount of padding required to align the length to a piece boundary.
+func padding(length, plength int64) int64 {
+	if plength == 0 || length%plength == 0 {
+		return 0
+	}
+
+	return plength - length%plength
+}
@@ -0,0 +1,110 @@
+package metainfo
+
+import (
+	"iter"
+	"maps"
+	"slices"
+
+	"github.com/james-lawrence/torrent/bencode"
+)
+
+// FileTree is the BEP 52 representation of the files within a torrent. Each
+// directory is a dictionary keyed by path component, files are stored
+// under the empty key.
+type FileTree struct {
+	File *FileTreeFile
+	Dir  map[string]FileTree
+}
+
+// FileTreeFile is a leaf of the FileTree.
+type FileTreeFile struct {
+	Length     int64  `bencode:"length"`
+	PiecesRoot []byte `bencode:"pieces root,omitempty"`
+}
+
+// Root of the file's merkle tree, zero for empty files.
+func (t FileTreeFile) Root() (h HashV2) {
+	copy(h[:], t.PiecesRoot)
+	return h
+}
+
+func (t FileTree) MarshalBencode() ([]byte, error) {
+	if t.File != nil {
+		return bencode.Marshal(map[string]FileTreeFile{"": *t.File})
+	}
+
+	if t.Dir == nil {
+		return []byte("de"), nil
+	}
+
+	return bencode.Marshal(t.Dir)
+}
+
+func (t *FileTree) UnmarshalBencode(b []byte) (err error) {
+	var (
+		encoded map[string]bencode.Bytes
+	)
+
+	if err = bencode.Unmarshal(b, &encoded); err != nil {
+		return err
+	}
+
+	for k, v := range encoded {
+		if k == "" {
+			var f FileTreeFile
+			if err = bencode.Unmarshal(v, &f); err != nil {
+				return err
+			}
+			t.File = &f
+			continue
+		}
+
+		var sub FileTree
+		if err = bencode.Unmarshal(v, &sub); err != nil {
+			return err
+		}
+
+		t.Insert([]string{k}, sub)
+	}
+
+	return nil
+}
+
+// Insert the subtree at the given path.
+func (t *FileTree) Insert(path []string, sub FileTree) {
+	if len(path) == 0 {
+		*t = sub
+		return
+	}
+
+	if t.Dir == nil {
+		t.Dir = make(map[string]FileTree)
+	}
+
+	child := t.Dir[path[0]]
+	child.Insert(path[1:], sub)
+	t.Dir[path[0]] = child
+}
+
+// Walk the files of the tree depth first in path order, which is the order
+// their data is laid out within the torrent.
+func (t FileTree) Walk() iter.Seq2[[]string, FileTreeFile] {
+	var walk func(prefix []string, n FileTree, yield func([]string, FileTreeFile) bool) bool
+	walk = func(prefix []string, n FileTree, yield func([]string, FileTreeFile) bool) bool {
+		if n.File != nil {
+			return yield(prefix, *n.File)
+		}
+
+		for _, k := range slices.Sorted(maps.Keys(n.Dir)) {
+			if !walk(append(prefix[:len(prefix):len(prefix)], k), n.Dir[k], yield) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return func(yield func([]string, FileTreeFile) bool) {
+		walk(nil, t, yield)
+	}
+}
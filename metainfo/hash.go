@@ -2,6 +2,7 @@ package metainfo
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 )
@@ -50,3 +51,42 @@ func NewHashFromBytes(b []byte) (ret Hash) {
 	copy(ret[:], hasher.Sum(nil))
 	return
 }
+
+const HashV2Size = 32
+
+// 32-byte SHA256 hash used for v2 info hashes and merkle roots (BEP 52).
+type HashV2 [HashV2Size]byte
+
+func (h HashV2) Bytes() []byte {
+	return h[:]
+}
+
+func (h HashV2) String() string {
+	return fmt.Sprintf("%x", h[:])
+}
+
+func (h HashV2) IsZero() bool {
+	return h == HashV2{}
+}
+
+// Truncate the hash to the 20 bytes used by the handshake, trackers and the DHT.
+func (h HashV2) Truncate() (ret Hash) {
+	copy(ret[:], h[:HashSize])
+	return ret
+}
+
+func NewHashV2FromHex(s string) (h HashV2) {
+	if len(s) != 2*HashV2Size {
+		panic(fmt.Errorf("hash hex string has bad length: %d", len(s)))
+	}
+
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		panic(err)
+	}
+
+	return h
+}
+
+func NewHashV2FromBytes(b []byte) HashV2 {
+	return sha256.Sum256(b)
+}
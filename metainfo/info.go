@@ -33,6 +33,12 @@ func OptionDisplayName(s string) Option {
 	}
 }
 
+// OptionHybrid generates hybrid torrents, which contain both the v1 pieces and
+// the v2 (BEP 52) file tree and piece layers. Supported by NewFromReader and NewFromPath.
+func OptionHybrid(i *Info) {
+	i.MetaVersion = 2
+}
+
 func NewFromReader(src io.Reader, options ...Option) (info *Info, err error) {
 	info = langx.Autoptr(langx.Clone(Info{
 		PieceLength: bytesx.MiB,
@@ -42,7 +48,15 @@ func NewFromReader(src io.Reader, options ...Option) (info *Info, err error) {
 	length := readlength(0)
 	wrapped := io.TeeReader(src, digest)
 	wrapped = io.TeeReader(wrapped, &length)
-	if info.Pieces, err = ComputePieces(wrapped, info.PieceLength); err != nil {
+
+	var v2 *FileTreeFile
+	if !info.IsV2() {
+		info.Pieces, err = ComputePieces(wrapped, info.PieceLength)
+	} else {
+		v2, err = info.computeHybrid(wrapped)
+	}
+
+	if err != nil {
 		return nil, err
 	}
 
@@ -51,9 +65,49 @@ func NewFromReader(src io.Reader, options ...Option) (info *Info, err error) {
 		info.Name = hex.EncodeToString(digest.Sum(nil))
 	}
 
+	if v2 != nil {
+		info.FileTree.Insert([]string{info.Name}, FileTree{File: v2})
+	}
+
 	return info, nil
 }
 
+// computes the v1 pieces along with the v2 file and piece layers of a single
+// file in one pass over the data.
+func (info *Info) computeHybrid(src io.Reader) (_ *FileTreeFile, err error) {
+	type computed struct {
+		f     FileTreeFile
+		layer []HashV2
+		err   error
+	}
+
+	pr, pw := io.Pipe()
+	v2 := make(chan computed, 1)
+	go func() {
+		f, layer, err := ComputeFileV2(pr, info.PieceLength)
+		pr.CloseWithError(err)
+		v2 <- computed{f: f, layer: layer, err: err}
+	}()
+
+	info.Pieces, err = ComputePieces(io.TeeReader(src, pw), info.PieceLength)
+	pw.CloseWithError(err)
+	result := <-v2
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	info.PieceLayers = make(PieceLayers)
+	if len(result.layer) > 0 {
+		info.PieceLayers.Set(result.f.Root(), result.layer)
+	}
+
+	return &result.f, nil
+}
+
 func NewInfo(options ...Option) *Info {
 	return langx.Autoptr(langx.Clone(Info{
 		PieceLength: bytesx.MiB,
@@ -101,9 +155,15 @@ func NewFromPath(root string, options ...Option) (info *Info, err error) {
 		return nil, err
 	}
 
-	slices.Sort(info.Files, func(l, r FileInfo) bool {
-		return strings.Join(l.Path, "/") < strings.Join(r.Path, "/")
-	})
+	if info.IsV2() {
+		if err = info.generateFileTree(root); err != nil {
+			return nil, fmt.Errorf("error generating file tree: %s", err)
+		}
+	} else {
+		slices.Sort(info.Files, func(l, r FileInfo) bool {
+			return strings.Join(l.Path, "/") < strings.Join(r.Path, "/")
+		})
+	}
 
 	err = info.GeneratePieces(func(fi FileInfo) (io.ReadCloser, error) {
 		return os.Open(filepath.Join(root, strings.Join(fi.Path, string(filepath.Separator))))
@@ -115,6 +175,55 @@ func NewFromPath(root string, options ...Option) (info *Info, err error) {
 	return info, err
 }
 
+// generates the file tree and piece layers of a hybrid torrent. the v1 files are
+// laid out in the order of the file tree with padding files aligning each file
+// to a piece boundary.
+func (info *Info) generateFileTree(root string) (err error) {
+	compute := func(path string) (f FileTreeFile, err error) {
+		src, err := os.Open(path)
+		if err != nil {
+			return f, err
+		}
+		defer src.Close()
+
+		f, layer, err := ComputeFileV2(src, info.PieceLength)
+		if err != nil {
+			return f, err
+		}
+
+		if len(layer) > 0 {
+			info.PieceLayers.Set(f.Root(), layer)
+		}
+
+		return f, nil
+	}
+
+	info.PieceLayers = make(PieceLayers)
+
+	if len(info.Files) == 0 {
+		f, err := compute(root)
+		if err != nil {
+			return err
+		}
+
+		info.FileTree.Insert([]string{info.Name}, FileTree{File: &f})
+		return nil
+	}
+
+	for _, fi := range info.Files {
+		f, err := compute(filepath.Join(root, filepath.Join(fi.Path...)))
+		if err != nil {
+			return err
+		}
+
+		info.FileTree.Insert(fi.Path, FileTree{File: &f})
+	}
+
+	info.Files = info.upvertedFilesV2()
+
+	return nil
+}
+
 // Compute the pieces from the given reader and block size
 func ComputePieces(src io.Reader, length int64) (pieces []byte, err error) {
 	if length == 0 {
@@ -144,20 +253,32 @@ func ComputePieces(src io.Reader, length int64) (pieces []byte, err error) {
 
 // Info dictionary.
 type Info struct {
-	PieceLength  int64      `bencode:"piece length"`
-	Pieces       []byte     `bencode:"pieces"`
-	Name         string     `bencode:"name"`
-	Length       int64      `bencode:"length,omitempty"`
-	Private      *bool      `bencode:"private,omitempty"` // pointer to handle backwards compatibility
-	Source       string     `bencode:"source,omitempty"`
-	Files        []FileInfo `bencode:"files,omitempty"`
-	cachedLength int64      // used to cache the total length of the torrent
+	PieceLength int64      `bencode:"piece length"`
+	Pieces      []byte     `bencode:"pieces"`
+	Name        string     `bencode:"name"`
+	Length      int64      `bencode:"length,omitempty"`
+	Private     *bool      `bencode:"private,omitempty"` // pointer to handle backwards compatibility
+	Source      string     `bencode:"source,omitempty"`
+	Files       []FileInfo `bencode:"files,omitempty"`
+	MetaVersion int64      `bencode:"meta version,omitempty"` // 2 for BEP 52 torrents.
+	FileTree    FileTree   `bencode:"file tree,omitempty"`
+	// PieceLayers of BEP 52 torrents, these are not part of the info dictionary
+	// and are only populated when the info was generated locally.
+	PieceLayers  PieceLayers `bencode:"-"`
+	cachedLength int64       // used to cache the total length of the torrent
 }
 
 // Concatenates all the files in the torrent into w. open is a function that
 // gets at the contents of the given file.
 func (info *Info) writeFiles(w io.Writer, open func(fi FileInfo) (io.ReadCloser, error)) error {
 	for _, fi := range info.UpvertedFiles() {
+		if fi.IsPadding() {
+			if _, err := io.CopyN(w, zeros{}, fi.Length); err != nil {
+				return fmt.Errorf("error padding %v: %s", fi, err)
+			}
+			continue
+		}
+
 		r, err := open(fi)
 		if err != nil {
 			return fmt.Errorf("error opening %v: %s", fi, err)
@@ -192,11 +313,7 @@ func (info *Info) TotalLength() (ret int64) {
 		atomic.StoreInt64(&info.cachedLength, ret)
 	}()
 
-	if !info.IsDir() {
-		return info.Length
-	}
-
-	for _, fi := range info.Files {
+	for _, fi := range info.UpvertedFiles() {
 		ret += fi.Length
 	}
 
@@ -204,17 +321,58 @@ func (info *Info) TotalLength() (ret int64) {
 }
 
 func (info *Info) NumPieces() uint64 {
-	return uint64(len(info.Pieces) / 20)
+	if info.HasV1() || info.PieceLength == 0 {
+		return uint64(len(info.Pieces) / 20)
+	}
+
+	return uint64((info.TotalLength() + info.PieceLength - 1) / info.PieceLength)
 }
 
 func (info *Info) IsDir() bool {
-	return len(info.Files) != 0
+	if info.HasV1() {
+		return len(info.Files) != 0
+	}
+
+	root, ok := info.FileTree.Dir[info.Name]
+	return !(ok && root.File != nil && len(info.FileTree.Dir) == 1)
+}
+
+// IsV2 reports if the info describes a BitTorrent v2 (BEP 52) torrent, which
+// includes hybrid torrents.
+func (info *Info) IsV2() bool {
+	return info.MetaVersion == 2
+}
+
+// HasV1 reports if the info contains the v1 pieces and files, i.e. it is
+// not a v2 only torrent.
+func (info *Info) HasV1() bool {
+	return !info.IsV2() || len(info.Pieces) > 0
+}
+
+// FilesV2 returns the files of the BEP 52 file tree along with their offsets
+// within the torrent. Files in v2 torrents are aligned to piece boundaries.
+func (info *Info) FilesV2() (files []FileV2) {
+	offset := int64(0)
+	for path, f := range info.FileTree.Walk() {
+		files = append(files, FileV2{
+			Path:         path,
+			Offset:       offset,
+			FileTreeFile: f,
+		})
+		offset += f.Length + padding(f.Length, info.PieceLength)
+	}
+
+	return files
 }
 
 // The files field, converted up from the old single-file in the parent info
 // dict if necessary. This is a helper to avoid having to conditionally handle
 // single and multi-file torrent infos.
 func (info *Info) UpvertedFiles() []FileInfo {
+	if !info.HasV1() {
+		return info.upvertedFilesV2()
+	}
+
 	if len(info.Files) == 0 {
 		return []FileInfo{{
 			Length: info.Length,
@@ -226,6 +384,30 @@ func (info *Info) UpvertedFiles() []FileInfo {
 	return info.Files
 }
 
+// converts the v2 file tree into the v1 layout, padding files are inserted to
+// keep each file aligned to a piece boundary.
+func (info *Info) upvertedFilesV2() (files []FileInfo) {
+	if !info.IsDir() {
+		return []FileInfo{{
+			Length: info.FileTree.Dir[info.Name].File.Length,
+		}}
+	}
+
+	v2 := info.FilesV2()
+	for i, f := range v2 {
+		files = append(files, FileInfo{
+			Length: f.Length,
+			Path:   f.Path[:len(f.Path):len(f.Path)],
+		})
+
+		if n := padding(f.Length, info.PieceLength); n > 0 && i < len(v2)-1 {
+			files = append(files, NewPaddingFile(n))
+		}
+	}
+
+	return files
+}
+
 func (info *Info) Piece(index int) Piece {
 	return Piece{info, pieceIndex(index)}
 }
@@ -261,6 +443,13 @@ func (info *Info) Hashes() (ret [][]byte) {
 
 type readlength uint64
 
+type zeros struct{}
+
+func (zeros) Read(b []byte) (int, error) {
+	clear(b)
+	return len(b), nil
+}
+
 func (t *readlength) Write(b []byte) (int, error) {
 	bn := len(b)
 	atomic.AddUint64((*uint64)(t), uint64(bn))
@@ -278,7 +467,11 @@ func Files(info *Info) iter.Seq[File] {
 			return
 		}
 
-		for _, fd := range info.Files {
+		for _, fd := range info.UpvertedFiles() {
+			if fd.IsPadding() {
+				continue
+			}
+
 			c := File{
 				Path:   fd.DisplayPath(info),
 				Offset: uint64(fd.Offset(info)),
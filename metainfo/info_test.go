@@ -1,7 +1,10 @@
 package metainfo
 
 import (
+	"bytes"
 	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -60,3 +63,199 @@ func TestOffsetToIndex(t *testing.T) {
 		require.Equal(t, int64(3), info.OffsetToIndex(4*bytesx.MiB))
 	})
 }
+
+func TestInfoV2(t *testing.T) {
+	const plength = 32 * bytesx.KiB
+	var (
+		layers = PieceLayers{}
+		info   = Info{
+			Name:        "root",
+			PieceLength: plength,
+			MetaVersion: 2,
+		}
+	)
+
+	for _, c := range []struct {
+		path   []string
+		length int64
+	}{
+		{[]string{"nested", "d"}, 70 * bytesx.KiB},
+		{[]string{"a"}, 100*bytesx.KiB + 1},
+		{[]string{"c"}, 0},
+		{[]string{"b"}, 10 * bytesx.KiB},
+	} {
+		f, layer, err := ComputeFileV2(io.LimitReader(cryptox.NewChaCha8(c.path[0]), c.length), plength)
+		require.NoError(t, err)
+		require.Equal(t, c.length, f.Length)
+		if len(layer) > 0 {
+			require.Equal(t, f.Root(), LayerRoot(plength, layer))
+			layers.Set(f.Root(), layer)
+		}
+		info.FileTree.Insert(c.path, FileTree{File: &f})
+	}
+
+	encoded, err := bencode.Marshal(info)
+	require.NoError(t, err)
+
+	var decoded Info
+	require.NoError(t, bencode.Unmarshal(encoded, &decoded))
+	require.Equal(t, info.FileTree, decoded.FileTree)
+
+	require.True(t, decoded.IsV2())
+	require.False(t, decoded.HasV1())
+	require.True(t, decoded.IsDir())
+	require.Equal(t, []FileInfo{
+		{Length: 100*bytesx.KiB + 1, Path: []string{"a"}},
+		NewPaddingFile(28*bytesx.KiB - 1),
+		{Length: 10 * bytesx.KiB, Path: []string{"b"}},
+		NewPaddingFile(22 * bytesx.KiB),
+		{Length: 0, Path: []string{"c"}},
+		{Length: 70 * bytesx.KiB, Path: []string{"nested", "d"}},
+	}, decoded.UpvertedFiles())
+	require.Equal(t, int64(230*bytesx.KiB), decoded.TotalLength())
+	require.Equal(t, uint64(8), decoded.NumPieces())
+
+	offsets := []int64{}
+	for _, f := range decoded.FilesV2() {
+		offsets = append(offsets, f.Offset)
+	}
+	require.Equal(t, []int64{0, 4 * plength, 5 * plength, 5 * plength}, offsets)
+
+	hashes, ok := layers.Layer(decoded.FilesV2()[0].Root())
+	require.True(t, ok)
+	require.Len(t, hashes, 4)
+	_, ok = layers.Layer(decoded.FilesV2()[1].Root())
+	require.False(t, ok, "single piece files have no layer")
+}
+
+func TestInfoV2SingleFile(t *testing.T) {
+	f, _, err := ComputeFileV2(io.LimitReader(cryptox.NewChaCha8(t.Name()), 40*bytesx.KiB), 16*bytesx.KiB)
+	require.NoError(t, err)
+
+	info := Info{
+		Name:        "single",
+		PieceLength: 16 * bytesx.KiB,
+		MetaVersion: 2,
+	}
+	info.FileTree.Insert([]string{"single"}, FileTree{File: &f})
+
+	require.False(t, info.IsDir())
+	require.Equal(t, []FileInfo{{Length: 40 * bytesx.KiB}}, info.UpvertedFiles())
+	require.Equal(t, uint64(3), info.NumPieces())
+}
+
+func TestNewFromPathHybrid(t *testing.T) {
+	const plength = 16 * bytesx.KiB
+
+	root := t.TempDir()
+	contents := map[string]int64{
+		"a":   20 * bytesx.KiB,
+		"b":   0,
+		"x-z": bytesx.KiB,
+		"x/y": 40 * bytesx.KiB,
+	}
+	for path, n := range contents {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0700))
+		data, err := io.ReadAll(io.LimitReader(cryptox.NewChaCha8(path), n))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(root, path), data, 0600))
+	}
+
+	info, err := NewFromPath(root, OptionPieceLength(plength), OptionHybrid)
+	require.NoError(t, err)
+	require.True(t, info.IsV2())
+	require.True(t, info.HasV1())
+
+	// files are ordered by the file tree and aligned to piece boundaries.
+	require.Equal(t, []FileInfo{
+		{Length: 20 * bytesx.KiB, Path: []string{"a"}},
+		NewPaddingFile(12 * bytesx.KiB),
+		{Length: 0, Path: []string{"b"}},
+		{Length: 40 * bytesx.KiB, Path: []string{"x", "y"}},
+		NewPaddingFile(8 * bytesx.KiB),
+		{Length: bytesx.KiB, Path: []string{"x-z"}},
+	}, info.Files)
+	require.Equal(t, uint64(6), info.NumPieces())
+
+	data := bytes.NewBuffer(nil)
+	for _, fi := range info.Files {
+		var src io.Reader = cryptox.NewChaCha8(filepath.Join(fi.Path...))
+		if fi.IsPadding() {
+			src = zeros{}
+		}
+		_, err := io.CopyN(data, src, fi.Length)
+		require.NoError(t, err)
+	}
+	pieces, err := ComputePieces(data, plength)
+	require.NoError(t, err)
+	require.Equal(t, pieces, info.Pieces)
+
+	encoded, err := bencode.Marshal(info)
+	require.NoError(t, err)
+	var decoded Info
+	require.NoError(t, bencode.Unmarshal(encoded, &decoded))
+
+	// the v1 and v2 layouts of the data must be identical.
+	v1 := []FileV2{}
+	offset := int64(0)
+	for _, fi := range decoded.UpvertedFiles() {
+		if !fi.IsPadding() {
+			v1 = append(v1, FileV2{Path: fi.Path, Offset: offset})
+		}
+		offset += fi.Length
+	}
+	v2 := []FileV2{}
+	for _, f := range decoded.FilesV2() {
+		v2 = append(v2, FileV2{Path: f.Path, Offset: f.Offset})
+	}
+	require.Equal(t, v1, v2)
+
+	hashes, ok := info.PieceLayers.Layer(decoded.FilesV2()[2].Root())
+	require.True(t, ok)
+	require.Len(t, hashes, 3)
+}
+
+func TestNewFromPathHybridSingleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "single.bin")
+	data, err := io.ReadAll(io.LimitReader(cryptox.NewChaCha8(t.Name()), 40*bytesx.KiB))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	info, err := NewFromPath(path, OptionPieceLength(16*bytesx.KiB), OptionHybrid)
+	require.NoError(t, err)
+	require.False(t, info.IsDir())
+	require.Equal(t, int64(40*bytesx.KiB), info.Length)
+	require.Equal(t, uint64(3), info.NumPieces())
+
+	f, _, err := ComputeFileV2(bytes.NewReader(data), 16*bytesx.KiB)
+	require.NoError(t, err)
+	require.Equal(t, &f, info.FileTree.Dir["single.bin"].File)
+
+	_, err = NewFromPath(path, OptionPieceLength(24*bytesx.KiB), OptionHybrid)
+	require.Error(t, err)
+}
+
+func TestNewFromReaderHybrid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "single.bin")
+	data, err := io.ReadAll(io.LimitReader(cryptox.NewChaCha8(t.Name()), 40*bytesx.KiB))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	expected, err := NewFromPath(path, OptionPieceLength(16*bytesx.KiB), OptionHybrid)
+	require.NoError(t, err)
+
+	info, err := NewFromReader(bytes.NewReader(data), OptionPieceLength(16*bytesx.KiB), OptionDisplayName("single.bin"), OptionHybrid)
+	require.NoError(t, err)
+	require.Equal(t, expected.Pieces, info.Pieces)
+	require.Equal(t, expected.FileTree, info.FileTree)
+	require.Equal(t, expected.PieceLayers, info.PieceLayers)
+	require.Len(t, info.PieceLayers, 1)
+
+	// the name of unnamed torrents is known once the data is read.
+	info, err = NewFromReader(bytes.NewReader(data), OptionPieceLength(16*bytesx.KiB), OptionHybrid)
+	require.NoError(t, err)
+	require.Equal(t, expected.FileTree.Dir["single.bin"], info.FileTree.Dir[info.Name])
+
+	_, err = NewFromReader(bytes.NewReader(data), OptionPieceLength(24*bytesx.KiB), OptionHybrid)
+	require.Error(t, err)
+}
@@ -1,23 +1,36 @@
 package metainfo
 
 import (
+	"cmp"
 	"encoding/base32"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 )
 
 // Magnet link components.
 type Magnet struct {
-	InfoHash    Hash       // Expected in this implementation
-	Trackers    []string   // "tr" values
-	DisplayName string     // "dn" value, if not empty
-	Params      url.Values // All other values, such as "x.pe", "as", "xs" etc.
+	InfoHash    Hash        // "xt" btih value, zero for v2 only torrents.
+	InfoHashV2  HashV2      // "xt" btmh value (BEP 52), zero for v1 only torrents.
+	Trackers    []string    // "tr" values
+	DisplayName string      // "dn" value, if not empty
+	Peers       []string    // "x.pe" values, peer addresses in host:port form.
+	Sources     []string    // "xs" values, urls the .torrent file can be downloaded from.
+	Fallbacks   []string    // "as" values, urls the .torrent file can be downloaded from.
+	SelectOnly  []FileRange // "so" value, ranges of the files to download (BEP 53).
+	Params      url.Values  // All other values, such as "ws" etc.
 }
 
-const xtPrefix = "urn:btih:"
+const (
+	xtPrefix   = "urn:btih:"
+	xtPrefixV2 = "urn:btmh:"
+	// multihash prefix for sha2-256 digests.
+	multihashSHA256 = "1220"
+)
 
 func (m Magnet) String() string {
 	// Deep-copy m.Params
@@ -32,13 +45,33 @@ func (m Magnet) String() string {
 	if m.DisplayName != "" {
 		vs.Add("dn", m.DisplayName)
 	}
+	for _, pe := range m.Peers {
+		vs.Add("x.pe", pe)
+	}
+	for _, xs := range m.Sources {
+		vs.Add("xs", xs)
+	}
+	for _, as := range m.Fallbacks {
+		vs.Add("as", as)
+	}
+	if len(m.SelectOnly) > 0 {
+		vs.Add("so", encodeSelectOnly(m.SelectOnly))
+	}
 
 	// Transmission and Deluge both expect "urn:btih:" to be unescaped. Deluge wants it to be at the
 	// start of the magnet link. The InfoHash field is expected to be BitTorrent in this
 	// implementation.
+	xts := make([]string, 0, 2)
+	if m.InfoHash != (Hash{}) || m.InfoHashV2.IsZero() {
+		xts = append(xts, "xt="+xtPrefix+m.InfoHash.String())
+	}
+	if !m.InfoHashV2.IsZero() {
+		xts = append(xts, "xt="+xtPrefixV2+multihashSHA256+m.InfoHashV2.String())
+	}
+
 	u := url.URL{
 		Scheme:   "magnet",
-		RawQuery: "xt=" + xtPrefix + m.InfoHash.String(),
+		RawQuery: strings.Join(xts, "&"),
 	}
 	if len(vs) != 0 {
 		u.RawQuery += "&" + vs.Encode()
@@ -58,17 +91,28 @@ func ParseMagnetURI(uri string) (m Magnet, err error) {
 		return
 	}
 	q := u.Query()
-	xt := q.Get("xt")
-	m.InfoHash, err = parseInfohash(q.Get("xt"))
-	if err != nil {
-		err = fmt.Errorf("error parsing infohash %q: %w", xt, err)
-		return
+	if q["xt"], err = m.parseExactTopics(q["xt"]); err != nil {
+		return m, err
+	}
+	if len(q["xt"]) == 0 {
+		delete(q, "xt")
 	}
-	dropFirst(q, "xt")
 	m.DisplayName = q.Get("dn")
 	dropFirst(q, "dn")
 	m.Trackers = q["tr"]
 	delete(q, "tr")
+	m.Peers = q["x.pe"]
+	delete(q, "x.pe")
+	m.Sources = q["xs"]
+	delete(q, "xs")
+	m.Fallbacks = q["as"]
+	delete(q, "as")
+	if so := q.Get("so"); so != "" {
+		if m.SelectOnly, err = parseSelectOnly(so); err != nil {
+			return m, fmt.Errorf("error parsing so %q: %w", so, err)
+		}
+	}
+	delete(q, "so")
 	if len(q) == 0 {
 		q = nil
 	}
@@ -76,6 +120,52 @@ func ParseMagnetURI(uri string) (m Magnet, err error) {
 	return
 }
 
+// parses the v1 and v2 info hashes from the xt parameters, at least one is required.
+// returns the xt parameters that are not info hashes.
+func (m *Magnet) parseExactTopics(xts []string) (unknown []string, err error) {
+	found := false
+	for _, xt := range xts {
+		switch {
+		case strings.HasPrefix(xt, xtPrefix):
+			if m.InfoHash, err = parseInfohash(xt); err != nil {
+				return nil, fmt.Errorf("error parsing infohash %q: %w", xt, err)
+			}
+		case strings.HasPrefix(xt, xtPrefixV2):
+			if m.InfoHashV2, err = parseInfohashV2(xt); err != nil {
+				return nil, fmt.Errorf("error parsing infohash %q: %w", xt, err)
+			}
+		default:
+			unknown = append(unknown, xt)
+			continue
+		}
+		found = true
+	}
+
+	if !found {
+		return nil, errors.New("bad xt parameter, missing btih or btmh info hash")
+	}
+
+	return unknown, nil
+}
+
+func parseInfohashV2(xt string) (ih HashV2, err error) {
+	encoded := strings.TrimPrefix(xt, xtPrefixV2)
+	if !strings.HasPrefix(encoded, multihashSHA256) {
+		return ih, errors.New("unsupported multihash, only sha2-256 is supported")
+	}
+	encoded = strings.TrimPrefix(encoded, multihashSHA256)
+
+	if len(encoded) != 2*HashV2Size {
+		return ih, fmt.Errorf("unhandled xt parameter encoding (encoded length %d)", len(encoded))
+	}
+
+	if _, err = hex.Decode(ih[:], []byte(encoded)); err != nil {
+		return ih, fmt.Errorf("error decoding xt: %w", err)
+	}
+
+	return ih, nil
+}
+
 func parseInfohash(xt string) (ih Hash, err error) {
 	if !strings.HasPrefix(xt, xtPrefix) {
 		err = errors.New("bad xt parameter prefix")
@@ -106,6 +196,82 @@ func parseInfohash(xt string) (ih Hash, err error) {
 	return
 }
 
+// FileRange is an inclusive range of file indices, see BEP 53.
+type FileRange struct {
+	First int
+	Last  int
+}
+
+// FileIndex the range containing only the file at the given index.
+func FileIndex(idx int) FileRange {
+	return FileRange{First: idx, Last: idx}
+}
+
+// Contains reports if the file index is within the range.
+func (t FileRange) Contains(idx int) bool {
+	return t.First <= idx && idx <= t.Last
+}
+
+func (t FileRange) String() string {
+	if t.First == t.Last {
+		return strconv.Itoa(t.First)
+	}
+
+	return fmt.Sprintf("%d-%d", t.First, t.Last)
+}
+
+// parses the BEP 53 file index list, a comma separated list of indices and
+// inclusive ranges, e.g. "0,2,4-6". the ranges are sorted and merged.
+func parseSelectOnly(so string) (ranges []FileRange, err error) {
+	for _, item := range strings.Split(so, ",") {
+		first, last, isrange := strings.Cut(item, "-")
+		begin, err := strconv.Atoi(first)
+		if err != nil || begin < 0 {
+			return nil, fmt.Errorf("invalid file index %q", item)
+		}
+
+		end := begin
+		if isrange {
+			if end, err = strconv.Atoi(last); err != nil || end < begin {
+				return nil, fmt.Errorf("invalid file range %q", item)
+			}
+		}
+
+		ranges = append(ranges, FileRange{First: begin, Last: end})
+	}
+
+	return compactFileRanges(ranges), nil
+}
+
+// sorts the ranges merging overlapping and adjacent ranges.
+func compactFileRanges(ranges []FileRange) (compacted []FileRange) {
+	ranges = slices.SortedFunc(slices.Values(ranges), func(a, b FileRange) int {
+		return cmp.Compare(a.First, b.First)
+	})
+
+	for _, r := range ranges {
+		if n := len(compacted); n > 0 && r.First <= compacted[n-1].Last+1 {
+			compacted[n-1].Last = max(compacted[n-1].Last, r.Last)
+			continue
+		}
+
+		compacted = append(compacted, r)
+	}
+
+	return compacted
+}
+
+// encodes the file ranges as a BEP 53 list.
+func encodeSelectOnly(ranges []FileRange) string {
+	ranges = compactFileRanges(ranges)
+	items := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		items = append(items, r.String())
+	}
+
+	return strings.Join(items, ",")
+}
+
 func dropFirst(vs url.Values, key string) {
 	sl := vs[key]
 	switch len(sl) {
@@ -112,3 +112,88 @@ func contains(haystack []string, needle string) bool {
 	}
 	return false
 }
+
+func TestMagnetV2(t *testing.T) {
+	const (
+		v1 = "631a31dd0a46257d5078c0dee4e66e26f73e42ac"
+		v2 = "d8dd32ac93357c368556af3ac1d95c9d76bd0dff6fa9833ecdac3d53134efabb"
+	)
+
+	t.Run("v2 only", func(t *testing.T) {
+		m, err := ParseMagnetURI("magnet:?xt=urn:btmh:1220" + v2 + "&dn=example")
+		require.NoError(t, err)
+		require.Equal(t, Hash{}, m.InfoHash)
+		require.Equal(t, NewHashV2FromHex(v2), m.InfoHashV2)
+		require.Equal(t, "example", m.DisplayName)
+		require.Nil(t, m.Params)
+
+		rt, err := ParseMagnetURI(m.String())
+		require.NoError(t, err)
+		require.Equal(t, m, rt)
+		require.NotContains(t, m.String(), "btih")
+	})
+
+	t.Run("hybrid", func(t *testing.T) {
+		m, err := ParseMagnetURI("magnet:?xt=urn:btih:" + v1 + "&xt=urn:btmh:1220" + v2)
+		require.NoError(t, err)
+		require.Equal(t, NewHashFromHex(v1), m.InfoHash)
+		require.Equal(t, NewHashV2FromHex(v2), m.InfoHashV2)
+
+		rt, err := ParseMagnetURI(m.String())
+		require.NoError(t, err)
+		require.Equal(t, m, rt)
+	})
+
+	t.Run("unsupported multihash", func(t *testing.T) {
+		_, err := ParseMagnetURI("magnet:?xt=urn:btmh:1320" + v2)
+		require.Error(t, err)
+	})
+}
+
+func TestMagnetExtensions(t *testing.T) {
+	const (
+		v1 = "631a31dd0a46257d5078c0dee4e66e26f73e42ac"
+		v2 = "d8dd32ac93357c368556af3ac1d95c9d76bd0dff6fa9833ecdac3d53134efabb"
+	)
+
+	t.Run("round trip", func(t *testing.T) {
+		m, err := ParseMagnetURI("magnet:?xt=urn:btih:" + v1 + "&xt=urn:btmh:1220" + v2 +
+			"&x.pe=10.0.0.1:6881&x.pe=%5B::1%5D:6882&so=0,2,4-6&xs=http://example.com/a.torrent&as=http://mirror.example.com/a.torrent&ws=http://example.com/data")
+		require.NoError(t, err)
+		require.Equal(t, NewHashFromHex(v1), m.InfoHash)
+		require.Equal(t, NewHashV2FromHex(v2), m.InfoHashV2)
+		require.Equal(t, []string{"10.0.0.1:6881", "[::1]:6882"}, m.Peers)
+		require.Equal(t, []FileRange{{First: 0, Last: 0}, {First: 2, Last: 2}, {First: 4, Last: 6}}, m.SelectOnly)
+		require.Equal(t, []string{"http://example.com/a.torrent"}, m.Sources)
+		require.Equal(t, []string{"http://mirror.example.com/a.torrent"}, m.Fallbacks)
+		require.Equal(t, []string{"http://example.com/data"}, m.Params["ws"])
+
+		rt, err := ParseMagnetURI(m.String())
+		require.NoError(t, err)
+		require.Equal(t, m, rt)
+		require.Contains(t, m.String(), "so=0%2C2%2C4-6")
+	})
+
+	t.Run("select only", func(t *testing.T) {
+		ranges, err := parseSelectOnly("7,1-3,2,4")
+		require.NoError(t, err)
+		require.Equal(t, []FileRange{{First: 1, Last: 4}, {First: 7, Last: 7}}, ranges)
+		require.Equal(t, "1-4,7", encodeSelectOnly(ranges))
+		require.True(t, ranges[0].Contains(3))
+		require.False(t, ranges[0].Contains(5))
+
+		// ranges are not expanded.
+		ranges, err = parseSelectOnly("0-2000000000")
+		require.NoError(t, err)
+		require.Equal(t, []FileRange{{First: 0, Last: 2000000000}}, ranges)
+		require.Equal(t, "0-2000000000", encodeSelectOnly(ranges))
+
+		for _, invalid := range []string{"", "a", "-1", "3-1", "1-", "1,,2"} {
+			_, err := parseSelectOnly(invalid)
+			require.Error(t, err, invalid)
+		}
+
+		_, err = ParseMagnetURI("magnet:?xt=urn:btih:" + v1 + "&so=1-a")
+		require.Error(t, err)
+	})
+}
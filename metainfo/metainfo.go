@@ -19,6 +19,7 @@ type MetaInfo struct {
 	CreatedBy    string        `bencode:"created by,omitempty"`
 	Encoding     string        `bencode:"encoding,omitempty"`
 	UrlList      UrlList       `bencode:"url-list,omitempty"`
+	PieceLayers  PieceLayers   `bencode:"piece layers,omitempty"`
 }
 
 // Load a MetaInfo from an io.Reader. Returns a non-nil error in case of
@@ -56,6 +57,11 @@ func (mi MetaInfo) HashInfoBytes() (infoHash Hash) {
 	return NewHashFromBytes(mi.InfoBytes)
 }
 
+// HashInfoBytesV2 computes the BEP 52 info hash.
+func (mi MetaInfo) HashInfoBytesV2() (infoHash HashV2) {
+	return NewHashV2FromBytes(mi.InfoBytes)
+}
+
 // Encode to bencoded form.
 func (mi MetaInfo) Write(w io.Writer) error {
 	return bencode.NewEncoder(w).Encode(mi)
@@ -92,7 +98,7 @@ func (mi *MetaInfo) UpvertedAnnounceList() AnnounceList {
 
 // NodeList return nodes as a string slice.
 func (mi *MetaInfo) NodeList() (ret []string) {
-	ret = make([]string, len(mi.Nodes))
+	ret = make([]string, 0, len(mi.Nodes))
 	for _, node := range mi.Nodes {
 		ret = append(ret, string(node))
 	}
@@ -0,0 +1,94 @@
+package metainfo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/james-lawrence/torrent/internal/merkle"
+)
+
+// PieceLayers maps the pieces root of a file onto the concatenated SHA256
+// hashes of its pieces (BEP 52). Files no larger than a single piece are omitted.
+type PieceLayers map[string]string
+
+// Layer returns the piece hashes of the file with the given pieces root.
+func (t PieceLayers) Layer(root HashV2) (hashes []HashV2, ok bool) {
+	encoded, ok := t[string(root[:])]
+	if !ok {
+		return nil, false
+	}
+
+	hashes = make([]HashV2, len(encoded)/HashV2Size)
+	for i := range hashes {
+		copy(hashes[i][:], encoded[i*HashV2Size:])
+	}
+
+	return hashes, true
+}
+
+// Set the piece hashes of the file with the given pieces root.
+func (t PieceLayers) Set(root HashV2, hashes []HashV2) {
+	encoded := make([]byte, 0, len(hashes)*HashV2Size)
+	for _, h := range hashes {
+		encoded = append(encoded, h[:]...)
+	}
+
+	t[string(root[:])] = string(encoded)
+}
+
+// LayerRoot computes the pieces root of a file from its piece layer.
+func LayerRoot(plength int64, hashes []HashV2) HashV2 {
+	leaves := make([][merkle.Size]byte, 0, len(hashes))
+	for _, h := range hashes {
+		leaves = append(leaves, h)
+	}
+
+	return merkle.Root(leaves, 0, merkle.Pad(int(plength/merkle.BlockSize)))
+}
+
+// ComputeFileV2 computes the BEP 52 pieces root and piece layer of the file
+// contents read from src.
+func ComputeFileV2(src io.Reader, plength int64) (f FileTreeFile, layer []HashV2, err error) {
+	if plength < merkle.BlockSize || plength&(plength-1) != 0 {
+		return f, nil, fmt.Errorf("piece length must be a power of two and at least %d", merkle.BlockSize)
+	}
+
+	var (
+		digest = merkle.New()
+		root   HashV2
+	)
+
+	for {
+		digest.Reset()
+		n, err := io.CopyN(digest, src, plength)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return f, nil, err
+		}
+
+		if n == 0 {
+			break
+		}
+
+		f.Length += n
+		// single piece files use the root of their own (smaller) tree.
+		root = digest.Root(0)
+		layer = append(layer, digest.Root(int(plength/merkle.BlockSize)))
+
+		if n < plength {
+			break
+		}
+	}
+
+	switch len(layer) {
+	case 0:
+		return f, nil, nil
+	case 1:
+		f.PiecesRoot = root[:]
+		return f, nil, nil
+	default:
+		root = LayerRoot(plength, layer)
+		f.PiecesRoot = root[:]
+		return f, layer, nil
+	}
+}
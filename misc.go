@@ -9,6 +9,7 @@ import (
 	"time"
 
 	pp "github.com/james-lawrence/torrent/btprotocol"
+	"github.com/james-lawrence/torrent/internal/merkle"
 	"github.com/james-lawrence/torrent/internal/netx"
 	"github.com/james-lawrence/torrent/metainfo"
 )
@@ -103,6 +104,9 @@ func validateInfo(info *metainfo.Info) error {
 	if len(info.Pieces)%20 != 0 {
 		return errors.New("pieces has invalid length")
 	}
+	if info.IsV2() && (info.PieceLength < merkle.BlockSize || info.PieceLength&(info.PieceLength-1) != 0) {
+		return errors.New("v2 piece length must be a power of two of at least 16KiB")
+	}
 	if info.PieceLength == 0 {
 		if info.TotalLength() != 0 {
 			return errors.New("zero piece length")
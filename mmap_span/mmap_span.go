@@ -59,6 +59,26 @@ func (ms *MMapSpan) ReadAt(p []byte, off int64) (n int, err error) {
 	return
 }
 
+// Range calls fn with the mapped memory of the n bytes at the offset, in order.
+// The memory must not be retained after fn returns.
+func (ms *MMapSpan) Range(off, n int64, fn func(b []byte) error) (err error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	ms.ApplyTo(off, func(intervalOffset int64, interval sizer) (stop bool) {
+		b := (*interval.(segment).MMap)[intervalOffset:]
+		b = b[:min(int64(len(b)), n)]
+		n -= int64(len(b))
+		if err = fn(b); err != nil {
+			return true
+		}
+		return n == 0
+	})
+	if err == nil && n != 0 {
+		err = io.EOF
+	}
+	return err
+}
+
 func (ms *MMapSpan) WriteAt(p []byte, off int64) (n int, err error) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
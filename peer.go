@@ -19,6 +19,13 @@ func PeerOptionSource(src peerSource) PeerOption {
 	}
 }
 
+// PeerOptionSwarm records the swarm the peer was discovered in, see Metadata.Swarms.
+func PeerOptionSwarm(id int160.T) PeerOption {
+	return func(p *Peer) {
+		p.Swarm = id
+	}
+}
+
 func PeerOptionEncrypted(b bool) PeerOption {
 	return func(p *Peer) {
 		p.SupportsEncryption = b
@@ -50,6 +57,9 @@ type Peer struct {
 	Source             peerSource
 	SupportsEncryption bool // Peer is known to support encryption.
 	Trusted            bool // Whether we can ignore poor or bad behaviour from the peer.
+	// The infohash of the swarm the peer was discovered in, used for the handshake
+	// with the peer. Defaults to the torrent's ID when zero.
+	Swarm int160.T
 }
 
 // FromPex generate Peer from peer exchange
@@ -3,9 +3,13 @@ package torrent
 import (
 	"errors"
 	"io"
+	"log"
+	"sync"
 	"sync/atomic"
 
+	"github.com/RoaringBitmap/roaring/v2"
 	"github.com/james-lawrence/torrent/internal/atomicx"
+	"github.com/james-lawrence/torrent/internal/errorsx"
 	"github.com/james-lawrence/torrent/storage"
 )
 
@@ -61,40 +65,77 @@ type Reader interface {
 	io.Reader
 	io.Seeker
 	io.Closer
+	// SetReadahead adjusts the number of bytes ahead of the read position that
+	// are downloaded with urgent priority.
+	SetReadahead(n int64)
 }
 
 func NewReader(t Torrent) Reader {
-	return &reader{
+	return newReader(t, 0, t.Info().TotalLength())
+}
+
+func newReader(t Torrent, offset, length int64) *reader {
+	r := &reader{
 		TorrentImpl: t.Storage(),
-		length:      t.Info().TotalLength(),
+		t:           t,
+		offset:      offset,
+		length:      length,
+		readahead:   defaultReadahead,
+		window:      -1,
 	}
+
+	r.advance()
+
+	return r
 }
 
 // Accesses Torrent data via a Client. Reads block until the data is
 // available. Seeks and readahead also drive Client behaviour.
 type reader struct {
 	storage.TorrentImpl
+	t Torrent
 	// Adjust the read/seek window to handle Readers locked to File extents
 	// and the like.
 	offset, length int64
 	pos            int64
+
+	mu        sync.Mutex
+	readahead int64
+	// piece containing the start of the currently registered readahead window.
+	window int64
 }
 
 var _ io.ReadCloser = &reader{}
 
 func (r *reader) Read(b []byte) (n int, err error) {
+	pos := atomic.LoadInt64(&r.pos)
+	if pos >= r.length {
+		return 0, io.EOF
+	}
+
+	b = b[:min(int64(len(b)), r.length-pos)]
+
 	// log.Println("read initiated", r.pos, r.length)
-	n, err = r.ReadAt(b, r.pos)
+	n, err = r.ReadAt(b, r.offset+pos)
 	atomic.AddInt64(&r.pos, int64(n)) // npos
 	// log.Println("read completed", npos, r.length)
+	r.advance()
 	return n, err
 }
 
 func (r *reader) Close() error {
-	return r.TorrentImpl.Close()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return errorsx.Compact(
+		r.t.Tune(tuneReadahead(r, readahead{})),
+		r.TorrentImpl.Close(),
+	)
 }
 
 func (r *reader) Seek(off int64, whence int) (ret int64, err error) {
+	defer r.advance()
+
 	switch whence {
 	case io.SeekStart:
 		atomic.SwapInt64(&r.pos, off)
@@ -108,3 +149,70 @@ func (r *reader) Seek(off int64, whence int) (ret int64, err error) {
 		return -1, errors.ErrUnsupported
 	}
 }
+
+func (r *reader) SetReadahead(n int64) {
+	r.mu.Lock()
+	r.readahead = n
+	r.window = -1
+	r.mu.Unlock()
+
+	r.advance()
+}
+
+// moves the readahead window to the current position, the window is only
+// updated once the read position crosses into a different piece.
+func (r *reader) advance() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	plength := r.t.Info().PieceLength
+	if plength <= 0 {
+		return
+	}
+
+	pos := min(max(atomic.LoadInt64(&r.pos), 0), r.length)
+	pid := (r.offset + pos) / plength
+	if pid == r.window {
+		return
+	}
+	r.window = pid
+
+	if err := r.t.Tune(tuneReadahead(r, readahead{offset: r.offset + pos, length: min(r.readahead, r.length-pos)})); err != nil {
+		log.Println("unable to update readahead window", err)
+	}
+}
+
+// readahead region of the torrent a reader is about to read.
+type readahead struct {
+	offset int64
+	length int64
+}
+
+// returns the chunks of every piece overlapping the region.
+func (t readahead) chunks(c *chunks) *roaring.Bitmap {
+	b := roaring.New()
+	if t.length <= 0 {
+		return b
+	}
+
+	cmin, _ := c.Range(c.PIDForOffset(uint64(t.offset)))
+	_, cmax := c.Range(c.PIDForOffset(uint64(t.offset + t.length - 1)))
+	b.AddRange(cmin, cmax)
+	return b
+}
+
+// register the readahead window of the reader, empty windows unregister the reader.
+func tuneReadahead(r *reader, w readahead) Tuner {
+	return func(t *torrent) {
+		t.lock()
+		defer t.unlock()
+
+		if w.length <= 0 {
+			delete(t.readaheads, r)
+		} else {
+			t.readaheads[r] = w
+		}
+
+		t.prioritize()
+	}
+}
@@ -0,0 +1,99 @@
+package torrent
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/internal/bitmapx"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+func TestReaderReadahead(t *testing.T) {
+	pending := func(t *testing.T, tt *torrent) []int {
+		reqs, err := tt.chunks.Pop(int(tt.chunks.cmaximum), bitmapx.Fill(tt.chunks.cmaximum))
+		require.NoError(t, err)
+		tt.chunks.Retry(reqs...)
+		return requestPieces(reqs...)
+	}
+
+	setup := func(t *testing.T) *torrent {
+		cl := &Client{config: TestingConfig(t, t.TempDir())}
+		md, err := New(metainfo.Hash{}, OptionChunk(bytesx.KiB))
+		require.NoError(t, err)
+		tt := newTorrent(cl, md)
+		require.NoError(t, tt.setInfo(&metainfo.Info{
+			Name:        "example",
+			PieceLength: 4 * bytesx.KiB,
+			Pieces:      make([]byte, metainfo.HashSize*8),
+			Files: []metainfo.FileInfo{
+				{Path: []string{"a"}, Length: 10 * bytesx.KiB},
+				{Path: []string{"b"}, Length: 22 * bytesx.KiB},
+			},
+		}))
+		return tt
+	}
+
+	t.Run("window pieces are downloaded first", func(t *testing.T) {
+		tt := setup(t)
+		require.NoError(t, tt.Tune(TuneAutoDownload))
+
+		// rarity does not reorder the window, readers need the data in order.
+		tt.chunks.Claimed(1, 2, 5)
+
+		r := tt.Files()[1].NewReader()
+		r.SetReadahead(6 * bytesx.KiB)
+		pids := pending(t, tt)
+		require.Equal(t, []int{2, 3}, pids[:2])
+		require.ElementsMatch(t, []int{0, 1, 4, 5, 6, 7}, pids[2:])
+
+		_, err := r.Seek(12*bytesx.KiB, io.SeekStart)
+		require.NoError(t, err)
+		pids = pending(t, tt)
+		require.Equal(t, []int{5, 6}, pids[:2])
+		require.ElementsMatch(t, []int{0, 1, 2, 3, 4, 7}, pids[2:])
+
+		require.NoError(t, r.Close())
+		require.Empty(t, tt.readaheads)
+		require.Empty(t, tt.chunks.priorities[PriorityUrgent])
+	})
+
+	t.Run("file priorities are retained as the window moves", func(t *testing.T) {
+		tt := setup(t)
+		require.NoError(t, tt.Tune(TuneAutoDownload, TuneFilePriority(tt.Files()[0], PriorityHigh)))
+		cached := tt.priorities
+
+		r := tt.Files()[1].NewReader()
+		defer r.Close()
+		r.SetReadahead(bytesx.KiB)
+		_, err := r.Seek(20*bytesx.KiB, io.SeekStart)
+		require.NoError(t, err)
+
+		pids := pending(t, tt)
+		require.Equal(t, []int{7}, pids[:1])
+		require.ElementsMatch(t, []int{0, 1, 2}, pids[1:4])
+		require.ElementsMatch(t, []int{3, 4, 5, 6}, pids[4:])
+
+		// moving the window reuses the file priorities.
+		require.Equal(t, fmt.Sprintf("%p", cached), fmt.Sprintf("%p", tt.priorities))
+	})
+
+	t.Run("concurrent readers combine their windows", func(t *testing.T) {
+		tt := setup(t)
+
+		r1 := NewReader(tt)
+		defer r1.Close()
+		r1.SetReadahead(bytesx.KiB)
+
+		r2 := tt.Files()[1].NewReader()
+		defer r2.Close()
+		r2.SetReadahead(bytesx.KiB)
+		_, err := r2.Seek(20*bytesx.KiB, io.SeekStart)
+		require.NoError(t, err)
+
+		require.Equal(t, []int{0, 7}, pending(t, tt)[:2])
+	})
+}
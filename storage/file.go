@@ -77,9 +77,10 @@ func (fs *fileClientImpl) OpenTorrent(info *metainfo.Info, infoHash int160.T) (T
 	for i, fi := range upverted {
 		path := fs.pathMaker(fs.baseDir, infoHash, info, &fi)
 		entries[i] = fileEntry{
-			path:   path,
-			begin:  begin,
-			length: fi.Length,
+			path:    path,
+			begin:   begin,
+			length:  fi.Length,
+			padding: fi.IsPadding(),
 		}
 		begin += fi.Length
 	}
@@ -106,10 +107,16 @@ type fileEntry struct {
 	path   string
 	begin  int64
 	length int64
+	// BEP 47 padding files are never stored, they read as zeros and writes are discarded.
+	padding bool
 }
 
 func createAllDirectories(entries []fileEntry) error {
 	for _, e := range entries {
+		if e.padding {
+			continue
+		}
+
 		if err := os.MkdirAll(filepath.Dir(e.path), 0777); err != nil {
 			return err
 		}
@@ -152,7 +159,7 @@ func (fs *fileTorrentImpl) Close() error {
 // length files because they have no corresponding pieces.
 func CreateNativeZeroLengthFiles(dir string, infohash int160.T, info *metainfo.Info, pathMaker FilePathMaker) (err error) {
 	for _, fi := range info.UpvertedFiles() {
-		if fi.Length != 0 {
+		if fi.Length != 0 || fi.IsPadding() {
 			continue
 		}
 
@@ -214,6 +221,14 @@ func (t fileTorrentImplIO) ReadAt(b []byte, off int64) (n int, err error) {
 		fe := t.fts.files[i]
 		localOff := off - fe.begin
 		requested := min(int64(len(b)), fe.length-localOff)
+		if fe.padding {
+			clear(b[:requested])
+			n += int(requested)
+			off += requested
+			b = b[requested:]
+			continue
+		}
+
 		var n1 int
 		n1, err = t.readFileAt(fe, b[:requested], localOff)
 		n += n1
@@ -242,6 +257,13 @@ func (t fileTorrentImplIO) WriteAt(p []byte, off int64) (n int, err error) {
 		fe := t.fts.files[i]
 		localOff := off - fe.begin
 		n1 := min(int64(len(p)), fe.length-localOff)
+		if fe.padding {
+			n += int(n1)
+			off += n1
+			p = p[n1:]
+			continue
+		}
+
 		var f *os.File
 		f, err = os.OpenFile(fe.path, os.O_WRONLY|os.O_CREATE, 0666)
 		if err != nil {
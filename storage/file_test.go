@@ -67,6 +67,36 @@ func TestReadAllData(t *testing.T) {
 	require.Equal(t, md5x.FormatHex(expected), md5x.FormatHex(result))
 }
 
+func TestPaddingFiles(t *testing.T) {
+	td := t.TempDir()
+	info := &metainfo.Info{
+		Name:        "padded",
+		PieceLength: 8,
+		Files: []metainfo.FileInfo{
+			{Path: []string{"a"}, Length: 5},
+			metainfo.NewPaddingFile(3),
+			{Path: []string{"b"}, Length: 4},
+		},
+	}
+
+	ts, err := NewFile(td).OpenTorrent(info, int160.Zero())
+	require.NoError(t, err)
+	defer ts.Close()
+
+	// writes to the padding are discarded and never stored.
+	n, err := ts.WriteAt([]byte("aaaaapppbbbb"), 0)
+	require.NoError(t, err)
+	require.Equal(t, 12, n)
+	require.NoDirExists(t, filepath.Join(td, int160.Zero().String(), ".pad"))
+
+	// the padding reads as zeros.
+	buf := make([]byte, 12)
+	n, err = ts.ReadAt(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, 12, n)
+	require.Equal(t, []byte("aaaaa\x00\x00\x00bbbb"), buf)
+}
+
 func RandomDataTorrent(dir string, n int64, options ...metainfo.Option) (info *metainfo.Info, digested hash.Hash, err error) {
 	digested = md5.New()
 
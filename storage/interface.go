@@ -21,6 +21,14 @@ type TorrentImpl interface {
 	Close() error
 }
 
+// Mapped storage exposes the memory backing the torrent data, allowing it to be
+// read, e.g. for hashing, without copying.
+type Mapped interface {
+	// Range calls fn with the memory of the n bytes at the offset, in order.
+	// The memory must not be retained after fn returns.
+	Range(off, n int64, fn func(b []byte) error) error
+}
+
 func ErrClosed() error {
 	return errors.New("storage closed")
 }
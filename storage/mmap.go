@@ -57,6 +57,11 @@ func (ts *mmapTorrentStorage) WriteAt(p []byte, off int64) (n int, err error) {
 	return io.NewOffsetWriter(ts.span, off).Write(p)
 }
 
+// Range implements Mapped.
+func (ts *mmapTorrentStorage) Range(off, n int64, fn func(b []byte) error) error {
+	return ts.span.Range(off, n, fn)
+}
+
 func (ts *mmapTorrentStorage) Close() error {
 	return ts.span.Close()
 }
@@ -1,9 +1,13 @@
 package torrent
 
 import (
+	"iter"
+	"maps"
+	"slices"
 	"sync"
 
 	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/langx"
 )
 
 func NewCache(s MetadataStore, b BitmapStore) *memoryseeding {
@@ -12,6 +16,7 @@ func NewCache(s MetadataStore, b BitmapStore) *memoryseeding {
 		MetadataStore: s,
 		bm:            b,
 		torrents:      make(map[int160.T]*torrent, 128),
+		aliases:       make(map[int160.T]int160.T),
 	}
 }
 
@@ -20,6 +25,52 @@ type memoryseeding struct {
 	bm       BitmapStore
 	_mu      *sync.RWMutex
 	torrents map[int160.T]*torrent
+	// maps the secondary swarms of torrents, i.e. the v2 swarm of hybrid
+	// torrents, onto the ID of the torrent.
+	aliases map[int160.T]int160.T
+}
+
+// records the secondary swarms of the torrent, must hold the lock.
+func (t *memoryseeding) alias(md Metadata) {
+	for _, id := range md.Swarms()[1:] {
+		t.aliases[id] = md.ID
+	}
+}
+
+// resolve the ID of the torrent from the ID of any of its swarms.
+func (t *memoryseeding) resolve(id int160.T) int160.T {
+	t._mu.RLock()
+	defer t._mu.RUnlock()
+	return langx.DefaultIfZero(id, t.aliases[id])
+}
+
+// Each iterates over the IDs of the stored torrents along with the secondary
+// swarms of the torrents in memory.
+func (t *memoryseeding) Each() iter.Seq[int160.T] {
+	return func(yield func(int160.T) bool) {
+		for id := range t.MetadataStore.Each() {
+			if !yield(id) {
+				return
+			}
+		}
+
+		t._mu.RLock()
+		aliases := slices.Collect(maps.Keys(t.aliases))
+		t._mu.RUnlock()
+
+		for _, id := range aliases {
+			if !yield(id) {
+				return
+			}
+		}
+	}
+}
+
+// snapshot of the torrents in memory.
+func (t *memoryseeding) active() []*torrent {
+	t._mu.RLock()
+	defer t._mu.RUnlock()
+	return slices.Collect(maps.Values(t.torrents))
 }
 
 func (t *memoryseeding) Close() error {
@@ -37,6 +88,7 @@ func (t *memoryseeding) Close() error {
 
 // sync bitmap to disk
 func (t *memoryseeding) Sync(id int160.T) error {
+	id = t.resolve(id)
 	t._mu.Lock()
 	defer t._mu.Unlock()
 	c, ok := t.torrents[id]
@@ -56,6 +108,7 @@ func (t *memoryseeding) Sync(id int160.T) error {
 
 // clear torrent from memory
 func (t *memoryseeding) Drop(id int160.T) error {
+	id = t.resolve(id)
 	if err := t.Sync(id); err != nil {
 		return err
 	}
@@ -63,6 +116,9 @@ func (t *memoryseeding) Drop(id int160.T) error {
 	t._mu.Lock()
 	c, ok := t.torrents[id]
 	delete(t.torrents, id)
+	maps.DeleteFunc(t.aliases, func(_ int160.T, v int160.T) bool {
+		return v == id
+	})
 	t._mu.Unlock()
 	if !ok {
 		return nil
@@ -98,6 +154,7 @@ func (t *memoryseeding) Insert(md Metadata, fn func(md Metadata, options ...Tune
 
 		x := fn(md, options...)
 		t.torrents[id] = x
+		t.alias(md)
 
 		return x, nil
 	}
@@ -117,6 +174,7 @@ func (t *memoryseeding) Insert(md Metadata, fn func(md Metadata, options ...Tune
 }
 
 func (t *memoryseeding) Load(id int160.T, fn func(md Metadata, options ...Tuner) *torrent, options ...Tuner) (dlt *torrent, cached bool, _ error) {
+	id = t.resolve(id)
 	t._mu.RLock()
 	x, ok := t.torrents[id]
 	t._mu.RUnlock()
@@ -140,6 +198,7 @@ func (t *memoryseeding) Load(id int160.T, fn func(md Metadata, options ...Tuner)
 
 		x := fn(md, options...)
 		t.torrents[id] = x
+		t.alias(md)
 
 		return x, false, nil
 	}
@@ -161,6 +220,7 @@ func (t *memoryseeding) Load(id int160.T, fn func(md Metadata, options ...Tuner)
 }
 
 func (t *memoryseeding) Metadata(id int160.T) (md Metadata, err error) {
+	id = t.resolve(id)
 	t._mu.RLock()
 	defer t._mu.RUnlock()
 
@@ -2,11 +2,14 @@ package torrent
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/bytesx"
+	"github.com/james-lawrence/torrent/metainfo"
 	"github.com/james-lawrence/torrent/torrenttest"
 	"github.com/stretchr/testify/require"
 )
@@ -93,6 +96,35 @@ func TestTorrentCache(t *testing.T) {
 		require.NoError(t, c.Close())
 	})
 
+	t.Run("load hybrid torrent from its v2 swarm", func(t *testing.T) {
+		tmpdir := t.TempDir()
+		c := NewCache(NewMetadataCache(tmpdir), NewBitmapCache(tmpdir))
+
+		info, _, err := torrenttest.Random(tmpdir, 32*bytesx.KiB, metainfo.OptionPieceLength(16*bytesx.KiB), metainfo.OptionHybrid)
+		require.NoError(t, err)
+		md, err := NewFromInfo(info)
+		require.NoError(t, err)
+
+		swarms := md.Swarms()
+		require.Len(t, swarms, 2)
+		require.Equal(t, md.ID, swarms[0])
+		require.Equal(t, int160.FromByteArray(md.IDv2.Truncate()), swarms[1])
+
+		tor1, err := c.Insert(md, zeroTorrent)
+		require.NoError(t, err)
+
+		tor2, cached, err := c.Load(swarms[1], zeroTorrent)
+		require.NoError(t, err)
+		require.True(t, cached)
+		require.True(t, tor1 == tor2)
+		require.Contains(t, slices.Collect(c.Each()), swarms[1])
+
+		require.NoError(t, c.Drop(swarms[1]))
+		require.EqualValues(t, 0, len(c.torrents))
+		require.EqualValues(t, 0, len(c.aliases))
+		require.NoError(t, c.Close())
+	})
+
 	t.Run("drop non-existent torrent", func(t *testing.T) {
 		tmpdir := t.TempDir()
 		c := NewCache(NewMetadataCache(tmpdir), NewBitmapCache(tmpdir))
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net"
 	"net/netip"
 	"strconv"
@@ -76,6 +77,15 @@ func TuneReadPeerID(id *int160.T) Tuner {
 	}
 }
 
+// Read the choker's most recent decision for each of the torrent's connections.
+func TuneReadChoking(dst *[]ChokePeer) Tuner {
+	return func(t *torrent) {
+		t.chokemu.Lock()
+		defer t.chokemu.Unlock()
+		*dst = append([]ChokePeer(nil), t.choking...)
+	}
+}
+
 func TuneReadHashID(id *int160.T) Tuner {
 	return func(t *torrent) {
 		t.rLock()
@@ -172,10 +182,19 @@ func TuneClientPeer(cl *Client) Tuner {
 	}
 }
 
+// Enable or disable local service discovery (BEP 14) for the torrent, private
+// torrents are never announced on the local network.
+func TuneLSD(enabled bool) Tuner {
+	return func(t *torrent) {
+		t.lsddisabled.Store(!enabled)
+	}
+}
+
 func TuneDisableTrackers(t *torrent) {
 	t.lock()
 	defer t.unlock()
 	t.md.Trackers = nil
+	t.md.Tiers = nil
 }
 
 // add trackers to the torrent.
@@ -232,7 +251,19 @@ func TuneDownloadRange(offset int64, length int64) Tuner {
 	}
 }
 
-// Announce to trackers looking for at least one successful request that returns peers.
+// TuneFilePriority set the download priority of a file returned by Files.
+// pieces shared between files are downloaded at the highest priority amongst them.
+func TuneFilePriority(f *File, p Priority) Tuner {
+	return func(t *torrent) {
+		t.lock()
+		defer t.unlock()
+		f.priority = p
+		t.priorities = nil
+		t.prioritize()
+	}
+}
+
+// Announce to the trackers following BEP 12 until one of them succeeds.
 func TuneAnnounceOnce(options ...tracker.AnnounceOption) Tuner {
 	return func(t *torrent) {
 		go TrackerAnnounceUntil(context.Background(), t, func() bool {
@@ -241,9 +272,22 @@ func TuneAnnounceOnce(options ...tracker.AnnounceOption) Tuner {
 	}
 }
 
+// Announce to the trackers following BEP 12 until the torrent completes, the
+// completed event is announced as soon as the torrent completes.
 func TuneAnnounceUntilComplete(t *torrent) {
+	sub := t.pieceStateChanges.Subscribe()
+	go func() {
+		defer sub.Close()
+		trackerAnnounceUntil(context.Background(), t, func() bool {
+			return !t.chunks.Incomplete()
+		}, sub.Values)
+	}()
+}
+
+// Announce to the trackers following BEP 12 until the torrent is closed, e.g. while seeding.
+func TuneAnnounceUntilClosed(t *torrent) {
 	go TrackerAnnounceUntil(context.Background(), t, func() bool {
-		return !t.chunks.Incomplete()
+		return false
 	})
 }
 
@@ -342,6 +386,16 @@ func TuneSeeding(t *torrent) {
 	t.chunks.MergeInto(t.chunks.completed, bitmapx.Fill(t.chunks.pieces))
 }
 
+// Enable super seeding (BEP 16), once all the data is available pieces are revealed
+// to each peer individually as they spread throughout the swarm instead of advertising
+// everything. intended for an initial seed, reducing how often it uploads each piece.
+func TuneSuperSeeding(enabled bool) Tuner {
+	return func(t *torrent) {
+		t.superseed.enabled.Store(enabled)
+		t.superseedSpread()
+	}
+}
+
 func TuneRecordMetadata(t *torrent) {
 	if t.Info() == nil {
 		panic("cannot persist torrent metadata when missing info")
@@ -355,6 +409,11 @@ func tuneMerge(md Metadata) Tuner {
 	return func(t *torrent) {
 		t.md.DisplayName = langx.DefaultIfZero(t.md.DisplayName, md.DisplayName)
 		t.md.Trackers = append(t.md.Trackers, md.Trackers...)
+		t.md.Tiers = append(t.md.Tiers, md.Tiers...)
+		t.md.Webseeds = slicesx.AppendMissing(t.md.Webseeds, md.Webseeds...)
+		t.md.Peers = slicesx.AppendMissing(t.md.Peers, md.Peers...)
+		t.md.Sources = slicesx.AppendMissing(t.md.Sources, md.Sources...)
+		t.selectOnly(md.SelectOnly...)
 
 		if md.ChunkSize != t.md.ChunkSize && md.ChunkSize != 0 {
 			log.Println("merging set chunk size")
@@ -375,6 +434,7 @@ type Torrent interface {
 	Info() *metainfo.Info         // TODO: remove, this should be pulled from Metadata()
 	GotInfo() <-chan struct{}     // TODO: remove, torrents should never be returned if they don't have the meta info.
 	Storage() storage.TorrentImpl // temporary replacement for reader.
+	Files() []*File
 }
 
 // Download a torrent into a writer blocking until completion.
@@ -447,13 +507,18 @@ func VerifyStored(ctx context.Context, md *metainfo.MetaInfo, t io.ReaderAt) (mi
 		return nil, nil, err
 	}
 
+	var layers *pieceLayers
+	if !info.HasV1() {
+		layers = newPieceLayers(info, md.PieceLayers)
+	}
+
 	chunks := newChunks(defaultChunkSize, info)
-	digests := newDigests(t, func(i int) *metainfo.Piece {
+	digests := newDigests(newDefaultHashPool(), t, func(i int) *metainfo.Piece {
 		return langx.Autoptr(info.Piece(i))
 	}, func(idx int, cause error) func() {
 		chunks.Hashed(uint64(idx), cause)
 		return func() {}
-	})
+	}, layers)
 
 	digests.EnqueueBitmap(bitmapx.Fill(chunks.pieces))
 	digests.Wait()
@@ -485,6 +550,7 @@ func zeroTorrent(md Metadata, options ...Tuner) *torrent {
 		lastConnection:          atomicx.Pointer(time.Now()),
 		event:                   &sync.Cond{L: mu},
 		chunks:                  newChunks(defaultChunkSize, metainfo.NewInfo(), chunkoptCond(chunkcond)),
+		readaheads:              make(map[*reader]readahead),
 	}
 
 	*tmp.digests = newDigestsFromTorrent(tmp)
@@ -514,9 +580,11 @@ func newTorrent(cl *Client, src Metadata, options ...Tuner) *torrent {
 		storage:                 storage.NewZero(),
 		digests:                 new(digests),
 		wantPeersEvent:          make(chan struct{}, 1),
+		chokeEvent:              make(chan struct{}, 1),
 		closed:                  make(chan struct{}),
 		lastConnection:          atomicx.Pointer(time.Now()),
 		event:                   &sync.Cond{L: m},
+		readaheads:              make(map[*reader]readahead),
 	}
 	*t.digests = newDigestsFromTorrent(t)
 	if err := t.setInfoBytes(src.InfoBytes); err != nil {
@@ -617,6 +685,11 @@ type torrent struct {
 	info  *metainfo.Info
 	files []*File
 
+	// byte regions open readers expect to read next.
+	readaheads map[*reader]readahead
+	// chunks of the files grouped by their priority, rebuilt when nil.
+	priorities map[Priority]*roaring.Bitmap
+
 	// Active peer connections, running message stream loops. TODO: Make this
 	// open (not-closed) connections only.
 	conns *conns
@@ -630,8 +703,12 @@ type torrent struct {
 	peers          peerPool
 	wantPeersEvent chan struct{}
 
+	// announces the torrent to its trackers.
+	announcer announcer
+
 	readabledataavailable atomic.Bool
 	metainfoAvailable     atomic.Bool
+	lsddisabled           atomic.Bool
 
 	// The bencoded bytes of the info dict. This is actively manipulated if
 	// the info bytes aren't initially available, and we try to fetch them
@@ -647,12 +724,24 @@ type torrent struct {
 	// digest management determines if pieces are valid.
 	digests *digests
 
+	// piece layers of v2 torrents, nil for v1 torrents.
+	layers *pieceLayers
+
 	// peer exchange for the current torrent
 	pex *pex
 
 	// last time a peer connection was established.
 	lastConnection *atomic.Pointer[time.Time]
 
+	// BEP 16 super seeding state.
+	superseed superseeder
+
+	// serializes the choker, and the most recent decisions it made.
+	chokemu     sync.Mutex
+	chokeEvent  chan struct{}
+	choking     []ChokePeer
+	chokerstart sync.Once // the choker runs once there is data to upload.
+
 	// signal events on this torrent.
 	event *sync.Cond
 }
@@ -759,6 +848,8 @@ func (t *torrent) setChunkSize(size uint64) {
 	// potential bug here use to be '*t.chunks = *newChunks(...)' change to straight assignment to deal with
 	// Unlock called on a non-locked mutex.
 	*t.chunks = *newChunks(size, langx.FirstNonZero(t.info, metainfo.NewInfo()), chunkoptMutex(t.chunks.mu), chunkoptCond(t.chunks.cond), chunkoptCompleted(t.chunks.completed))
+	// chunk indices depend on the chunk size.
+	t.priorities = nil
 }
 
 // There's a connection to that address already.
@@ -794,6 +885,11 @@ func (t *torrent) addPeer(p Peer) {
 	default:
 	}
 
+	if !t.peerAllowed(p) {
+		metrics.Add("private torrent peers discarded", 1)
+		return
+	}
+
 	if t.peers.Add(p) {
 		metrics.Add("peers replaced", 1)
 	}
@@ -869,12 +965,16 @@ func (t *torrent) setInfo(info *metainfo.Info) (err error) {
 
 	t.initFiles()
 
+	if len(t.md.SelectOnly) > 0 {
+		t.prioritize()
+	}
+
 	return nil
 }
 
 func (t *torrent) onSetInfo() {
 	for _, conn := range t.conns.list() {
-		if err := conn.resetclaimed(); err != nil {
+		if err := errorsx.Compact(conn.resetclaimed(), conn.requestPieceLayers()); err != nil {
 			t.cln.config.info().Println(errorsx.Wrap(err, "closing connection"))
 			conn.Close()
 		}
@@ -893,7 +993,11 @@ func (t *torrent) setInfoBytes(b []byte) error {
 		return nil
 	}
 
-	if id := int160.FromHashedBytes(b); !id.Equal(t.md.ID) {
+	if !t.md.IDv2.IsZero() {
+		if id := metainfo.NewHashV2FromBytes(b); id != t.md.IDv2 {
+			return errorsx.Errorf("info bytes have wrong hash %d %s != %s", len(b), id.String(), t.md.IDv2.String())
+		}
+	} else if id := int160.FromHashedBytes(b); !id.Equal(t.md.ID) {
 		return errorsx.Errorf("info bytes have wrong hash %d %s != %s", len(b), id.String(), t.md.ID.String())
 	}
 
@@ -905,6 +1009,11 @@ func (t *torrent) setInfoBytes(b []byte) error {
 		return err
 	}
 
+	// hybrid torrents track their piece layers to serve peers in the v2 swarm.
+	if info.IsV2() {
+		t.layers = newPieceLayers(&info, t.md.PieceLayers)
+	}
+
 	t.metadataBytes = b
 	t.metadataCompletedChunks = nil
 	*t.digests = newDigestsFromTorrent(t)
@@ -1007,6 +1116,8 @@ func (t *torrent) close() error {
 		close(t.closed)
 	}
 
+	go t.announcer.stop(t)
+
 	for _, conn := range t.conns.list() {
 		conn.Close()
 	}
@@ -1110,6 +1221,11 @@ func (t *torrent) openNewConns() {
 			return
 		}
 
+		// peers discovered before the info revealed the torrent is private.
+		if !t.peerAllowed(popped.p) {
+			continue
+		}
+
 		t.cln.config.debug().Printf("initiating connection to peer %p %s\n", t, popped.p.AddrPort)
 		t.initiateConn(context.Background(), popped.p)
 	}
@@ -1162,6 +1278,15 @@ func (t *torrent) bytesCompleted() int64 {
 	return t.info.TotalLength() - t.bytesLeft()
 }
 
+// cancel the request on every connection except the one that delivered it.
+func (t *torrent) cancelRequest(from *connection, r request) {
+	for _, c := range t.conns.filtered(func(c *connection) bool { return c == from }) {
+		if c.cancel(r) {
+			c.allStats(add(1, func(cs *ConnStats) *count { return &cs.ChunksEndgameCancelled }))
+		}
+	}
+}
+
 func (t *torrent) dropConnection(c *connection) {
 	if t.deleteConnection(c) {
 		t.openNewConns()
@@ -1176,6 +1301,11 @@ func (t *torrent) deleteConnection(c *connection) (ret bool) {
 	c.Close()
 	// l2.Printf("closed c(%p) - pending(%d)\n", c, len(c.requests))
 	nlen, ret := t.conns.delete(c)
+	c.superseedRelease()
+	if ret {
+		// free up the connection's upload slot.
+		t.rechoke()
+	}
 
 	if nlen == 0 {
 		t.assertNoPendingRequests()
@@ -1243,7 +1373,7 @@ func (t *torrent) seeding() bool {
 
 // Adds peers revealed in an announce until the announce ends, or we have
 // enough peers.
-func (t *torrent) consumeDhtAnnouncePeers(ctx context.Context, pvs <-chan dht.PeersValues) {
+func (t *torrent) consumeDhtAnnouncePeers(ctx context.Context, swarm int160.T, pvs <-chan dht.PeersValues) {
 	for {
 		select {
 		case v, ok := <-pvs:
@@ -1252,11 +1382,16 @@ func (t *torrent) consumeDhtAnnouncePeers(ctx context.Context, pvs <-chan dht.Pe
 				return
 			}
 
+			if t.private() {
+				return
+			}
+
 			peers := slicesx.MapTransform(func(cp dht.Peer) Peer {
 				return NewPeer(
 					int160.Zero(),
 					cp.AddrPort,
 					PeerOptionSource(peerSourceDhtGetPeers),
+					PeerOptionSwarm(swarm),
 				)
 			}, slicesx.Filter(func(v dht.Peer) bool { return v.Port() != 0 }, v.Peers...)...)
 
@@ -1272,21 +1407,33 @@ func (t *torrent) announceToDht(impliedPort bool, s *dht.Server) error {
 	ctx, done := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer done()
 
-	ps, err := s.AnnounceTraversal(ctx, t.md.ID, dht.AnnouncePeer(impliedPort, t.cln.LocalPort()))
-	if err != nil {
-		return err
+	// hybrid torrents announce to both their v1 and v2 swarms, a failure of one
+	// swarm does not prevent announcing to the other.
+	var failed error
+	announced := 0
+	for _, id := range t.md.Swarms() {
+		ps, err := s.AnnounceTraversal(ctx, id, dht.AnnouncePeer(impliedPort, t.cln.LocalPort()))
+		if err != nil {
+			failed = errors.Join(failed, errorsx.Wrapf(err, "swarm %s", id))
+			continue
+		}
+
+		defer ps.Close()
+		announced++
+		go t.consumeDhtAnnouncePeers(ctx, id, ps.Peers)
 	}
 
-	defer ps.Close()
-	go t.consumeDhtAnnouncePeers(ctx, ps.Peers)
+	if announced == 0 {
+		return failed
+	}
 
 	select {
 	case <-t.closed:
 	case <-ctx.Done():
-		return context.Cause(ctx)
+		return errors.Join(failed, context.Cause(ctx))
 	}
 
-	return nil
+	return failed
 }
 
 func (t *torrent) dhtAnnouncer(s *dht.Server) {
@@ -1301,6 +1448,12 @@ func (t *torrent) dhtAnnouncer(s *dht.Server) {
 			log.Println("dht ancouncing peers wanted event", int160.FromByteArray(s.ID()), t.md.ID)
 		}
 
+		// private torrents are never announced to the DHT (BEP 27).
+		if t.private() {
+			t.cln.config.debug().Println("dht announcer stopped, torrent is private", t.md.ID)
+			return
+		}
+
 		t.stats.DHTAnnounce.Add(1)
 
 		if err := t.announceToDht(true, s); err == nil {
@@ -1346,6 +1499,7 @@ func (t *torrent) statsLocked() (ret Stats) {
 	ret.PendingPeers, ret.HalfOpenPeers = t.peers.Stats()
 	ret.LastConnection = langx.Autoderef(t.lastConnection.Load())
 	t.chunks.Snapshot(&ret)
+	ret.Hashing = t.digests.Stats()
 
 	// TODO: these can be moved to the connections directly.
 	// moving it will reduce the need to iterate the connections
@@ -1438,6 +1592,7 @@ func (t *torrent) addConnection(c *connection) (err error) {
 
 	t.conns.insert(c)
 	t.pex.added(c)
+	t.rechoke()
 
 	t.lock()
 	defer t.unlock()
@@ -1583,7 +1738,12 @@ func (t *torrent) Length() int64 {
 
 func (t *torrent) initFiles() {
 	var offset int64
-	for _, fi := range t.info.UpvertedFiles() {
+	for idx, fi := range t.info.UpvertedFiles() {
+		if fi.IsPadding() {
+			offset += fi.Length
+			continue
+		}
+
 		var path []string
 		if len(fi.PathUTF8) != 0 {
 			path = fi.PathUTF8
@@ -1596,10 +1756,49 @@ func (t *torrent) initFiles() {
 			offset,
 			fi.Length,
 			fi,
-			// PiecePriorityNone,
+			t.selected(idx),
 		})
 		offset += fi.Length
 	}
+
+	t.priorities = nil
+}
+
+// recompute the chunk priorities from the file priorities and reader windows.
+// the file priorities are cached, only the reader windows are recomputed.
+func (t *torrent) prioritize() {
+	if t.priorities == nil {
+		t.priorities = t.filePriorities()
+	}
+
+	levels := maps.Clone(t.priorities)
+	readahead := roaring.New()
+	for _, w := range t.readaheads {
+		readahead.Or(w.chunks(t.chunks))
+	}
+
+	if urgent, ok := levels[PriorityUrgent]; ok {
+		levels[PriorityUrgent] = roaring.Or(urgent, readahead)
+	} else {
+		levels[PriorityUrgent] = readahead
+	}
+
+	t.chunks.Prioritize(levels)
+	// readers block until their data is available, so ensure its downloaded.
+	t.chunks.Want(readahead)
+}
+
+// groups the chunks of the files by their priority.
+func (t *torrent) filePriorities() map[Priority]*roaring.Bitmap {
+	levels := make(map[Priority]*roaring.Bitmap, 4)
+	for _, f := range t.files {
+		if _, ok := levels[f.priority]; !ok {
+			levels[f.priority] = roaring.New()
+		}
+		levels[f.priority].Or(f.chunks())
+	}
+
+	return levels
 }
 
 // Returns handles to the files in the torrent. This requires that the Info is
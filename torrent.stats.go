@@ -18,6 +18,8 @@ type Stats struct {
 	Unverified  int
 	Failed      int
 	Completed   int
+	// outstanding chunks requested from multiple peers.
+	Endgame int
 
 	// Ordered by expected descending quantities (if all is well).
 	MaximumAllowedPeers int
@@ -29,6 +31,9 @@ type Stats struct {
 
 	Seeding        bool
 	LastConnection time.Time
+
+	// piece verification metrics.
+	Hashing HashingStats
 }
 
 func (stats Stats) String() string {
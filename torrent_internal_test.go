@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"net/netip"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	pp "github.com/james-lawrence/torrent/btprotocol"
 	"github.com/james-lawrence/torrent/dht/int160"
 	"github.com/james-lawrence/torrent/internal/testutil"
+	"github.com/james-lawrence/torrent/metainfo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -94,7 +96,7 @@ func TestPieceHashFailed(t *testing.T) {
 	tt.setChunkSize(2)
 	require.NoError(t, tt.setInfoBytes(mi.InfoBytes))
 
-	tt.digests.check(1)
+	tt.digests.check(1, nil)
 
 	// the piece should be marked as a failure. this means the connections will
 	// retry the piece either during their write loop or during their cleanup phase.
@@ -160,3 +162,35 @@ func TestTorrentMetainfoIncompleteMetadata(t *testing.T) {
 	assert.False(t, tt.(*torrent).haveAllMetadataPieces())
 	assert.Nil(t, tt.(*torrent).Metadata().InfoBytes)
 }
+
+func TestTorrentCancelRequest(t *testing.T) {
+	cl := &Client{config: TestingConfig(t, t.TempDir())}
+	ts, err := New(metainfo.Hash{})
+	require.NoError(t, err)
+	tt := newTorrent(cl, ts)
+	require.NoError(t, tt.setInfo(&metainfo.Info{
+		Pieces:      make([]byte, metainfo.HashSize*3),
+		Length:      24 * (1 << 10),
+		PieceLength: 8 * (1 << 10),
+	}))
+
+	conns := make([]*connection, 3)
+	for i := range conns {
+		conns[i] = cl.newConnection(nil, false, netip.AddrPort{})
+		conns[i].setTorrent(tt)
+		tt.conns.insert(conns[i])
+	}
+
+	r := newRequest(1, 0, defaultChunkSize)
+	for _, c := range conns[:2] {
+		c.requests[r.Digest] = r
+	}
+
+	tt.cancelRequest(conns[0], r)
+
+	require.Contains(t, conns[0].requests, r.Digest, "delivering connection should be untouched")
+	require.Empty(t, conns[1].requests)
+	require.Equal(t, r.ToMsg(pp.Cancel).MustMarshalBinary(), conns[1].currentbuffer.Bytes())
+	require.Zero(t, conns[2].currentbuffer.Len())
+	require.EqualValues(t, 1, tt.stats.ChunksEndgameCancelled.Int64())
+}
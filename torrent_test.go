@@ -10,6 +10,8 @@ import (
 
 	"github.com/james-lawrence/torrent"
 	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/bytesx"
 	"github.com/james-lawrence/torrent/internal/md5x"
 	"github.com/james-lawrence/torrent/internal/testx"
 	"github.com/james-lawrence/torrent/metainfo"
@@ -84,3 +86,30 @@ func TestEmptyFilesAndZeroPieceLengthWithMMapStorage(t *testing.T) {
 	defer ci.Close()
 	testEmptyFilesAndZeroPieceLength(t, dir, cfg, torrent.OptionStorage(ci))
 }
+
+func TestVerifyStoredHybrid(t *testing.T) {
+	root := t.TempDir()
+	webseedFile(t, filepath.Join(root, "hybrid", "a.bin"), 70000)
+	webseedFile(t, filepath.Join(root, "hybrid", "b.bin"), 50000)
+
+	mi, err := metainfo.NewCreator(
+		metainfo.CreatorOptionPieceLength(32*bytesx.KiB),
+		metainfo.CreatorOptionInfo(metainfo.OptionHybrid),
+	).Create(t.Context(), filepath.Join(root, "hybrid"))
+	require.NoError(t, err)
+	info, err := mi.UnmarshalInfo()
+	require.NoError(t, err)
+
+	// the data is read from the directory the torrent was created from.
+	named := func(dir string, _ int160.T, info *metainfo.Info, fi *metainfo.FileInfo) string {
+		return filepath.Join(dir, info.Name, filepath.Join(fi.Path...))
+	}
+	data, err := storage.NewFile(root, storage.FileOptionPathMaker(named)).OpenTorrent(&info, mi.ID())
+	require.NoError(t, err)
+	defer data.Close()
+
+	missing, _, err := torrent.VerifyStored(t.Context(), mi, data)
+	require.NoError(t, err)
+	require.True(t, missing.IsEmpty(), "missing chunks %v", missing.ToArray())
+	require.NoDirExists(t, filepath.Join(root, "hybrid", ".pad"))
+}
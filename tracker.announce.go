@@ -0,0 +1,302 @@
+package torrent
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/metainfo"
+	"github.com/james-lawrence/torrent/tracker"
+)
+
+const ErrNoTrackers = errorsx.String("torrent has no trackers")
+
+const (
+	defaultTrackerInterval = 30 * time.Minute
+	trackerAnnounceTimeout = 30 * time.Second
+	trackerStoppedTimeout  = 10 * time.Second
+)
+
+// TrackerStatus reports the state of a tracker the torrent announces to.
+type TrackerStatus struct {
+	URL       string
+	Tier      int  // BEP 12 tier of the tracker.
+	Working   bool // the most recent announce succeeded.
+	Started   bool // the tracker has been sent the started event.
+	Completed bool // the tracker has been sent the completed event.
+	Disabled  bool // the tracker asked to never be retried.
+	Seeders   int32
+	Leechers  int32
+	Peers     int // number of peers returned by the most recent announce.
+	Failures  int // consecutive failed announces.
+	Err       error
+	// the interval and minimum interval requested by the tracker.
+	Interval    time.Duration
+	MinInterval time.Duration
+	Announced   time.Time // time of the most recent successful announce.
+	Next        time.Time // time the tracker expects the next announce.
+	Retry       time.Time // earliest time the tracker will be announced to.
+}
+
+// TuneReadTrackers reads the state of the trackers ordered by tier and the
+// order they'll be announced to.
+func TuneReadTrackers(dst *[]TrackerStatus) Tuner {
+	return func(t *torrent) {
+		*dst = t.announcer.status()
+	}
+}
+
+type announcetracker struct {
+	TrackerStatus
+	leeching bool // started while incomplete, completed will be sent once finished.
+}
+
+// resolve the event to send to the tracker, started is sent to trackers that haven't
+// been started and completed only to trackers that were started while incomplete.
+func (t *announcetracker) event(requested tracker.AnnounceEvent, remaining int64) tracker.AnnounceEvent {
+	switch {
+	case requested == tracker.Stopped:
+		return tracker.Stopped
+	case !t.Started:
+		return tracker.Started
+	case remaining == 0 && t.leeching && !t.Completed:
+		return tracker.Completed
+	default:
+		return tracker.None
+	}
+}
+
+func (t *announcetracker) succeeded(now time.Time, event tracker.AnnounceEvent, remaining int64, res tracker.AnnounceResponse, peers int) {
+	switch event {
+	case tracker.Started:
+		t.Started, t.leeching = true, remaining > 0
+	case tracker.Completed:
+		t.Completed = true
+	case tracker.Stopped:
+		t.Started, t.Completed, t.leeching = false, false, false
+	}
+
+	t.Working = true
+	t.Failures = 0
+	t.Err = nil
+	t.Seeders, t.Leechers, t.Peers = res.Seeders, res.Leechers, peers
+	t.Interval = langx.DefaultIfZero(defaultTrackerInterval, time.Duration(res.Interval)*time.Second)
+	t.MinInterval = time.Duration(res.MinInterval) * time.Second
+	t.Announced = now
+	t.Next = now.Add(t.Interval)
+	t.Retry = now.Add(t.MinInterval)
+}
+
+func (t *announcetracker) failed(now time.Time, cause error) {
+	t.Working = false
+	t.Failures++
+	t.Err = cause
+	t.Retry = now.Add(min(time.Minute<<min(t.Failures-1, 6), time.Hour))
+
+	var failure tracker.Failure
+	if errors.As(cause, &failure) {
+		t.Disabled = failure.Never
+		t.Retry = now.Add(langx.DefaultIfZero(t.Retry.Sub(now), failure.RetryIn))
+	}
+}
+
+// announcer implements BEP 12, the trackers of each tier are shuffled and announced to
+// in order until one succeeds, working trackers are promoted to the front of their
+// tier. when every tracker in a tier fails the next tier is tried.
+type announcer struct {
+	passmu sync.Mutex // serializes announcements
+	mu     sync.Mutex
+	tiers  [][]*announcetracker
+}
+
+// update the tiers to reflect the trackers of the torrent, retaining the
+// state and order of known trackers. new trackers are shuffled into their tier.
+func (t *announcer) sync(list metainfo.AnnounceList) {
+	var known []*announcetracker
+	for _, tier := range t.tiers {
+		known = append(known, tier...)
+	}
+
+	tiers := make([][]*announcetracker, 0, len(list))
+	for i, uris := range list {
+		members := make(map[string]struct{}, len(uris))
+		for _, uri := range uris {
+			members[uri] = struct{}{}
+		}
+
+		tier := make([]*announcetracker, 0, len(uris))
+		for _, c := range known {
+			if _, ok := members[c.URL]; ok {
+				delete(members, c.URL)
+				c.Tier = i
+				tier = append(tier, c)
+			}
+		}
+
+		added := make([]*announcetracker, 0, len(members))
+		for _, uri := range uris {
+			if _, ok := members[uri]; ok {
+				added = append(added, &announcetracker{TrackerStatus: TrackerStatus{URL: uri, Tier: i}})
+			}
+		}
+		rand.Shuffle(len(added), func(i, j int) { added[i], added[j] = added[j], added[i] })
+
+		tiers = append(tiers, append(tier, added...))
+	}
+
+	t.tiers = tiers
+}
+
+// move the tracker to the front of its tier.
+func (t *announcer) promote(c *announcetracker) {
+	tier := t.tiers[c.Tier]
+	for i, x := range tier {
+		if x == c {
+			copy(tier[1:i+1], tier[:i])
+			tier[0] = c
+			return
+		}
+	}
+}
+
+func (t *announcer) status() (results []TrackerStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, tier := range t.tiers {
+		for _, c := range tier {
+			results = append(results, c.TrackerStatus)
+		}
+	}
+
+	return results
+}
+
+// announce to the first tracker that succeeds. returns the delay until the next
+// announce is due.
+func (t *announcer) announce(ctx context.Context, tt *torrent, options ...tracker.AnnounceOption) (delay time.Duration, peers Peers, err error) {
+	var (
+		list      metainfo.AnnounceList
+		remaining int64
+	)
+
+	t.passmu.Lock()
+	defer t.passmu.Unlock()
+
+	tt.rLock()
+	list = tt.md.AnnounceList()
+	tt.rUnlock()
+
+	if err = tt.Tune(TuneReadBytesRemaining(&remaining)); err != nil {
+		return delay, peers, err
+	}
+
+	requested := langx.Clone(tracker.AnnounceRequest{}, options...).Event
+
+	t.mu.Lock()
+	t.sync(list)
+	tiers := make([][]*announcetracker, 0, len(t.tiers))
+	for _, tier := range t.tiers {
+		tiers = append(tiers, append([]*announcetracker(nil), tier...))
+	}
+	t.mu.Unlock()
+
+	if len(tiers) == 0 {
+		return delay, peers, ErrNoTrackers
+	}
+
+	for _, tier := range tiers {
+		for _, c := range tier {
+			t.mu.Lock()
+			event := c.event(requested, remaining)
+			// completed and stopped are announced regardless of the minimum interval
+			// of working trackers, they're only sent once.
+			urgent := c.Working && (event == tracker.Completed || event == tracker.Stopped)
+			eligible := !c.Disabled && (urgent || !time.Now().Before(c.Retry))
+			t.mu.Unlock()
+
+			if !eligible {
+				continue
+			}
+
+			actx, done := context.WithTimeout(ctx, trackerAnnounceTimeout)
+			res, found, cause := trackerAnnounceSwarms(actx, tt, c.URL, langx.Compose(options...), func(ar *tracker.AnnounceRequest) {
+				ar.Event = event
+			})
+			done()
+
+			t.mu.Lock()
+			if cause == nil {
+				c.succeeded(time.Now(), event, remaining, res, len(found))
+				t.promote(c)
+				delay = c.Interval
+			} else {
+				c.failed(time.Now(), cause)
+			}
+			t.mu.Unlock()
+
+			if cause == nil {
+				return delay, found, nil
+			}
+
+			tt.cln.config.debug().Println("announce failed", c.URL, cause)
+			err = langx.FirstNonZero(err, cause)
+		}
+	}
+
+	return t.retry(), peers, langx.FirstNonZero(err, error(ErrNoPeers))
+}
+
+// the delay until the next tracker can be announced to.
+func (t *announcer) retry() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delay := time.Hour
+	for _, tier := range t.tiers {
+		for _, c := range tier {
+			if c.Disabled {
+				continue
+			}
+
+			delay = min(delay, time.Until(c.Retry))
+		}
+	}
+
+	return max(delay, time.Second)
+}
+
+// announce the torrent is stopping to the trackers that have been started.
+func (t *announcer) stop(tt *torrent) {
+	t.passmu.Lock()
+	defer t.passmu.Unlock()
+
+	t.mu.Lock()
+	var started []*announcetracker
+	for _, tier := range t.tiers {
+		for _, c := range tier {
+			if c.Started {
+				started = append(started, c)
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	for _, c := range started {
+		ctx, done := context.WithTimeout(context.Background(), trackerStoppedTimeout)
+		_, _, err := trackerAnnounceSwarms(ctx, tt, c.URL, tracker.AnnounceOptionEventStopped)
+		done()
+
+		t.mu.Lock()
+		if err == nil {
+			c.succeeded(time.Now(), tracker.Stopped, 0, tracker.AnnounceResponse{}, 0)
+		} else {
+			c.failed(time.Now(), err)
+		}
+		t.mu.Unlock()
+	}
+}
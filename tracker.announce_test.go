@@ -0,0 +1,241 @@
+package torrent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/testx"
+	"github.com/james-lawrence/torrent/torrenttest"
+	"github.com/james-lawrence/torrent/tracker"
+)
+
+type eventregistry struct {
+	*tracker.MemoryRegistry
+	mu     sync.Mutex
+	events []tracker.AnnounceEvent
+}
+
+func (t *eventregistry) Announce(ctx context.Context, id int160.T, p tracker.Registration) error {
+	t.mu.Lock()
+	t.events = append(t.events, p.Event)
+	t.mu.Unlock()
+	return t.MemoryRegistry.Announce(ctx, id, p)
+}
+
+func (t *eventregistry) announced() []tracker.AnnounceEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]tracker.AnnounceEvent(nil), t.events...)
+}
+
+func TestTrackerAnnouncer(t *testing.T) {
+	working := func(t *testing.T) (string, *eventregistry) {
+		r := &eventregistry{MemoryRegistry: tracker.NewMemoryRegistry(time.Hour)}
+		srv := httptest.NewServer(tracker.NewServer(tracker.ServerOptionRegistry(r), tracker.ServerOptionInterval(time.Minute)))
+		t.Cleanup(srv.Close)
+		return srv.URL + "/announce", r
+	}
+
+	failing := func(t *testing.T, reason map[string]any) string {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoded, err := bencode.Marshal(reason)
+			require.NoError(t, err)
+			w.Write(encoded)
+		}))
+		t.Cleanup(srv.Close)
+		return srv.URL + "/announce"
+	}
+
+	// tracker requiring a minimum interval between announces, returns the events it received.
+	throttled := func(t *testing.T) (string, func() []string) {
+		var (
+			mu     sync.Mutex
+			events []string
+		)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			events = append(events, r.URL.Query().Get("event"))
+			mu.Unlock()
+
+			encoded, err := bencode.Marshal(map[string]any{"interval": 3600, "min interval": 3600, "peers": ""})
+			require.NoError(t, err)
+			w.Write(encoded)
+		}))
+		t.Cleanup(srv.Close)
+
+		return srv.URL + "/announce", func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), events...)
+		}
+	}
+
+	start := func(t *testing.T, options ...Option) *torrent {
+		cl, err := Autosocket(t).Bind(NewClient(TestingConfig(t, t.TempDir())))
+		require.NoError(t, err)
+		t.Cleanup(func() { cl.Close() })
+
+		info, _, err := torrenttest.Random(t.TempDir(), 32)
+		require.NoError(t, err)
+		md, err := NewFromInfo(info, options...)
+		require.NoError(t, err)
+		tt, _, err := cl.start(md, TuneMaxConnections(0))
+		require.NoError(t, err)
+		return tt
+	}
+
+	read := func(t *testing.T, tt *torrent) (trackers []TrackerStatus) {
+		require.NoError(t, tt.Tune(TuneReadTrackers(&trackers)))
+		return trackers
+	}
+
+	t.Run("fails over to the next tier", func(t *testing.T) {
+		ctx, done := testx.Context(t)
+		defer done()
+
+		broken := failing(t, map[string]any{"failure reason": "broken"})
+		uri, r := working(t)
+		tt := start(t, OptionTiers([]string{broken}, []string{uri}))
+
+		delay, _, err := tt.announcer.announce(ctx, tt)
+		require.NoError(t, err)
+		require.Equal(t, time.Minute, delay)
+		require.Equal(t, []tracker.AnnounceEvent{tracker.Started}, r.announced())
+
+		trackers := read(t, tt)
+		require.Len(t, trackers, 2)
+		require.Equal(t, broken, trackers[0].URL)
+		require.False(t, trackers[0].Working)
+		require.Equal(t, 1, trackers[0].Failures)
+		require.ErrorAs(t, trackers[0].Err, &tracker.Failure{})
+		require.True(t, trackers[0].Retry.After(time.Now()))
+		require.Equal(t, uri, trackers[1].URL)
+		require.Equal(t, 1, trackers[1].Tier)
+		require.True(t, trackers[1].Working)
+		require.True(t, trackers[1].Started)
+
+		// the failing tracker isn't retried until its backoff elapses.
+		_, _, err = tt.announcer.announce(ctx, tt)
+		require.NoError(t, err)
+		require.Equal(t, []tracker.AnnounceEvent{tracker.Started, tracker.None}, r.announced())
+		require.Equal(t, 1, read(t, tt)[0].Failures)
+	})
+
+	t.Run("promotes working trackers within a tier", func(t *testing.T) {
+		ctx, done := testx.Context(t)
+		defer done()
+
+		broken := failing(t, map[string]any{"failure reason": "broken"})
+		uri, _ := working(t)
+		tt := start(t, OptionTiers([]string{broken, uri}))
+
+		_, _, err := tt.announcer.announce(ctx, tt)
+		require.NoError(t, err)
+
+		trackers := read(t, tt)
+		require.Len(t, trackers, 2)
+		require.Equal(t, uri, trackers[0].URL)
+		require.Equal(t, 0, trackers[1].Tier)
+	})
+
+	t.Run("honors retry in", func(t *testing.T) {
+		ctx, done := testx.Context(t)
+		defer done()
+
+		delayed := failing(t, map[string]any{"failure reason": "busy", "retry in": 5})
+		never := failing(t, map[string]any{"failure reason": "gone", "retry in": "never"})
+		tt := start(t, OptionTiers([]string{delayed}, []string{never}))
+
+		_, _, err := tt.announcer.announce(ctx, tt)
+		require.Error(t, err)
+
+		trackers := read(t, tt)
+		require.Len(t, trackers, 2)
+		require.WithinDuration(t, time.Now().Add(5*time.Minute), trackers[0].Retry, time.Minute)
+		require.False(t, trackers[0].Disabled)
+		require.True(t, trackers[1].Disabled)
+	})
+
+	t.Run("stopped is sent to started trackers", func(t *testing.T) {
+		ctx, done := testx.Context(t)
+		defer done()
+
+		uri, r := working(t)
+		unused, unannounced := working(t)
+		tt := start(t, OptionTiers([]string{uri}, []string{unused}))
+
+		_, _, err := tt.announcer.announce(ctx, tt)
+		require.NoError(t, err)
+		tt.announcer.stop(tt)
+
+		require.Equal(t, []tracker.AnnounceEvent{tracker.Started, tracker.Stopped}, r.announced())
+		require.Empty(t, unannounced.announced())
+		require.False(t, read(t, tt)[0].Started)
+	})
+
+	t.Run("completed is only sent when started incomplete", func(t *testing.T) {
+		c := announcetracker{}
+		require.Equal(t, tracker.Started, c.event(tracker.None, 10))
+		c.succeeded(time.Now(), tracker.Started, 10, tracker.AnnounceResponse{}, 0)
+		require.Equal(t, tracker.None, c.event(tracker.Started, 10))
+		require.Equal(t, tracker.Completed, c.event(tracker.None, 0))
+		c.succeeded(time.Now(), tracker.Completed, 0, tracker.AnnounceResponse{}, 0)
+		require.Equal(t, tracker.None, c.event(tracker.Completed, 0))
+		require.Equal(t, tracker.Stopped, c.event(tracker.Stopped, 0))
+
+		seeding := announcetracker{}
+		seeding.succeeded(time.Now(), seeding.event(tracker.None, 0), 0, tracker.AnnounceResponse{}, 0)
+		require.Equal(t, tracker.None, seeding.event(tracker.Completed, 0))
+	})
+	t.Run("events ignore the minimum interval", func(t *testing.T) {
+		ctx, done := testx.Context(t)
+		defer done()
+
+		uri, events := throttled(t)
+		tt := start(t, OptionTiers([]string{uri}))
+
+		_, _, err := tt.announcer.announce(ctx, tt)
+		require.NoError(t, err)
+		require.Equal(t, time.Hour, read(t, tt)[0].MinInterval)
+
+		// regular announces wait for the minimum interval.
+		_, _, err = tt.announcer.announce(ctx, tt)
+		require.ErrorIs(t, err, ErrNoPeers)
+		require.Equal(t, []string{"started"}, events())
+
+		tt.chunks.Complete(0)
+		_, _, err = tt.announcer.announce(ctx, tt)
+		require.NoError(t, err)
+		require.Equal(t, []string{"started", "completed"}, events())
+
+		_, _, err = tt.announcer.announce(ctx, tt, tracker.AnnounceOptionEventStopped)
+		require.NoError(t, err)
+		require.Equal(t, []string{"started", "completed", "stopped"}, events())
+	})
+
+	t.Run("completed is announced once the torrent completes", func(t *testing.T) {
+		uri, events := throttled(t)
+		tt := start(t, OptionTiers([]string{uri}))
+
+		require.NoError(t, tt.Tune(TuneAnnounceUntilComplete))
+		require.Eventually(t, func() bool {
+			return len(events()) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		tt.chunks.Complete(0)
+		tt.pieceStateChanges.Publish(0)
+		require.Eventually(t, func() bool {
+			return len(events()) == 2
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, []string{"started", "completed"}, events())
+	})
+}
@@ -2,7 +2,6 @@ package torrent
 
 import (
 	"context"
-	"log"
 	"time"
 
 	"github.com/james-lawrence/torrent/dht/int160"
@@ -50,6 +49,32 @@ func TrackerEvent(ctx context.Context, l Torrent, announceuri string, options ..
 	return &res, errorsx.Wrapf(err, "announce: %s", announceuri)
 }
 
+// TrackerScrape retrieves the swarm size of the torrent from the tracker without announcing.
+func TrackerScrape(ctx context.Context, l Torrent, uri string) (ret *tracker.ScrapeResult, err error) {
+	var (
+		announcer tracker.Announce
+		infoid    int160.T
+	)
+
+	if err = l.Tune(
+		TuneReadHashID(&infoid),
+		TuneReadAnnounce(&announcer),
+	); err != nil {
+		return nil, err
+	}
+
+	scraped, err := announcer.ForTracker(uri).Scrape(ctx, infoid)
+	if err != nil {
+		return nil, errorsx.Wrapf(err, "scrape: %s", uri)
+	}
+
+	if len(scraped) == 0 {
+		return nil, errorsx.Errorf("scrape: %s: missing result", uri)
+	}
+
+	return &scraped[0], nil
+}
+
 func TrackerAnnounceOnce(ctx context.Context, l Torrent, uri string, options ...tracker.AnnounceOption) (delay time.Duration, peers Peers, err error) {
 	ctx, done := context.WithTimeout(ctx, 30*time.Second)
 	defer done()
@@ -70,72 +95,89 @@ func TrackerAnnounceOnce(ctx context.Context, l Torrent, uri string, options ...
 	return delay, peers.AppendFromTracker(announced.Peers), nil
 }
 
-func TrackerAnnounceUntil(ctx context.Context, t *torrent, donefn func() bool, options ...tracker.AnnounceOption) {
-	const mindelay = 1 * time.Minute
-	var delay time.Duration = mindelay
-
-	trackers := t.md.Trackers
-
-	for {
-		var (
-			totalpeers       = 0
-			failed     error = nil
-		)
-
-		for _, uri := range trackers {
-			t.cln.config.debug().Println("announced initiated", t.md.DisplayName, t.Metadata().DisplayName, len(trackers), uri)
-			ctx, done := context.WithTimeout(context.Background(), time.Minute)
-			d, peers, err := TrackerAnnounceOnce(ctx, t, uri, options...)
-			done()
+// announce to each of the swarms the torrent participates in, see Metadata.Swarms.
+// succeeds if any of the swarms were announced.
+func trackerAnnounceSwarms(ctx context.Context, t *torrent, uri string, options ...tracker.AnnounceOption) (res tracker.AnnounceResponse, peers Peers, err error) {
+	announced := false
+	for _, id := range t.md.Swarms() {
+		swarm, cause := TrackerEvent(ctx, t, uri, langx.Compose(options...), tracker.AnnounceOptionInfoHash(id))
+		if cause != nil {
+			err = langx.FirstNonZero(err, cause)
+			continue
+		}
 
-			totalpeers += len(peers)
-			failed = langx.FirstNonZero(failed, errorsx.Ignore(err, ErrNoPeers))
+		for _, p := range Peers(nil).AppendFromTracker(swarm.Peers) {
+			peers = append(peers, langx.Clone(p, PeerOptionSwarm(id)))
+		}
 
-			t.cln.config.debug().Println("announced completed", t.md.DisplayName, t.Metadata().DisplayName, len(trackers), uri)
+		announced = true
+		res.Interval = max(res.Interval, swarm.Interval)
+		res.MinInterval = max(res.MinInterval, swarm.MinInterval)
+		res.Seeders = max(res.Seeders, swarm.Seeders)
+		res.Leechers = max(res.Leechers, swarm.Leechers)
+	}
 
-			if errorsx.Is(err, context.DeadlineExceeded) {
-				t.cln.config.errors().Println(err)
-				continue
-			}
+	if announced {
+		return res, peers, nil
+	}
 
-			if errorsx.Is(err, tracker.ErrMissingInfoHash) {
-				t.cln.config.errors().Println(err)
-				if len(trackers) == 1 {
-					return
-				}
+	return res, peers, err
+}
 
-				continue
-			}
+// TrackerAnnounceUntil announces to the trackers of the torrent following BEP 12 until
+// donefn returns true, announcing again once the interval requested by the tracker elapses.
+func TrackerAnnounceUntil(ctx context.Context, t *torrent, donefn func() bool, options ...tracker.AnnounceOption) {
+	trackerAnnounceUntil(ctx, t, donefn, nil, options...)
+}
 
-			if err == nil {
-				t.addPeers(peers...)
-				go t.maybeNewConns()
-				continue
-			}
+// announces until donefn returns true. the delay between announces is cut short once
+// donefn returns true after a value is received from wake, e.g. to immediately announce
+// the completed event.
+func trackerAnnounceUntil(ctx context.Context, t *torrent, donefn func() bool, wake <-chan interface{}, options ...tracker.AnnounceOption) {
+	for {
+		delay, peers, err := t.announcer.announce(ctx, t, options...)
+		if errorsx.Is(err, ErrNoTrackers) {
+			return
+		}
 
-			if delay < d {
-				delay = d
-			}
+		if len(peers) > 0 {
+			t.addPeers(peers...)
+			go t.maybeNewConns()
+		}
 
-			if err == ErrNoPeers {
-				continue
-			}
+		if err != nil && !errorsx.Is(err, ErrNoPeers) {
+			t.cln.config.errors().Println(err)
+		}
 
-			t.cln.config.debug().Println("announce failed", t.info == nil, err)
+		if donefn() {
+			return
 		}
 
-		if totalpeers > 0 && failed == nil {
-			log.Println("announce succeeded, but there are no peers", t.Metadata().ID.String(), len(trackers))
-			continue
+		t.cln.config.debug().Println("announce sleeping", t.Metadata().ID.String(), delay)
+		if !trackerAnnounceSleep(ctx, t, delay, donefn, wake) {
+			return
 		}
+	}
+}
 
-		t.cln.config.debug().Println("announce sleeping for maximum delay", t.Metadata().ID.String(), delay)
-		time.Sleep(delay)
-		delay = mindelay
+// sleep until the delay elapses or donefn returns true after a wake up, returns false
+// when the torrent or context are closed.
+func trackerAnnounceSleep(ctx context.Context, t *torrent, delay time.Duration, donefn func() bool, wake <-chan interface{}) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
 
-		if donefn() {
-			// log.Println("announce completed", t.Metadata().ID.String())
-			return
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-t.closed:
+			return false
+		case <-timer.C:
+			return true
+		case <-wake:
+			if donefn() {
+				return true
+			}
 		}
 	}
 }
@@ -7,9 +7,12 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/james-lawrence/torrent/dht/int160"
 	"github.com/james-lawrence/torrent/dht/krpc"
 
 	"github.com/james-lawrence/torrent/bencode"
@@ -17,7 +20,8 @@ import (
 )
 
 const (
-	ErrMissingInfoHash = errorsx.String("missing info hash")
+	ErrMissingInfoHash   = errorsx.String("missing info hash")
+	ErrScrapeUnsupported = errorsx.String("tracker does not support scrape")
 )
 
 type HttpResponse struct {
@@ -29,7 +33,10 @@ type HttpResponse struct {
 	Incomplete    int32  `bencode:"incomplete"`
 	Peers         Peers  `bencode:"peers"`
 	// BEP 7
-	Peers6 krpc.CompactIPv6NodeAddrs `bencode:"peers6"`
+	Peers6      krpc.CompactIPv6NodeAddrs `bencode:"peers6"`
+	MinInterval int32                     `bencode:"min interval"`
+	// BEP 31, minutes before retrying or 'never'.
+	RetryIn any `bencode:"retry in"`
 }
 
 type Peers []Peer
@@ -103,6 +110,17 @@ func setAnnounceParams(_url *url.URL, ar *AnnounceRequest, opts Announce) {
 	_url.RawQuery = q.Encode()
 }
 
+func httpClient(opt Announce) *http.Client {
+	return &http.Client{
+		Timeout: time.Second * 15,
+		Transport: &http.Transport{
+			DialContext:         opt.Dialer.DialContext,
+			Proxy:               http.ProxyFromEnvironment,
+			TLSHandshakeTimeout: 15 * time.Second,
+		},
+	}
+}
+
 func announceHTTP(ctx context.Context, _url *url.URL, ar AnnounceRequest, opt Announce) (ret AnnounceResponse, err error) {
 	dup, err := url.Parse(_url.String())
 	if err != nil {
@@ -113,14 +131,7 @@ func announceHTTP(ctx context.Context, _url *url.URL, ar AnnounceRequest, opt An
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dup.String(), nil)
 	req.Header.Set("User-Agent", opt.UserAgent)
 
-	resp, err := (&http.Client{
-		Timeout: time.Second * 15,
-		Transport: &http.Transport{
-			DialContext:         opt.Dialer.DialContext,
-			Proxy:               http.ProxyFromEnvironment,
-			TLSHandshakeTimeout: 15 * time.Second,
-		},
-	}).Do(req)
+	resp, err := httpClient(opt).Do(req)
 	if err != nil {
 		return ret, err
 	}
@@ -146,11 +157,19 @@ func announceHTTP(ctx context.Context, _url *url.URL, ar AnnounceRequest, opt An
 		case "InfoHash not found.", "Torrent has been deleted.":
 			return ret, ErrMissingInfoHash
 		default:
-			return ret, fmt.Errorf("tracker gave failure reason: %q", trackerResponse.FailureReason)
+			failure := Failure{Reason: trackerResponse.FailureReason}
+			switch retry := trackerResponse.RetryIn.(type) {
+			case int64:
+				failure.RetryIn = time.Duration(retry) * time.Minute
+			case string:
+				failure.Never = retry == "never"
+			}
+			return ret, failure
 		}
 	}
 
 	ret.Interval = trackerResponse.Interval
+	ret.MinInterval = trackerResponse.MinInterval
 	ret.Leechers = trackerResponse.Incomplete
 	ret.Seeders = trackerResponse.Complete
 	ret.Peers = trackerResponse.Peers
@@ -164,3 +183,90 @@ func announceHTTP(ctx context.Context, _url *url.URL, ar AnnounceRequest, opt An
 
 	return ret, nil
 }
+
+type HttpScrapeFile struct {
+	Complete   int32 `bencode:"complete"`
+	Downloaded int32 `bencode:"downloaded"`
+	Incomplete int32 `bencode:"incomplete"`
+}
+
+type HttpScrapeResponse struct {
+	FailureReason string                    `bencode:"failure reason"`
+	Files         map[string]HttpScrapeFile `bencode:"files"`
+}
+
+// ScrapeURL derives the scrape url from an announce url, the last path component
+// must begin with 'announce' which is replaced with 'scrape'. see BEP 48.
+func ScrapeURL(announce *url.URL) (*url.URL, error) {
+	dup := *announce
+	dir, last := path.Split(dup.Path)
+	if !strings.HasPrefix(last, "announce") {
+		return nil, errorsx.Wrapf(ErrScrapeUnsupported, "%s", announce)
+	}
+
+	dup.Path = dir + "scrape" + strings.TrimPrefix(last, "announce")
+	dup.RawPath = ""
+	return &dup, nil
+}
+
+func scrapeHTTP(ctx context.Context, _url *url.URL, opt Announce, infohashes ...int160.T) (ret []ScrapeResult, err error) {
+	dup, err := ScrapeURL(_url)
+	if err != nil {
+		return ret, err
+	}
+
+	q := dup.Query()
+	for _, id := range infohashes {
+		q.Add("info_hash", id.ByteString())
+	}
+	dup.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dup.String(), nil)
+	if err != nil {
+		return ret, err
+	}
+	req.Header.Set("User-Agent", opt.UserAgent)
+
+	resp, err := httpClient(opt).Do(req)
+	if err != nil {
+		return ret, err
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err = io.Copy(&buf, resp.Body); err != nil {
+		return ret, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ret, errorsx.Wrapf(ErrScrapeUnsupported, "%s", dup)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ret, fmt.Errorf("response from tracker: %s: %s", resp.Status, buf.String())
+	}
+
+	var scraped HttpScrapeResponse
+	err = bencode.Unmarshal(buf.Bytes(), &scraped)
+	if _, ok := err.(bencode.ErrUnusedTrailingBytes); ok {
+		err = nil
+	} else if err != nil {
+		return ret, fmt.Errorf("error decoding %q: %s", buf.Bytes(), err)
+	}
+
+	if scraped.FailureReason != "" {
+		return ret, fmt.Errorf("tracker gave failure reason: %q", scraped.FailureReason)
+	}
+
+	for _, id := range infohashes {
+		f := scraped.Files[id.ByteString()]
+		ret = append(ret, ScrapeResult{
+			InfoHash:   id,
+			Complete:   f.Complete,
+			Incomplete: f.Incomplete,
+			Downloaded: f.Downloaded,
+		})
+	}
+
+	return ret, nil
+}
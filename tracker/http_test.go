@@ -1,12 +1,18 @@
 package tracker
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/testx"
 )
 
 func TestUnmarshalHTTPResponsePeerDicts(t *testing.T) {
@@ -49,3 +55,64 @@ func TestUnmarshalHttpResponsePeers6NotCompact(t *testing.T) {
 		&hr,
 	))
 }
+
+func TestScrapeURL(t *testing.T) {
+	for announce, expected := range map[string]string{
+		"http://example.com/announce":          "http://example.com/scrape",
+		"http://example.com/x/announce":        "http://example.com/x/scrape",
+		"http://example.com/announce.php":      "http://example.com/scrape.php",
+		"http://example.com/announce?x2%0644":  "http://example.com/scrape?x2%0644",
+		"http://example.com/x/announce?key=ab": "http://example.com/x/scrape?key=ab",
+	} {
+		u, err := url.Parse(announce)
+		require.NoError(t, err)
+		scrape, err := ScrapeURL(u)
+		require.NoError(t, err)
+		require.Equal(t, expected, scrape.String())
+	}
+
+	for _, announce := range []string{
+		"http://example.com/a",
+		"http://example.com/announce/x",
+		"http://example.com/x%064announce",
+	} {
+		u, err := url.Parse(announce)
+		require.NoError(t, err)
+		_, err = ScrapeURL(u)
+		require.ErrorIs(t, err, ErrScrapeUnsupported)
+	}
+}
+
+func TestScrapeHTTP(t *testing.T) {
+	ctx, done := testx.Context(t)
+	defer done()
+
+	known := int160.Random()
+	unknown := int160.Random()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scrape" {
+			http.NotFound(w, r)
+			return
+		}
+
+		require.Equal(t, []string{known.ByteString(), unknown.ByteString()}, r.URL.Query()["info_hash"])
+		encoded, err := bencode.Marshal(HttpScrapeResponse{
+			Files: map[string]HttpScrapeFile{
+				known.ByteString(): {Complete: 1, Downloaded: 3, Incomplete: 2},
+			},
+		})
+		require.NoError(t, err)
+		w.Write(encoded)
+	}))
+	defer srv.Close()
+
+	scraped, err := Scrape(ctx, fmt.Sprintf("%s/announce", srv.URL), known, unknown)
+	require.NoError(t, err)
+	require.Equal(t, []ScrapeResult{
+		{InfoHash: known, Complete: 1, Incomplete: 2, Downloaded: 3},
+		{InfoHash: unknown},
+	}, scraped)
+
+	_, err = Scrape(ctx, fmt.Sprintf("%s/x/announce", srv.URL), known)
+	require.ErrorIs(t, err, ErrScrapeUnsupported)
+}
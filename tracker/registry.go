@@ -0,0 +1,168 @@
+package tracker
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/james-lawrence/torrent/dht/int160"
+)
+
+// Registration of a peer within a swarm.
+type Registration struct {
+	ID       int160.T
+	AddrPort netip.AddrPort
+	Left     int64 // bytes remaining, zero when the peer is seeding.
+	Event    AnnounceEvent
+}
+
+// Registry stores the peers participating in the swarms tracked by a Server.
+// implementations must be safe for concurrent use.
+type Registry interface {
+	// Announce records the announcement of a peer. peers announcing Stopped are
+	// removed from the swarm and peers announcing Completed are counted as a download.
+	Announce(ctx context.Context, id int160.T, p Registration) error
+	// Peers returns up to n peers participating in the swarm.
+	Peers(ctx context.Context, id int160.T, n int) ([]Registration, error)
+	// Scrape returns the size of the swarm.
+	Scrape(ctx context.Context, id int160.T) (ScrapeResult, error)
+}
+
+type registered struct {
+	Registration
+	expires time.Time
+}
+
+type memoryswarm struct {
+	peers      map[netip.AddrPort]registered
+	downloaded int32
+	expires    time.Time // swarms without peers are retained for their download count until they expire.
+}
+
+// NewMemoryRegistry stores swarms in memory, peers expire if they fail to
+// announce within the ttl.
+func NewMemoryRegistry(ttl time.Duration) *MemoryRegistry {
+	return &MemoryRegistry{
+		ttl:    ttl,
+		swarms: make(map[int160.T]*memoryswarm),
+	}
+}
+
+// MemoryRegistry is a Registry that stores swarms in memory.
+type MemoryRegistry struct {
+	ttl    time.Duration
+	mu     sync.Mutex
+	swarms map[int160.T]*memoryswarm
+	swept  time.Time // last time every swarm was pruned.
+}
+
+// remove the expired peers from the swarm, the swarm is deleted once it is empty.
+func (t *MemoryRegistry) prune(now time.Time, id int160.T, s *memoryswarm) {
+	for addr, r := range s.peers {
+		if now.After(r.expires) {
+			delete(s.peers, addr)
+		}
+	}
+
+	// retain swarms that have been downloaded so the count is reported by scrapes.
+	if len(s.peers) == 0 && (s.downloaded == 0 || now.After(s.expires)) {
+		delete(t.swarms, id)
+	}
+}
+
+// prune every swarm at most once per ttl, releasing swarms that are no longer announced.
+func (t *MemoryRegistry) sweep(now time.Time) {
+	if now.Sub(t.swept) < t.ttl {
+		return
+	}
+
+	t.swept = now
+	for id, s := range t.swarms {
+		t.prune(now, id, s)
+	}
+}
+
+// returns the swarm with its expired peers removed.
+func (t *MemoryRegistry) swarm(now time.Time, id int160.T) (*memoryswarm, bool) {
+	t.sweep(now)
+
+	s, ok := t.swarms[id]
+	if !ok {
+		return nil, false
+	}
+
+	t.prune(now, id, s)
+	s, ok = t.swarms[id]
+	return s, ok
+}
+
+func (t *MemoryRegistry) Announce(ctx context.Context, id int160.T, p Registration) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	s, ok := t.swarm(now, id)
+	if !ok {
+		s = &memoryswarm{peers: make(map[netip.AddrPort]registered)}
+		t.swarms[id] = s
+	}
+
+	s.expires = now.Add(t.ttl)
+
+	switch p.Event {
+	case Stopped:
+		delete(s.peers, p.AddrPort)
+	case Completed:
+		s.downloaded++
+		fallthrough
+	default:
+		s.peers[p.AddrPort] = registered{Registration: p, expires: now.Add(t.ttl)}
+	}
+
+	t.prune(now, id, s)
+
+	return nil
+}
+
+func (t *MemoryRegistry) Peers(ctx context.Context, id int160.T, n int) (peers []Registration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.swarm(time.Now(), id)
+	if !ok {
+		return peers, nil
+	}
+
+	peers = make([]Registration, 0, len(s.peers))
+	for _, r := range s.peers {
+		peers = append(peers, r.Registration)
+	}
+
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+
+	return peers[:min(n, len(peers))], nil
+}
+
+func (t *MemoryRegistry) Scrape(ctx context.Context, id int160.T) (res ScrapeResult, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res.InfoHash = id
+	s, ok := t.swarm(time.Now(), id)
+	if !ok {
+		return res, nil
+	}
+
+	res.Downloaded = s.downloaded
+	for _, r := range s.peers {
+		if r.Left == 0 {
+			res.Complete++
+		} else {
+			res.Incomplete++
+		}
+	}
+
+	return res, nil
+}
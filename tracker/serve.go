@@ -0,0 +1,193 @@
+package tracker
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/langx"
+)
+
+const (
+	defaultServerInterval = 30 * time.Minute
+	defaultServerNumWant  = 50
+	maximumServerNumWant  = 200
+	// maximum number of udp requests answered concurrently.
+	maximumServerUDPConcurrency = 128
+)
+
+type ServerOption func(*Server)
+
+// ServerOptionRegistry sets the storage for the swarms, defaults to a MemoryRegistry.
+func ServerOptionRegistry(r Registry) ServerOption {
+	return func(s *Server) {
+		s.registry = r
+	}
+}
+
+// ServerOptionInterval sets how frequently peers are asked to announce.
+func ServerOptionInterval(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.interval = d
+	}
+}
+
+// ServerOptionWhitelist restricts the server to tracking the provided infohashes.
+func ServerOptionWhitelist(ids ...int160.T) ServerOption {
+	return func(s *Server) {
+		if s.whitelist == nil {
+			s.whitelist = make(map[int160.T]struct{}, len(ids))
+		}
+
+		for _, id := range ids {
+			s.whitelist[id] = struct{}{}
+		}
+	}
+}
+
+// ServerOptionAuthorize is consulted for every infohash announced or scraped, requests
+// are rejected when it returns an error. the uri is the request uri, for udp announces
+// it is provided by the BEP 41 url data option and is empty for udp scrapes.
+func ServerOptionAuthorize(fn func(uri *url.URL, id int160.T) error) ServerOption {
+	return func(s *Server) {
+		s.authorize = fn
+	}
+}
+
+// NewServer creates a tracker server, see Server.ServeUDP and Server.ServeHTTP.
+func NewServer(options ...ServerOption) *Server {
+	s := langx.Autoptr(langx.Clone(Server{
+		interval:  defaultServerInterval,
+		authorize: func(*url.URL, int160.T) error { return nil },
+	}, options...))
+
+	if s.registry == nil {
+		// allow peers to miss an announce before they expire.
+		s.registry = NewMemoryRegistry(2 * s.interval)
+	}
+
+	if _, err := rand.Read(s.secret[:]); err != nil {
+		panic(errorsx.Wrap(err, "unable to generate connection secret"))
+	}
+
+	return s
+}
+
+// Server tracks the peers of swarms over udp (BEP 15) and http (BEP 3, 23, 48).
+type Server struct {
+	registry  Registry
+	interval  time.Duration
+	whitelist map[int160.T]struct{}
+	authorize func(uri *url.URL, id int160.T) error
+	secret    [32]byte // signs udp connection ids.
+}
+
+func (s *Server) permitted(uri *url.URL, id int160.T) error {
+	if s.whitelist != nil {
+		if _, ok := s.whitelist[id]; !ok {
+			return ErrMissingInfoHash
+		}
+	}
+
+	return s.authorize(uri, id)
+}
+
+// record the announcement and return up to numwant other peers from the swarm
+// along with the size of the swarm.
+func (s *Server) announce(ctx context.Context, uri *url.URL, id int160.T, p Registration, numwant int32) (swarm ScrapeResult, peers []Registration, err error) {
+	if err = s.permitted(uri, id); err != nil {
+		return swarm, peers, err
+	}
+
+	if err = s.registry.Announce(ctx, id, p); err != nil {
+		return swarm, peers, errorsx.Wrap(err, "unable to record announce")
+	}
+
+	if swarm, err = s.registry.Scrape(ctx, id); err != nil {
+		return swarm, peers, errorsx.Wrap(err, "unable to scrape swarm")
+	}
+
+	if p.Event == Stopped {
+		return swarm, peers, nil
+	}
+
+	n := defaultServerNumWant
+	if numwant >= 0 {
+		n = min(int(numwant), maximumServerNumWant)
+	}
+
+	found, err := s.registry.Peers(ctx, id, n+1)
+	if err != nil {
+		return swarm, peers, errorsx.Wrap(err, "unable to retrieve peers")
+	}
+
+	for _, c := range found {
+		if c.AddrPort == p.AddrPort || len(peers) == n {
+			continue
+		}
+
+		peers = append(peers, c)
+	}
+
+	return swarm, peers, nil
+}
+
+// scrape the swarms, swarms that are not permitted are reported as empty.
+func (s *Server) scrape(ctx context.Context, uri *url.URL, ids ...int160.T) (results []ScrapeResult, err error) {
+	results = make([]ScrapeResult, 0, len(ids))
+	for _, id := range ids {
+		if s.permitted(uri, id) != nil {
+			results = append(results, ScrapeResult{InfoHash: id})
+			continue
+		}
+
+		swarm, err := s.registry.Scrape(ctx, id)
+		if err != nil {
+			return results, errorsx.Wrap(err, "unable to scrape swarm")
+		}
+
+		results = append(results, swarm)
+	}
+
+	return results, nil
+}
+
+// udp connection ids are derived from the address of the peer and the current minute,
+// avoiding the need to track them. see Server.connected.
+func (s *Server) connectionID(addr netip.AddrPort, at time.Time) int64 {
+	encoded, _ := addr.MarshalBinary()
+	mac := hmac.New(sha256.New, s.secret[:])
+	mac.Write(encoded)
+	binary.Write(mac, binary.BigEndian, at.Unix()/60)
+	return int64(binary.BigEndian.Uint64(mac.Sum(nil)))
+}
+
+// connection ids are accepted for at least two minutes as required by BEP 15.
+func (s *Server) connected(id int64, addr netip.AddrPort) bool {
+	now := time.Now()
+	for i := range 3 {
+		if id == s.connectionID(addr, now.Add(-time.Duration(i)*time.Minute)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keep only the peers that can be encoded for the address family.
+func samefamily(addr netip.Addr, peers ...Registration) (results []Registration) {
+	for _, p := range peers {
+		if p.AddrPort.Addr().Is4() == addr.Is4() {
+			results = append(results, p)
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,220 @@
+package tracker
+
+import (
+	"net/http"
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/dht/krpc"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/langx"
+)
+
+type httpAnnounceResponse struct {
+	Interval   int32  `bencode:"interval"`
+	Complete   int32  `bencode:"complete"`
+	Incomplete int32  `bencode:"incomplete"`
+	Peers      any    `bencode:"peers"`
+	Peers6     []byte `bencode:"peers6,omitempty"`
+}
+
+type httpPeer struct {
+	ID   string `bencode:"peer id"`
+	IP   string `bencode:"ip"`
+	Port uint16 `bencode:"port"`
+}
+
+// ServeHTTP answers http tracker requests, the announce and scrape endpoints are
+// determined by the last component of the path as described by BEP 48.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, last := path.Split(r.URL.Path)
+	switch {
+	case strings.HasPrefix(last, "announce"):
+		s.serveHTTPAnnounce(w, r)
+	case strings.HasPrefix(last, "scrape"):
+		s.serveHTTPScrape(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveHTTPAnnounce(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	ih := q.Get("info_hash")
+	if len(ih) != 20 {
+		httpFailure(w, errorsx.New("invalid info_hash"))
+		return
+	}
+
+	peerid := q.Get("peer_id")
+	if len(peerid) != 20 {
+		httpFailure(w, errorsx.New("invalid peer_id"))
+		return
+	}
+
+	port, err := strconv.ParseUint(q.Get("port"), 10, 16)
+	if err != nil || port == 0 {
+		httpFailure(w, errorsx.New("invalid port"))
+		return
+	}
+
+	left, err := strconv.ParseInt(langx.DefaultIfZero("0", q.Get("left")), 10, 64)
+	if err != nil {
+		httpFailure(w, errorsx.New("invalid left"))
+		return
+	}
+
+	numwant, err := strconv.ParseInt(langx.DefaultIfZero("-1", q.Get("numwant")), 10, 32)
+	if err != nil {
+		httpFailure(w, errorsx.New("invalid numwant"))
+		return
+	}
+
+	event, err := parseAnnounceEvent(q.Get("event"))
+	if err != nil {
+		httpFailure(w, err)
+		return
+	}
+
+	remote, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		httpFailure(w, errorsx.New("unable to determine remote address"))
+		return
+	}
+
+	// the ip parameters of the request are ignored, peers are registered
+	// at the address the request originated from.
+	swarm, peers, err := s.announce(r.Context(), r.URL, int160.FromByteString(ih), Registration{
+		ID:       int160.FromByteString(peerid),
+		AddrPort: netip.AddrPortFrom(remote.Addr().Unmap(), uint16(port)),
+		Left:     left,
+		Event:    event,
+	}, int32(numwant))
+	if err != nil {
+		httpFailure(w, err)
+		return
+	}
+
+	resp := httpAnnounceResponse{
+		Interval:   int32(s.interval.Seconds()),
+		Complete:   swarm.Complete,
+		Incomplete: swarm.Incomplete,
+	}
+
+	if q.Get("compact") == "0" {
+		dicts := make([]httpPeer, 0, len(peers))
+		for _, p := range peers {
+			dicts = append(dicts, httpPeer{
+				ID:   p.ID.ByteString(),
+				IP:   p.AddrPort.Addr().String(),
+				Port: p.AddrPort.Port(),
+			})
+		}
+		resp.Peers = dicts
+	} else {
+		var (
+			compact4 krpc.CompactIPv4NodeAddrs
+			compact6 krpc.CompactIPv6NodeAddrs
+		)
+
+		for _, p := range peers {
+			if p.AddrPort.Addr().Is4() {
+				compact4 = append(compact4, krpc.NewNodeAddrFromAddrPort(p.AddrPort))
+			} else {
+				compact6 = append(compact6, krpc.NewNodeAddrFromAddrPort(p.AddrPort))
+			}
+		}
+
+		encoded := []byte{}
+		if len(compact4) > 0 {
+			if encoded, err = compact4.MarshalBinary(); err != nil {
+				httpFailure(w, err)
+				return
+			}
+		}
+		resp.Peers = encoded
+
+		if len(compact6) > 0 {
+			if resp.Peers6, err = compact6.MarshalBinary(); err != nil {
+				httpFailure(w, err)
+				return
+			}
+		}
+	}
+
+	httpRespond(w, resp)
+}
+
+func (s *Server) serveHTTPScrape(w http.ResponseWriter, r *http.Request) {
+	infohashes := r.URL.Query()["info_hash"]
+	ids := make([]int160.T, 0, len(infohashes))
+	for _, ih := range infohashes {
+		if len(ih) != 20 {
+			httpFailure(w, errorsx.New("invalid info_hash"))
+			return
+		}
+
+		ids = append(ids, int160.FromByteString(ih))
+	}
+
+	scraped, err := s.scrape(r.Context(), r.URL, ids...)
+	if err != nil {
+		httpFailure(w, err)
+		return
+	}
+
+	resp := HttpScrapeResponse{
+		Files: make(map[string]HttpScrapeFile, len(scraped)),
+	}
+	for _, swarm := range scraped {
+		resp.Files[swarm.InfoHash.ByteString()] = HttpScrapeFile{
+			Complete:   swarm.Complete,
+			Downloaded: swarm.Downloaded,
+			Incomplete: swarm.Incomplete,
+		}
+	}
+
+	httpRespond(w, resp)
+}
+
+func httpRespond(w http.ResponseWriter, v any) {
+	encoded, err := bencode.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(encoded)
+}
+
+// failures are reported within the response body as described by BEP 3.
+func httpFailure(w http.ResponseWriter, cause error) {
+	reason := cause.Error()
+	if errorsx.Is(cause, ErrMissingInfoHash) {
+		// recognized by clients as the tracker not tracking the torrent.
+		reason = "InfoHash not found."
+	}
+
+	httpRespond(w, map[string]string{"failure reason": reason})
+}
+
+func parseAnnounceEvent(s string) (AnnounceEvent, error) {
+	switch s {
+	case "", "empty":
+		return None, nil
+	case "completed":
+		return Completed, nil
+	case "started":
+		return Started, nil
+	case "stopped":
+		return Stopped, nil
+	default:
+		return None, errorsx.Errorf("invalid event: %q", s)
+	}
+}
@@ -0,0 +1,199 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/dht/krpc"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/netx"
+)
+
+// ServeUDP answers udp tracker requests (BEP 15) received on the packet connection,
+// returns once the connection is closed.
+func (s *Server) ServeUDP(pc net.PacketConn) error {
+	var (
+		buf = make([]byte, 0x10000)
+		sem = make(chan struct{}, maximumServerUDPConcurrency)
+	)
+
+	for {
+		n, from, err := pc.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return errorsx.Wrap(err, "unable to read udp request")
+		}
+
+		addr, err := netx.AddrPort(from)
+		if err != nil {
+			continue
+		}
+
+		// once every worker is busy stop reading, excess datagrams are dropped by the socket.
+		sem <- struct{}{}
+		go func(b []byte) {
+			defer func() { <-sem }()
+			s.serveUDP(context.Background(), pc, from, netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port()), b)
+		}(bytes.Clone(buf[:n]))
+	}
+}
+
+func (s *Server) serveUDP(ctx context.Context, pc net.PacketConn, to net.Addr, addr netip.AddrPort, b []byte) error {
+	respond := func(action Action, tid int32, parts ...any) error {
+		encoded, err := marshal(append([]any{ResponseHeader{Action: action, TransactionId: tid}}, parts...)...)
+		if err != nil {
+			return err
+		}
+
+		_, err = pc.WriteTo(encoded, to)
+		return err
+	}
+
+	failed := func(tid int32, cause error) error {
+		return errorsx.Compact(cause, respond(ActionError, tid, []byte(cause.Error())))
+	}
+
+	r := bytes.NewReader(b)
+	var h RequestHeader
+	if err := readBody(r, &h); err != nil {
+		return err
+	}
+
+	switch h.Action {
+	case ActionConnect:
+		if h.ConnectionId != connectRequestConnectionId {
+			return nil
+		}
+
+		return respond(ActionConnect, h.TransactionId, ConnectionResponse{ConnectionId: s.connectionID(addr, time.Now())})
+	case ActionAnnounce:
+		if !s.connected(h.ConnectionId, addr) {
+			return failed(h.TransactionId, errorsx.New("connection id expired"))
+		}
+
+		var ar AnnounceRequest
+		if err := readBody(r, &ar); err != nil {
+			return failed(h.TransactionId, errorsx.New("malformed announce"))
+		}
+
+		options := make([]byte, r.Len())
+		r.Read(options)
+		uri, err := udpURLData(options)
+		if err != nil {
+			return failed(h.TransactionId, err)
+		}
+
+		// the ip address of the request is ignored, peers are registered
+		// at the address the request originated from.
+		port := ar.Port
+		if port == 0 {
+			port = addr.Port()
+		}
+
+		swarm, peers, err := s.announce(ctx, uri, int160.FromByteArray(ar.InfoHash), Registration{
+			ID:       int160.FromByteArray(ar.PeerId),
+			AddrPort: netip.AddrPortFrom(addr.Addr(), port),
+			Left:     ar.Left,
+			Event:    ar.Event,
+		}, ar.NumWant)
+		if err != nil {
+			return failed(h.TransactionId, err)
+		}
+
+		compact := make([]krpc.NodeAddr, 0, len(peers))
+		for _, p := range samefamily(addr.Addr(), peers...) {
+			compact = append(compact, krpc.NewNodeAddrFromAddrPort(p.AddrPort))
+		}
+
+		var encoded []byte
+		if len(compact) > 0 {
+			bm := encoding.BinaryMarshaler(krpc.CompactIPv4NodeAddrs(compact))
+			if addr.Addr().Is6() {
+				bm = krpc.CompactIPv6NodeAddrs(compact)
+			}
+
+			if encoded, err = bm.MarshalBinary(); err != nil {
+				return failed(h.TransactionId, err)
+			}
+		}
+
+		return respond(ActionAnnounce, h.TransactionId, AnnounceResponseHeader{
+			Interval: int32(s.interval.Seconds()),
+			Leechers: swarm.Incomplete,
+			Seeders:  swarm.Complete,
+		}, encoded)
+	case ActionScrape:
+		if !s.connected(h.ConnectionId, addr) {
+			return failed(h.TransactionId, errorsx.New("connection id expired"))
+		}
+
+		ids := make([]int160.T, 0, scrapeBatchSize)
+		for r.Len() >= 20 && len(ids) < scrapeBatchSize {
+			var ih [20]byte
+			if err := readBody(r, &ih); err != nil {
+				return failed(h.TransactionId, errorsx.New("malformed scrape"))
+			}
+
+			ids = append(ids, int160.FromByteArray(ih))
+		}
+
+		scraped, err := s.scrape(ctx, &url.URL{}, ids...)
+		if err != nil {
+			return failed(h.TransactionId, err)
+		}
+
+		entries := make([]ScrapeResponseEntry, 0, len(scraped))
+		for _, swarm := range scraped {
+			entries = append(entries, ScrapeResponseEntry{
+				Seeders:   swarm.Complete,
+				Completed: swarm.Downloaded,
+				Leechers:  swarm.Incomplete,
+			})
+		}
+
+		return respond(ActionScrape, h.TransactionId, entries)
+	default:
+		return failed(h.TransactionId, errorsx.Errorf("unhandled action: %d", h.Action))
+	}
+}
+
+// decode the url data from the BEP 41 options trailing an announce.
+func udpURLData(options []byte) (*url.URL, error) {
+	var data []byte
+
+	for len(options) > 0 {
+		switch options[0] {
+		case optionTypeEndOfOptions:
+			options = nil
+			continue
+		case optionTypeNOP:
+			options = options[1:]
+			continue
+		}
+
+		if len(options) < 2 || len(options) < 2+int(options[1]) {
+			return nil, errorsx.New("malformed announce options")
+		}
+
+		if options[0] == optionTypeURLData {
+			data = append(data, options[2:2+int(options[1])]...)
+		}
+
+		options = options[2+int(options[1]):]
+	}
+
+	if len(data) == 0 {
+		return &url.URL{}, nil
+	}
+
+	uri, err := url.ParseRequestURI(string(data))
+	return uri, errorsx.Wrap(err, "invalid url data")
+}
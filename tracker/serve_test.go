@@ -0,0 +1,188 @@
+package tracker
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent/bencode"
+	"github.com/james-lawrence/torrent/dht/int160"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/internal/langx"
+	"github.com/james-lawrence/torrent/internal/testx"
+)
+
+func TestServer(t *testing.T) {
+	udpserver := func(t *testing.T, s *Server) string {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { pc.Close() })
+		go s.ServeUDP(pc)
+		return fmt.Sprintf("udp://%s/announce", pc.LocalAddr())
+	}
+
+	httpserver := func(t *testing.T, s *Server) string {
+		srv := httptest.NewServer(s)
+		t.Cleanup(srv.Close)
+		return fmt.Sprintf("%s/announce", srv.URL)
+	}
+
+	for name, serve := range map[string]func(*testing.T, *Server) string{"udp": udpserver, "http": httpserver} {
+		t.Run(name, func(t *testing.T) {
+			t.Run("announce and scrape", func(t *testing.T) {
+				ctx, done := testx.Context(t)
+				defer done()
+
+				uri := serve(t, NewServer())
+				swarm := int160.Random()
+
+				seeder := NewAccounceRequest(int160.Random(), 1000, swarm, AnnounceOptionSeeding, AnnounceOptionEventStarted)
+				res, err := Announce{TrackerUrl: uri}.Do(ctx, seeder)
+				require.NoError(t, err)
+				require.Empty(t, res.Peers)
+				require.EqualValues(t, 1, res.Seeders)
+				require.EqualValues(t, defaultServerInterval.Seconds(), res.Interval)
+
+				leecher := NewAccounceRequest(int160.Random(), 1001, swarm, AnnounceOptionRemaining(10), AnnounceOptionEventStarted)
+				res, err = Announce{TrackerUrl: uri}.Do(ctx, leecher)
+				require.NoError(t, err)
+				require.Len(t, res.Peers, 1)
+				require.EqualValues(t, 1000, res.Peers[0].Port)
+				require.True(t, res.Peers[0].IP.Equal(net.IPv4(127, 0, 0, 1)))
+				require.EqualValues(t, 1, res.Seeders)
+				require.EqualValues(t, 1, res.Leechers)
+
+				_, err = Announce{TrackerUrl: uri}.Do(ctx, langx.Clone(leecher, AnnounceOptionSeeding, AnnounceOptionEventCompleted))
+				require.NoError(t, err)
+				_, err = Announce{TrackerUrl: uri}.Do(ctx, langx.Clone(seeder, AnnounceOptionEventStopped))
+				require.NoError(t, err)
+
+				unknown := int160.Random()
+				scraped, err := Scrape(ctx, uri, swarm, unknown)
+				require.NoError(t, err)
+				require.Equal(t, []ScrapeResult{
+					{InfoHash: swarm, Complete: 1, Downloaded: 1},
+					{InfoHash: unknown},
+				}, scraped)
+			})
+
+			t.Run("whitelist", func(t *testing.T) {
+				ctx, done := testx.Context(t)
+				defer done()
+
+				allowed := int160.Random()
+				uri := serve(t, NewServer(ServerOptionWhitelist(allowed)))
+
+				_, err := Announce{TrackerUrl: uri}.Do(ctx, NewAccounceRequest(int160.Random(), 1000, allowed, AnnounceOptionSeeding))
+				require.NoError(t, err)
+
+				_, err = Announce{TrackerUrl: uri}.Do(ctx, NewAccounceRequest(int160.Random(), 1000, int160.Random()))
+				require.Error(t, err)
+
+				denied := int160.Random()
+				scraped, err := Scrape(ctx, uri, allowed, denied)
+				require.NoError(t, err)
+				require.Equal(t, []ScrapeResult{
+					{InfoHash: allowed, Complete: 1},
+					{InfoHash: denied},
+				}, scraped)
+			})
+
+			t.Run("authorize", func(t *testing.T) {
+				ctx, done := testx.Context(t)
+				defer done()
+
+				uri := serve(t, NewServer(ServerOptionAuthorize(func(uri *url.URL, id int160.T) error {
+					if uri.Query().Get("passkey") == "secret" {
+						return nil
+					}
+
+					return errorsx.New("unauthorized")
+				})))
+
+				_, err := Announce{TrackerUrl: uri + "?passkey=secret"}.Do(ctx, NewAccounceRequest(int160.Random(), 1000, int160.Random()))
+				require.NoError(t, err)
+
+				_, err = Announce{TrackerUrl: uri + "?passkey=invalid"}.Do(ctx, NewAccounceRequest(int160.Random(), 1000, int160.Random()))
+				require.ErrorContains(t, err, "unauthorized")
+			})
+		})
+	}
+
+	t.Run("http non-compact peers", func(t *testing.T) {
+		ctx, done := testx.Context(t)
+		defer done()
+
+		uri := httpserver(t, NewServer())
+		swarm := int160.Random()
+		peer := NewAccounceRequest(int160.Random(), 1000, swarm, AnnounceOptionSeeding)
+		_, err := Announce{TrackerUrl: uri}.Do(ctx, peer)
+		require.NoError(t, err)
+
+		q := url.Values{}
+		q.Set("info_hash", swarm.ByteString())
+		q.Set("peer_id", int160.Random().ByteString())
+		q.Set("port", "1001")
+		q.Set("compact", "0")
+		resp, err := http.Get(uri + "?" + q.Encode())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var decoded HttpResponse
+		require.NoError(t, bencode.NewDecoder(resp.Body).Decode(&decoded))
+		require.Len(t, decoded.Peers, 1)
+		require.Equal(t, peer.PeerId[:], decoded.Peers[0].ID)
+		require.EqualValues(t, 1000, decoded.Peers[0].Port)
+	})
+
+	t.Run("udp connection ids expire", func(t *testing.T) {
+		s := NewServer()
+		addr := netip.MustParseAddrPort("127.0.0.1:1000")
+		now := time.Now()
+		require.True(t, s.connected(s.connectionID(addr, now), addr))
+		require.True(t, s.connected(s.connectionID(addr, now.Add(-time.Minute)), addr))
+		require.False(t, s.connected(s.connectionID(addr, now.Add(-4*time.Minute)), addr))
+		require.False(t, s.connected(s.connectionID(addr, now), netip.MustParseAddrPort("127.0.0.1:1001")))
+	})
+}
+
+func TestMemoryRegistryExpiry(t *testing.T) {
+	ctx, done := testx.Context(t)
+	defer done()
+
+	r := NewMemoryRegistry(50 * time.Millisecond)
+	swarm, abandoned, downloaded := int160.Random(), int160.Random(), int160.Random()
+	require.NoError(t, r.Announce(ctx, swarm, Registration{AddrPort: netip.MustParseAddrPort("127.0.0.1:1000")}))
+	require.NoError(t, r.Announce(ctx, abandoned, Registration{AddrPort: netip.MustParseAddrPort("127.0.0.1:1001")}))
+	require.NoError(t, r.Announce(ctx, downloaded, Registration{AddrPort: netip.MustParseAddrPort("127.0.0.1:1002"), Event: Completed}))
+	require.NoError(t, r.Announce(ctx, downloaded, Registration{AddrPort: netip.MustParseAddrPort("127.0.0.1:1002"), Event: Stopped}))
+
+	peers, err := r.Peers(ctx, swarm, 10)
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+
+	// stopped swarms are retained for their download count.
+	scraped, err := r.Scrape(ctx, downloaded)
+	require.NoError(t, err)
+	require.Equal(t, ScrapeResult{InfoHash: downloaded, Downloaded: 1}, scraped)
+
+	time.Sleep(100 * time.Millisecond)
+
+	peers, err = r.Peers(ctx, swarm, 10)
+	require.NoError(t, err)
+	require.Empty(t, peers)
+
+	scraped, err = r.Scrape(ctx, swarm)
+	require.NoError(t, err)
+	require.Equal(t, ScrapeResult{InfoHash: swarm}, scraped)
+
+	// expired swarms are released, even those that are never requested again.
+	require.Empty(t, r.swarms)
+}
@@ -13,9 +13,10 @@ import (
 )
 
 type torrent struct {
-	Leechers int32
-	Seeders  int32
-	Peers    []krpc.NodeAddr
+	Leechers  int32
+	Seeders   int32
+	Completed int32
+	Peers     []krpc.NodeAddr
 }
 
 type server struct {
@@ -113,6 +114,32 @@ func (s *server) serveOne() (err error) {
 			Seeders:  t.Seeders,
 		}, b)
 		return
+	case ActionScrape:
+		if _, ok := s.conns[h.ConnectionId]; !ok {
+			s.respond(addr, ResponseHeader{
+				TransactionId: h.TransactionId,
+				Action:        ActionError,
+			}, []byte("not connected"))
+			return
+		}
+		var entries []ScrapeResponseEntry
+		for r.Len() >= 20 {
+			var ih [20]byte
+			if err = readBody(r, &ih); err != nil {
+				return
+			}
+			t := s.t[ih]
+			entries = append(entries, ScrapeResponseEntry{
+				Seeders:   t.Seeders,
+				Completed: t.Completed,
+				Leechers:  t.Leechers,
+			})
+		}
+		err = s.respond(addr, ResponseHeader{
+			TransactionId: h.TransactionId,
+			Action:        ActionScrape,
+		}, entries)
+		return
 	default:
 		err = fmt.Errorf("unhandled action: %d", h.Action)
 		s.respond(addr, ResponseHeader{
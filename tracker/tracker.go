@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"math"
 	"net"
 	"net/url"
@@ -48,6 +49,13 @@ func AnnounceOptionRemaining(n int64) AnnounceOption {
 	}
 }
 
+// AnnounceOptionInfoHash overrides the infohash being announced.
+func AnnounceOptionInfoHash(id int160.T) AnnounceOption {
+	return func(ar *AnnounceRequest) {
+		ar.InfoHash = int160.ByteArray(id)
+	}
+}
+
 func AnnounceOptionEventStarted(ar *AnnounceRequest) {
 	ar.Event = Started
 }
@@ -92,10 +100,11 @@ type AnnounceRequest struct {
 } // 82 bytes
 
 type AnnounceResponse struct {
-	Interval int32 // Minimum seconds the local peer should wait before next announce.
-	Leechers int32
-	Seeders  int32
-	Peers    []Peer
+	Interval    int32 // Minimum seconds the local peer should wait before next announce.
+	MinInterval int32 // Seconds the local peer must wait before announcing again, zero if unspecified.
+	Leechers    int32
+	Seeders     int32
+	Peers       []Peer
 }
 
 type AnnounceEvent int32
@@ -109,6 +118,19 @@ var (
 	ErrBadScheme = errors.New("unknown scheme")
 )
 
+// Failure is returned when the tracker rejects an announce with a failure reason.
+type Failure struct {
+	Reason string
+	// how long to wait before retrying the tracker (BEP 31), zero if unspecified.
+	RetryIn time.Duration
+	// the tracker should never be retried (BEP 31).
+	Never bool
+}
+
+func (t Failure) Error() string {
+	return fmt.Sprintf("tracker gave failure reason: %q", t.Reason)
+}
+
 type Announce struct {
 	TrackerUrl string
 	// UdpNetwork string
@@ -125,13 +147,19 @@ func (me Announce) ForTracker(uri string) Announce {
 	return me
 }
 
-func (me Announce) Do(ctx context.Context, req AnnounceRequest) (res AnnounceResponse, err error) {
+func (me Announce) dialer() netx.Dialer {
 	if me.Dialer == nil {
-		me.Dialer = &net.Dialer{
+		return &net.Dialer{
 			Timeout: 15 * time.Second,
 		}
 	}
 
+	return me.Dialer
+}
+
+func (me Announce) Do(ctx context.Context, req AnnounceRequest) (res AnnounceResponse, err error) {
+	me.Dialer = me.dialer()
+
 	_url, err := url.Parse(me.TrackerUrl)
 	if err != nil {
 		return res, err
@@ -145,3 +173,41 @@ func (me Announce) Do(ctx context.Context, req AnnounceRequest) (res AnnounceRes
 		return res, ErrBadScheme
 	}
 }
+
+// ScrapeResult is the swarm size of a single infohash reported by a tracker.
+type ScrapeResult struct {
+	InfoHash   int160.T
+	Complete   int32 // number of seeders.
+	Incomplete int32 // number of leechers.
+	Downloaded int32 // number of times the torrent has been downloaded.
+}
+
+// Scrape the tracker for the swarm sizes of the infohashes without announcing, results
+// are returned in the same order as the infohashes. infohashes unknown to the tracker
+// are reported as empty swarms. see BEP 48 (http) and BEP 15 (udp).
+func (me Announce) Scrape(ctx context.Context, infohashes ...int160.T) (res []ScrapeResult, err error) {
+	me.Dialer = me.dialer()
+
+	_url, err := url.Parse(me.TrackerUrl)
+	if err != nil {
+		return res, err
+	}
+
+	if len(infohashes) == 0 {
+		return res, nil
+	}
+
+	switch _url.Scheme {
+	case "http", "https":
+		return scrapeHTTP(ctx, _url, me, infohashes...)
+	case "udp", "udp4", "udp6":
+		return scrapeUDP(ctx, _url, me, infohashes...)
+	default:
+		return res, ErrBadScheme
+	}
+}
+
+// Scrape the tracker at the uri using the default options, see Announce.Scrape.
+func Scrape(ctx context.Context, uri string, infohashes ...int160.T) ([]ScrapeResult, error) {
+	return Announce{TrackerUrl: uri}.Scrape(ctx, infohashes...)
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/anacrolix/missinggo/pproffd"
+	"github.com/james-lawrence/torrent/dht/int160"
 	"github.com/james-lawrence/torrent/dht/krpc"
 	"github.com/james-lawrence/torrent/internal/errorsx"
 	"github.com/james-lawrence/torrent/internal/netx"
@@ -32,6 +33,9 @@ const (
 	optionTypeEndOfOptions = 0
 	optionTypeNOP          = 1
 	optionTypeURLData      = 2
+
+	// maximum number of infohashes in a single scrape request.
+	scrapeBatchSize = 74
 )
 
 type ConnectionRequest struct {
@@ -61,6 +65,14 @@ type AnnounceResponseHeader struct {
 	Seeders  int32
 }
 
+// ScrapeResponseEntry is the udp encoding of a scrape result, note the ordering
+// differs from the announce response.
+type ScrapeResponseEntry struct {
+	Seeders   int32
+	Completed int32
+	Leechers  int32
+} // 12 bytes
+
 func newTransactionId() int32 {
 	return int32(rand.Uint32())
 }
@@ -291,3 +303,49 @@ func announceUDP(ctx context.Context, _url *url.URL, ar AnnounceRequest, opt Ann
 	defer ua.Close()
 	return ua.Do(ctx, ar)
 }
+
+func (c *udpAnnounce) scrape(ctx context.Context, infohashes ...int160.T) (res []ScrapeResult, err error) {
+	if err = c.connect(ctx); err != nil {
+		return res, err
+	}
+
+	for offset := 0; offset < len(infohashes); offset += scrapeBatchSize {
+		batch := infohashes[offset:min(offset+scrapeBatchSize, len(infohashes))]
+		hashes := make([][20]byte, 0, len(batch))
+		for _, id := range batch {
+			hashes = append(hashes, int160.ByteArray(id))
+		}
+
+		b, err := c.request(ActionScrape, hashes, nil)
+		if err != nil {
+			return res, err
+		}
+
+		for _, id := range batch {
+			var e ScrapeResponseEntry
+			if err = readBody(b, &e); err == io.EOF {
+				return res, errorsx.Wrap(io.ErrUnexpectedEOF, "error parsing scrape response")
+			} else if err != nil {
+				return res, errorsx.Wrap(err, "error parsing scrape response")
+			}
+
+			res = append(res, ScrapeResult{
+				InfoHash:   id,
+				Complete:   e.Seeders,
+				Incomplete: e.Leechers,
+				Downloaded: e.Completed,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+func scrapeUDP(ctx context.Context, _url *url.URL, opt Announce, infohashes ...int160.T) ([]ScrapeResult, error) {
+	ua := udpAnnounce{
+		url: *_url,
+		a:   &opt,
+	}
+	defer ua.Close()
+	return ua.scrape(ctx, infohashes...)
+}
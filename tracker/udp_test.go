@@ -258,3 +258,36 @@ func TestURLPathOption(t *testing.T) {
 	write(w, AnnounceResponseHeader{})
 	conn.WriteTo(w.Bytes(), addr)
 }
+
+func TestScrapeLocalhost(t *testing.T) {
+	ctx, done := testx.Context(t)
+	defer done()
+
+	known := int160.Random()
+	unknown := int160.Random()
+	srv := server{
+		t: map[[20]byte]torrent{
+			int160.ByteArray(known): {
+				Seeders:   1,
+				Leechers:  2,
+				Completed: 3,
+			},
+		},
+	}
+	var err error
+	srv.pc, err = net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer srv.pc.Close()
+	go func() {
+		for i := 0; i < 2; i++ {
+			require.NoError(t, srv.serveOne())
+		}
+	}()
+
+	scraped, err := Scrape(ctx, fmt.Sprintf("udp://%s/announce", srv.pc.LocalAddr().String()), known, unknown)
+	require.NoError(t, err)
+	require.Equal(t, []ScrapeResult{
+		{InfoHash: known, Complete: 1, Incomplete: 2, Downloaded: 3},
+		{InfoHash: unknown},
+	}, scraped)
+}
@@ -0,0 +1,311 @@
+package torrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+
+	"github.com/james-lawrence/torrent/internal/backoffx"
+	"github.com/james-lawrence/torrent/internal/bitmapx"
+	"github.com/james-lawrence/torrent/internal/errorsx"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+const (
+	// maximum number of chunks requested from a webseed in a single pass.
+	webseedBatchSize = 64
+	// maximum amount of time to wait between checking for work when idle.
+	webseedIdleMaximum = 5 * time.Second
+)
+
+// http client respecting the proxy and dialer of the configuration.
+func newHTTPClient(cfg *ClientConfig) *http.Client {
+	proxy := http.ProxyFromEnvironment
+	if cfg.HTTPProxy != nil {
+		proxy = cfg.HTTPProxy
+	}
+
+	transport := &http.Transport{
+		Proxy:               proxy,
+		TLSHandshakeTimeout: 15 * time.Second,
+	}
+
+	if cfg.dialer != nil {
+		transport.DialContext = cfg.dialer.DialContext
+	}
+
+	return &http.Client{
+		Timeout:   time.Minute,
+		Transport: transport,
+	}
+}
+
+func newWebseed(t *torrent, uri string) *webseed {
+	return &webseed{
+		t:         t,
+		uri:       uri,
+		client:    newHTTPClient(t.cln.config),
+		available: roaring.New(),
+		backoff: backoffx.New(
+			backoffx.Exponential(time.Second),
+			backoffx.Maximum(5*time.Minute),
+		),
+	}
+}
+
+// webseed implements BEP 19, it acts as a virtual peer that has every piece
+// of the torrent available via http range requests.
+type webseed struct {
+	t       *torrent
+	uri     string
+	client  *http.Client
+	backoff backoffx.Strategy
+	// every chunk of the torrent, reused between steps.
+	available *roaring.Bitmap
+}
+
+// webseedSpan represents a contiguous region of a single file within the torrent.
+type webseedSpan struct {
+	uri     string
+	offset  int64 // offset into the file.
+	length  int64
+	padding bool // padding files are not served by webseeds, their contents are zeros.
+}
+
+func (t *webseed) String() string {
+	return fmt.Sprintf("webseed(%s)", t.uri)
+}
+
+// webseeds have no connection level stats, only the torrent and client stats are updated.
+func (t *webseed) allStats(f func(*ConnStats)) {
+	f(&t.t.stats)
+	f(&t.t.cln.stats)
+}
+
+// run the webseed until the torrent is closed.
+func (t *webseed) run() {
+	var (
+		attempts = 0
+		idle     = time.Duration(0)
+	)
+
+	select {
+	case <-t.t.GotInfo():
+	case <-t.t.closed:
+		return
+	}
+
+	for {
+		delay := time.Duration(0)
+
+		if n, err := t.step(context.Background()); errors.As(err, &empty{}) || n == 0 && err == nil {
+			idle = min(max(2*idle, 100*time.Millisecond), webseedIdleMaximum)
+			delay = idle
+		} else if err != nil {
+			delay = t.backoff.Backoff(attempts)
+			attempts++
+			t.t.cln.config.errors().Println(t, errorsx.Wrapf(err, "failed, retrying in %s", delay))
+		} else {
+			attempts = 0
+			idle = 0
+		}
+
+		select {
+		case <-t.t.closed:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// step retrieves a batch of missing chunks, returns the number of chunks written.
+func (t *webseed) step(ctx context.Context) (n int, err error) {
+	if cmaximum := uint64(t.t.chunks.cmaximum); t.available.GetCardinality() != cmaximum {
+		t.available = bitmapx.Fill(cmaximum)
+	}
+
+	reqs, err := t.t.chunks.Pop(webseedBatchSize, t.available)
+	if err != nil {
+		return 0, err
+	}
+
+	for len(reqs) > 0 {
+		batch := webseedContiguous(t.t.info, reqs)
+		reqs = reqs[len(batch):]
+
+		if remaining, err := t.fetch(ctx, batch); err != nil {
+			t.t.chunks.Retry(append(remaining, reqs...)...)
+			return n + len(batch) - len(remaining), err
+		}
+
+		n += len(batch)
+	}
+
+	return n, nil
+}
+
+// fetch the requests, which must be contiguous, writing them into storage.
+// on failure returns the requests that were not processed and need to be retried.
+func (t *webseed) fetch(ctx context.Context, reqs []request) (remaining []request, err error) {
+	var (
+		info   = t.t.info
+		first  = reqs[0]
+		offset = info.Piece(int(first.Index)).Offset() + int64(first.Begin)
+		length = int64(0)
+	)
+
+	for _, r := range reqs {
+		length += int64(r.Length)
+	}
+
+	buf := make([]byte, length)
+	cursor := buf
+	for _, span := range webseedSpans(t.uri, info, offset, length) {
+		if span.padding {
+			cursor = cursor[span.length:]
+			continue
+		}
+
+		if err = t.download(ctx, span, cursor[:span.length]); err != nil {
+			return reqs, err
+		}
+
+		cursor = cursor[span.length:]
+	}
+
+	for i, r := range reqs {
+		chunk := buf[:r.Length]
+		buf = buf[r.Length:]
+
+		if t.t.chunks.Available(r) {
+			t.t.chunks.Release(r)
+			t.allStats(add(1, func(cs *ConnStats) *count { return &cs.ChunksReadWasted }))
+			continue
+		}
+
+		if err = t.t.writeChunk(int(r.Index), int64(r.Begin), chunk); err != nil {
+			return reqs[i:], errorsx.Wrap(err, "failed to write chunk")
+		}
+
+		if err = t.t.chunks.Verify(r); err != nil {
+			return reqs[i:], errorsx.Wrap(err, "failed to verify")
+		}
+
+		t.allStats(add(1, func(cs *ConnStats) *count { return &cs.ChunksReadUseful }))
+		t.allStats(add(int64(r.Length), func(cs *ConnStats) *count { return &cs.BytesReadUsefulData }))
+
+		if idx := uint64(r.Index); t.t.chunks.ChunksAvailable(idx) {
+			t.t.digests.Enqueue(idx)
+		}
+	}
+
+	return nil, nil
+}
+
+func (t *webseed) download(ctx context.Context, span webseedSpan, dst []byte) (err error) {
+	ctx, done := context.WithTimeout(ctx, time.Minute)
+	defer done()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, span.uri, nil)
+	if err != nil {
+		return errorsx.Wrap(err, "unable to build request")
+	}
+
+	req.Header.Set("User-Agent", t.t.cln.config.HTTPUserAgent)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", span.offset, span.offset+span.length-1))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return errorsx.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// server ignored the range header, skip to the offset we care about.
+		if _, err = io.CopyN(io.Discard, resp.Body, span.offset); err != nil {
+			return errorsx.Wrap(err, "unable to seek response")
+		}
+	default:
+		return errorsx.Errorf("unexpected response %s: %s", span.uri, resp.Status)
+	}
+
+	if _, err = io.ReadFull(resp.Body, dst); err != nil {
+		return errorsx.Wrapf(err, "short response %s", span.uri)
+	}
+
+	t.allStats(add(span.length, func(cs *ConnStats) *count { return &cs.BytesRead }))
+	t.allStats(add(span.length, func(cs *ConnStats) *count { return &cs.BytesReadData }))
+
+	return nil
+}
+
+// returns the prefix of the requests that are contiguous within the torrent's data.
+func webseedContiguous(info *metainfo.Info, reqs []request) []request {
+	offset := func(r request) int64 {
+		return info.Piece(int(r.Index)).Offset() + int64(r.Begin)
+	}
+
+	for i := 1; i < len(reqs); i++ {
+		prev := reqs[i-1]
+		if offset(prev)+int64(prev.Length) != offset(reqs[i]) {
+			return reqs[:i]
+		}
+	}
+
+	return reqs
+}
+
+// maps a region of the torrent onto the urls of the files it covers.
+func webseedSpans(base string, info *metainfo.Info, offset, length int64) (spans []webseedSpan) {
+	var (
+		begin int64
+	)
+
+	for _, fi := range info.UpvertedFiles() {
+		end := begin + fi.Length
+
+		if length > 0 && offset < end && fi.Length > 0 {
+			n := min(end-offset, length)
+			spans = append(spans, webseedSpan{
+				uri:     webseedURL(base, info, fi),
+				offset:  offset - begin,
+				length:  n,
+				padding: fi.IsPadding(),
+			})
+			offset += n
+			length -= n
+		}
+
+		begin = end
+	}
+
+	return spans
+}
+
+// computes the url of the given file based on BEP 19.
+func webseedURL(base string, info *metainfo.Info, fi metainfo.FileInfo) string {
+	if !info.IsDir() {
+		if strings.HasSuffix(base, "/") {
+			return base + url.PathEscape(info.Name)
+		}
+
+		return base
+	}
+
+	segments := make([]string, 0, len(fi.Path)+1)
+	segments = append(segments, url.PathEscape(info.Name))
+	for _, p := range fi.Path {
+		segments = append(segments, url.PathEscape(p))
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.Join(segments, "/")
+}
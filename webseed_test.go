@@ -0,0 +1,133 @@
+package torrent_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/james-lawrence/torrent"
+	"github.com/james-lawrence/torrent/autobind"
+	"github.com/james-lawrence/torrent/internal/md5x"
+	"github.com/james-lawrence/torrent/internal/testutil"
+	"github.com/james-lawrence/torrent/metainfo"
+)
+
+func webseedFile(t *testing.T, path string, n int64) []byte {
+	buf := make([]byte, n)
+	_, err := io.ReadFull(rand.Reader, buf)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, buf, 0600))
+	return buf
+}
+
+func webseedDownload(t *testing.T, info *metainfo.Info, seeds ...string) []byte {
+	md, err := torrent.NewFromInfo(info, torrent.OptionWebseeds(seeds))
+	require.NoError(t, err)
+
+	leecher, err := autobind.NewLoopback().Bind(torrent.NewClient(TestingLeechConfig(t, t.TempDir())))
+	require.NoError(t, err)
+	defer leecher.Close()
+
+	tor, added, err := leecher.Start(md)
+	require.NoError(t, err)
+	require.True(t, added)
+
+	buf := bytes.NewBuffer(nil)
+	_, err = torrent.DownloadInto(t.Context(), buf, tor)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestWebseedSingleFile(t *testing.T) {
+	root := t.TempDir()
+	expected := webseedFile(t, filepath.Join(root, "single.bin"), 300*1024+17)
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(root)))
+	defer srv.Close()
+
+	info, err := metainfo.NewFromPath(filepath.Join(root, "single.bin"), metainfo.OptionPieceLength(64*1024))
+	require.NoError(t, err)
+
+	t.Run("directory url", func(t *testing.T) {
+		require.Equal(t, expected, webseedDownload(t, info, srv.URL+"/"))
+	})
+
+	t.Run("file url", func(t *testing.T) {
+		require.Equal(t, expected, webseedDownload(t, info, srv.URL+"/single.bin"))
+	})
+}
+
+func TestWebseedMultiFile(t *testing.T) {
+	root := t.TempDir()
+	expected := md5.New()
+	for _, path := range []string{"a.bin", "b.bin", filepath.Join("nested", "c d.bin")} {
+		_, err := expected.Write(webseedFile(t, filepath.Join(root, "multi", path), 100*1024+3))
+		require.NoError(t, err)
+	}
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(root)))
+	defer srv.Close()
+
+	info, err := metainfo.NewFromPath(filepath.Join(root, "multi"), metainfo.OptionPieceLength(64*1024))
+	require.NoError(t, err)
+
+	downloaded := webseedDownload(t, info, srv.URL)
+	require.Equal(t, md5x.FormatHex(expected), md5x.FormatHex(md5x.Digest(downloaded)))
+}
+
+func TestWebseedV2(t *testing.T) {
+	const plength = 32 * 1024
+	root := t.TempDir()
+	expected := md5.New()
+	for _, path := range []string{"a.bin", "b.bin", filepath.Join("nested", "c d.bin")} {
+		_, err := expected.Write(webseedFile(t, filepath.Join(root, "multi", path), 100*1024+3))
+		require.NoError(t, err)
+		if path != filepath.Join("nested", "c d.bin") {
+			_, err = expected.Write(make([]byte, 4*plength-(100*1024+3)))
+			require.NoError(t, err)
+		}
+	}
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(root)))
+	defer srv.Close()
+
+	info := testutil.InfoV2(t, filepath.Join(root, "multi"), plength)
+	require.False(t, info.HasV1())
+
+	downloaded := webseedDownload(t, info, srv.URL)
+	require.Equal(t, md5x.FormatHex(expected), md5x.FormatHex(md5x.Digest(downloaded)))
+}
+
+func TestWebseedHybrid(t *testing.T) {
+	const plength = 32 * 1024
+	root := t.TempDir()
+	expected := md5.New()
+	for _, path := range []string{"a.bin", filepath.Join("nested", "b.bin")} {
+		_, err := expected.Write(webseedFile(t, filepath.Join(root, "multi", path), 100*1024+3))
+		require.NoError(t, err)
+		if path == "a.bin" {
+			_, err = expected.Write(make([]byte, 4*plength-(100*1024+3)))
+			require.NoError(t, err)
+		}
+	}
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(root)))
+	defer srv.Close()
+
+	info, err := metainfo.NewFromPath(filepath.Join(root, "multi"), metainfo.OptionPieceLength(plength), metainfo.OptionHybrid)
+	require.NoError(t, err)
+	require.True(t, info.IsV2())
+	require.True(t, info.HasV1())
+
+	downloaded := webseedDownload(t, info, srv.URL)
+	require.Equal(t, md5x.FormatHex(expected), md5x.FormatHex(md5x.Digest(downloaded)))
+}